@@ -7,118 +7,241 @@ import (
 	"log"
 	"net/http"
 	"os"
-	"os/signal"
-	"syscall"
-	"time"
 
+	"proyecto/auth-server/internal/config"
 	"proyecto/auth-server/internal/controller"
+	"proyecto/auth-server/internal/gateway/metadatauser"
 	"proyecto/auth-server/internal/handler"
+	"proyecto/auth-server/internal/keys"
+	"proyecto/auth-server/internal/password"
+	"proyecto/auth-server/internal/repository/bolt"
 	"proyecto/auth-server/internal/repository/memory"
-
-	"proyecto/pkg/discovery/consul"
-	registrypkg "proyecto/pkg/registry" // alias solo para usar registrypkg.GenerateInstanceID
+	"proyecto/auth-server/internal/repository/postgres"
+	metadataclient "proyecto/metadataUser/client"
+
+	"proyecto/pkg/auth"
+	configpkg "proyecto/pkg/config"
+	"proyecto/pkg/health"
+	"proyecto/pkg/idempotency"
+	"proyecto/pkg/ratelimit"
+	registrypkg "proyecto/pkg/registry"
+	"proyecto/pkg/service"
 )
 
-const defaultServiceName = "auth-server"
-
 func main() {
-	// Config básica del microservicio Auth-server
-	var port int
-	flag.IntVar(&port, "port", 8082, "Puerto del microservicio de Autenticación (auth-server)")
+	// Config del microservicio Auth-server: valores por defecto, luego el
+	// archivo (-config), luego las variables de entorno y al final los flags.
+	var configFile string
+	var printConfig bool
+	flag.StringVar(&configFile, "config", os.Getenv("CONFIG_FILE"), "Archivo YAML de configuración")
+	flag.Int("port", 8082, "Puerto del microservicio de Autenticación (auth-server)")
+	flag.String("storage", "memory", "Almacenamiento de usuarios: memory, bolt o postgres")
+	flag.BoolVar(&printConfig, "print-config", false, "Muestra la configuración efectiva (sin secretos) y termina")
 	flag.Parse()
-	//para poder cambiar el nombre del microservicio si es que subo mas de 1 
-	serviceName := os.Getenv("SERVICE_NAME")
-	if serviceName == "" {
-		serviceName = defaultServiceName
-	}
 
-	//log para saber que se inicializó bien 
-	log.Printf("Starting %s on port %d", serviceName, port)
-
-	// ===== Consul =====
-	// Dónde está Consul (local: localhost:8500 (si no lo corro con docker) o si es docker: consul:8500 o docker->host: host.docker.internal:8500)
-	consulAddr := os.Getenv("CONSUL_HOST")
-	if consulAddr == "" {
-		consulAddr = "localhost:8500"
+	cfg := config.Default()
+	if err := configpkg.Load(configFile, &cfg); err != nil {
+		log.Fatalf("error cargando configuración: %v", err)
 	}
-	//para saber donde se esta colocando el microservicio 
-	log.Printf("CONSUL_HOST = %s", consulAddr)
-
-	reg, err := consul.NewRegistry(consulAddr)
+	cfg.ApplyFlags(flag.CommandLine)
+	if err := cfg.Validate(); err != nil {
+		log.Fatalf("configuración inválida:\n%v", err)
+	}
+	dump, err := configpkg.Redacted(cfg)
 	if err != nil {
-		log.Fatalf("error creando registry de consul: %v", err)
+		log.Fatalf("error mostrando configuración: %v", err)
 	}
-
-	ctx := context.Background()
-	instanceID := registrypkg.GenerateInstanceID(serviceName)
-
-	// Dirección que se **publica** en Consul para que otros clientes la usen.
-	// En local: localhost
-	// En Docker (misma red que Consul): el nombre del contenedor (p.ej. auth-server)
-	host := os.Getenv("SERVICE_HOST")
-	if host == "" {
-		// Fallback sensato: en local funciona; en Docker deberías pasar SERVICE_HOST=auth-server
-		host = "localhost"
+	if printConfig {
+		fmt.Print(dump)
+		return
 	}
-	hostPort := fmt.Sprintf("%s:%d", host, port)
+	log.Printf("Configuración efectiva:\n%s", dump)
 
-	// Registro en Consul (nota: 0.0.0.0 NO es válido para Consul)
-	if err := reg.Register(ctx, instanceID, serviceName, hostPort); err != nil {
-		log.Fatalf("error registrando servicio en consul: %v", err)
+	// Registry, /healthz, /readyz, latido y apagado ordenado los maneja pkg/service;
+	// el nombre se puede cambiar con SERVICE_NAME si es que subo mas de 1.
+	svc, err := service.New(cfg.Service)
+	if err != nil {
+		log.Fatalf("error iniciando servicio: %v", err)
 	}
-	log.Printf("%s registrado en Consul con ID=%s y address=%s", serviceName, instanceID, hostPort)
+	reg := svc.Registry
+	ctx := context.Background()
 
-	// Health TTL (latido): marca el servicio como "passing" periódicamente
-	go func() {
-		t := time.NewTicker(3 * time.Second)
-		defer t.Stop()
-		for range t.C {
-			if err := reg.ReportHealthyState(instanceID, serviceName); err != nil {
-				log.Printf("Failed to report healthy state: %v", err)
-			}
+	//Crear lo nesesario 
+	// Almacenamiento de usuarios: memory se pierde al reiniciar, bolt guarda en
+	// un archivo (BOLT_PATH), postgres usa DATABASE_URL; ambos migran al arrancar.
+	// Las sesiones (refresh tokens, revocaciones), los logins fallidos y las
+	// claves de idempotencia van en el mismo almacenamiento, para que un
+	// reinicio no los pierda.
+	var repo controller.AuthRepository
+	var sagas controller.SagaRepository
+	var refreshTokens controller.RefreshTokenRepository
+	var revocations controller.RevocationRepository
+	var loginAttempts controller.LoginAttemptRepository
+	var idempotencyStore idempotency.Store
+	switch cfg.Storage.Backend {
+	case "memory":
+		repo = memory.New()
+		sagas = memory.NewSagaRepository()
+		refreshTokens = memory.NewRefreshTokenRepository()
+		revocations = memory.NewRevocationRepository()
+		loginAttempts = memory.NewLoginAttemptRepository()
+		idempotencyStore = idempotency.NewMemoryStore()
+	case "bolt":
+		path := cfg.Storage.BoltPath
+		if path == "" {
+			path = svc.Name + ".db"
 		}
-	}()
+		boltRepo, err := bolt.Open(path)
+		if err != nil {
+			log.Fatalf("error abriendo base bolt: %v", err)
+		}
+		svc.OnShutdown(boltRepo.Close)
+		repo, sagas = boltRepo, boltRepo
+		refreshTokens, revocations, loginAttempts = boltRepo, boltRepo, boltRepo
+		idempotencyStore = boltRepo.IdempotencyStore()
+		log.Printf("Usuarios guardados en %s", path)
+	case "postgres":
+		pgRepo, err := postgres.Open(ctx, cfg.Storage.DatabaseURL)
+		if err != nil {
+			log.Fatalf("error conectando a postgres: %v", err)
+		}
+		svc.OnShutdown(pgRepo.Close)
+		repo, sagas = pgRepo, pgRepo
+		refreshTokens, revocations, loginAttempts = pgRepo, pgRepo, pgRepo
+		idempotencyStore = pgRepo.IdempotencyStore()
+		log.Printf("Usuarios guardados en PostgreSQL")
+	default:
+		log.Fatalf("backend de almacenamiento desconocido: %q", cfg.Storage.Backend)
+	}
 
-	// Deregistro limpio al salir por señal (Ctrl c /SIGTERM) se borra en consul 
-	stop := make(chan os.Signal, 1)
-	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
-	go func() {
-		<-stop
-		log.Println("Recibida señal, deregistrando servicio en Consul...")
-		_ = reg.Deregister(context.Background(), instanceID, serviceName)
-		os.Exit(0)
-	}()
+	// Llaves de firma: JWT_ALG (RS256 por defecto, EdDSA o HS256 con JWT_SECRET
+	// o JWT_SECRET_FILE); con JWT_KEY_DIR se cargan los *.pem de ese directorio,
+	// si no se genera una al arrancar. Sin una llave válida no se arranca.
+	secret, err := cfg.JWT.LoadSecret()
+	if err != nil {
+		log.Fatalf("error cargando llaves de firma: %v", err)
+	}
+	signingKeys, err := keys.Load(cfg.JWT.Alg, cfg.JWT.KeyDir, secret)
+	if err != nil {
+		log.Fatalf("error cargando llaves de firma: %v", err)
+	}
+	// Las llaves retiradas aceptan access tokens lo que dura uno, pero se
+	// guardan lo que dura un enlace de verificación para poder verificarlo.
+	keySet := keys.NewSet(cfg.JWT.AccessTokenTTL, signingKeys...)
+	keySet.KeepRetired(cfg.EmailVerification.TokenTTL)
+	log.Printf("Firmando tokens con %s (kid=%s)", keySet.Current().Algorithm, keySet.Current().ID)
+	// Con SIGHUP se vuelven a leer el archivo del secreto o el directorio de
+	// llaves; la llave más nueva pasa a ser la actual y la anterior se sigue
+	// aceptando hasta que venzan sus tokens.
+	if cfg.JWT.SecretFile != "" || cfg.JWT.KeyDir != "" {
+		svc.OnReload(func() error {
+			secret, err := cfg.JWT.LoadSecret()
+			if err != nil {
+				return fmt.Errorf("recargando llaves de firma: %w", err)
+			}
+			key, err := keySet.Reload(cfg.JWT.Alg, cfg.JWT.KeyDir, secret)
+			if err != nil {
+				return fmt.Errorf("recargando llaves de firma: %w", err)
+			}
+			log.Printf("Firmando tokens con %s (kid=%s)", key.Algorithm, key.ID)
+			return nil
+		})
+	}
 
-	//Crear lo nesesario 
-	repo := memory.New()
-	ctrl := controller.New(repo)
-	h := handler.New(ctrl, reg) // handler necesita el registry para llamar a metadata-user
+	// Contraseñas filtradas: la lista que viene con el servicio o BREACHED_PASSWORDS_FILE.
+	var breached controller.BreachedPasswords = password.DefaultBreached()
+	switch cfg.BreachedPasswords {
+	case "":
+	case "none":
+		breached = nil
+	default:
+		list, err := password.OpenBreached(cfg.BreachedPasswords)
+		if err != nil {
+			log.Fatalf("error cargando contraseñas filtradas: %v", err)
+		}
+		breached = list
+	}
 
-	// Rutas 
-	mux := http.NewServeMux()
-	mux.Handle("/Auth-Server/register", http.HandlerFunc(h.RegisterUser))
-	mux.Handle("/Auth-Server/login", http.HandlerFunc(h.Login))
-
-	//Servidor HTTP
-	srv := &http.Server{
-		Addr:         fmt.Sprintf(":%d", port),
-		Handler:      mux,
-		ReadTimeout:  10 * time.Second, // tiempo máx. para leer la petición
-		WriteTimeout: 15 * time.Second, // tiempo máx. para escribir la respuesta
-		IdleTimeout:  60 * time.Second, // tiempo máx. de conexión inactiva (keep-alive)
+	// Verificación del email: el enlace apunta a PUBLIC_URL o, si no está, a
+	// este host y puerto; MAIL_BACKEND=file deja los emails en MAIL_DIR.
+	verification := cfg.EmailVerification
+	if verification.PublicURL == "" {
+		verification.PublicURL = fmt.Sprintf("http://%s:%d", cfg.Service.Host, cfg.Service.Port)
 	}
 
-	// Graceful shutdown adicional (opcional): cierre ordenado del server
+	ctrl := controller.New(repo, refreshTokens, revocations, keySet,
+		controller.WithAccessTokenTTL(cfg.JWT.AccessTokenTTL),
+		controller.WithRefreshTokenTTL(cfg.JWT.RefreshTokenTTL),
+		controller.WithPasswordHashers(cfg.PasswordHash.Hashers()),
+		controller.WithPasswordPolicy(cfg.Password),
+		controller.WithBreachedPasswords(breached),
+		controller.WithLoginAttempts(loginAttempts),
+		controller.WithLockoutPolicy(cfg.Login),
+		controller.WithMailSender(cfg.Mail.Sender()),
+		controller.WithVerificationPolicy(verification),
+		controller.WithRevocationsToken(cfg.RevocationsToken),
+	)
+
+	// BOOTSTRAP_ADMIN promueve a admin a un usuario ya registrado al arrancar,
+	// para poder usar los endpoints de administración la primera vez.
+	if adminEmail := cfg.BootstrapAdmin; adminEmail != "" {
+		if err := ctrl.SetRole(ctx, adminEmail, auth.RoleAdmin); err != nil {
+			log.Printf("No se pudo promover a %s como admin: %v", adminEmail, err)
+		}
+	}
+	// METADATA_LB=least-inflight manda cada llamada a la instancia menos cargada;
+	// por defecto se reparten en round-robin.
+	var balancer metadataclient.Balancer = metadataclient.NewRoundRobin()
+	if cfg.Metadata.Balancer == "least-inflight" {
+		balancer = metadataclient.NewLeastInflight()
+	}
+	// METADATA_TAGS (p.ej. version=v2) limita las llamadas a esas instancias durante un despliegue.
+	var metadataQuery []registrypkg.QueryOption
+	for _, t := range cfg.Metadata.Tags {
+		metadataQuery = append(metadataQuery, registrypkg.WithTag(t))
+	}
+	metadata := metadataclient.New(reg,
+		metadataclient.WithBalancer(balancer),
+		metadataclient.WithTags(cfg.Metadata.Tags...),
+		metadataclient.WithTimeout(cfg.Metadata.Timeout),
+		metadataclient.WithRetries(cfg.Metadata.MaxAttempts, cfg.Metadata.Backoff),
+		metadataclient.WithBreakers(cfg.Metadata.Breaker.Config()),
+	)
+
+	svc.Health.Add("repository", health.Critical, health.PingCheck(repo))
+	svc.Health.Add(metadataclient.ServiceName, health.Warning, health.ResolvableCheck(reg, metadataclient.ServiceName, metadataQuery...))
+
+	// El registro es una saga (usuario de auth + metadatos); al arrancar se
+	// terminan o deshacen las que un reinicio dejó a medias.
+	registration := controller.NewRegistration(ctrl, sagas, metadatauser.New(metadata))
 	go func() {
-		<-stop
-		log.Println("Shutting down HTTP server...")
-		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-		defer cancel()
-		_ = srv.Shutdown(ctx)
+		if err := registration.Resume(context.Background()); err != nil {
+			log.Printf("Error retomando sagas de registro: %v", err)
+		}
 	}()
+	h := handler.New(ctrl, registration)
+	// Reintentos del registro con la misma Idempotency-Key reciben la primera respuesta.
+	idempotent := idempotency.Middleware(idempotencyStore, idempotency.DefaultRetention)
 
-	log.Printf("%s escuchando en %s", serviceName, srv.Addr)
-	if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-		log.Fatalf("server error: %v", err)
+	// Rutas 
+	mux := svc.Mux
+	// Límites por ruta (rate_limits en la config). metadata-user guarda en
+	// caché jwks y revoked, así que el "default" por IP le alcanza.
+	limit := ratelimit.New(ratelimit.NewMemoryStore(), cfg.RateLimits).Wrap
+	mux.Handle("/Auth-Server/register", limit("register", idempotent(http.HandlerFunc(h.RegisterUser))))
+	mux.Handle("/Auth-Server/login", limit("login", http.HandlerFunc(h.Login)))
+	mux.Handle(controller.VerifyEmailPath, limit("verify-email", http.HandlerFunc(h.VerifyEmail)))
+	mux.Handle("/Auth-Server/resend-verification", limit("resend-verification", http.HandlerFunc(h.ResendVerification)))
+	mux.Handle("/Auth-Server/refresh", limit("refresh", http.HandlerFunc(h.Refresh)))
+	mux.Handle("/Auth-Server/logout", limit("logout", http.HandlerFunc(h.Logout)))
+	mux.Handle("/Auth-Server/revoked", limit("revoked", http.HandlerFunc(h.Revoked)))
+	mux.Handle("/Auth-Server/admin/revoke-sessions", limit("admin", http.HandlerFunc(h.RevokeSessions)))
+	mux.Handle("/Auth-Server/admin/rotate-key", limit("admin", http.HandlerFunc(h.RotateKey)))
+	mux.Handle("/Auth-Server/admin/role", limit("admin", http.HandlerFunc(h.SetRole)))
+	mux.Handle("/.well-known/jwks.json", limit("jwks", http.HandlerFunc(h.JWKS)))
+	mux.Handle("/debug/vars", limit("admin", http.HandlerFunc(h.Metrics))) // métricas (estado de los circuit breakers)
+
+	if err := svc.Run(ctx); err != nil {
+		log.Fatalf("%s: %v", svc.Name, err)
 	}
 }
@@ -0,0 +1,223 @@
+// Package config es la configuración de auth-server: valores por defecto,
+// archivo YAML (-config o CONFIG_FILE), variables de entorno y flags.
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"time"
+
+	"proyecto/auth-server/internal/controller"
+	"proyecto/auth-server/internal/keys"
+	"proyecto/auth-server/internal/mail"
+	"proyecto/auth-server/internal/password"
+	"proyecto/pkg/auth"
+	"proyecto/pkg/breaker"
+	configpkg "proyecto/pkg/config"
+	"proyecto/pkg/ratelimit"
+	"proyecto/pkg/service"
+	"proyecto/pkg/storage"
+)
+
+const ServiceName = "auth-server"
+
+type Config struct {
+	Service  service.Config `yaml:"service"`
+	Storage  storage.Config `yaml:"storage"`
+	JWT      JWT            `yaml:"jwt"`
+	Metadata Metadata       `yaml:"metadata"`
+
+	Password password.Policy `yaml:"password"`
+	// BreachedPasswords es la lista de contraseñas filtradas (SHA-1, formato de
+	// Have I Been Pwned); vacío = la que viene con el servicio, none = sin chequeo.
+	BreachedPasswords string `yaml:"breached_passwords" env:"BREACHED_PASSWORDS_FILE"`
+
+	Login controller.LockoutPolicy `yaml:"login"`
+	// RateLimits son los límites por ruta (login, register, ...); "default"
+	// se aplica a las rutas sin uno propio y requests: 0 quita el límite.
+	RateLimits map[string]ratelimit.Limit `yaml:"rate_limits"`
+
+	PasswordHash password.HashConfig `yaml:"password_hash"`
+
+	EmailVerification controller.VerificationPolicy `yaml:"email_verification"`
+	Mail              mail.Config                   `yaml:"mail"`
+	// RevocationsToken es la credencial con la que los otros servicios bajan la
+	// lista de revocación; sin ella solo se puede con un token con revocations:read.
+	RevocationsToken string `yaml:"revocations_token" env:"REVOCATIONS_TOKEN" secret:"true"`
+	// BootstrapAdmin promueve a admin a ese usuario (ya registrado) al arrancar.
+	BootstrapAdmin string `yaml:"bootstrap_admin" env:"BOOTSTRAP_ADMIN"`
+}
+
+type JWT struct {
+	Alg    string `yaml:"alg" env:"JWT_ALG"` // RS256 (por defecto), EdDSA o HS256
+	Secret string `yaml:"secret" env:"JWT_SECRET" secret:"true"`
+	// SecretFile es un archivo con el secreto (p.ej. /run/secrets/jwt_secret);
+	// se vuelve a leer con SIGHUP.
+	SecretFile string `yaml:"secret_file" env:"JWT_SECRET_FILE"`
+	// KeyDir tiene los *.pem de firma; vacío = se genera una llave al arrancar,
+	// que solo conoce esa instancia. Con postgres es obligatorio.
+	KeyDir          string        `yaml:"key_dir" env:"JWT_KEY_DIR"`
+	AccessTokenTTL  time.Duration `yaml:"access_token_ttl" env:"ACCESS_TOKEN_TTL"`
+	RefreshTokenTTL time.Duration `yaml:"refresh_token_ttl" env:"REFRESH_TOKEN_TTL"`
+}
+
+// Metadata es la configuración del cliente de metadata-user.
+type Metadata struct {
+	Balancer    string        `yaml:"balancer" env:"METADATA_LB"`     // round-robin o least-inflight
+	Tags        []string      `yaml:"tags" env:"METADATA_TAGS"`       // p.ej. version=v2 durante un despliegue
+	Timeout     time.Duration `yaml:"timeout" env:"METADATA_TIMEOUT"` // por intento
+	MaxAttempts int           `yaml:"max_attempts" env:"METADATA_MAX_ATTEMPTS"`
+	Backoff     time.Duration `yaml:"backoff" env:"METADATA_BACKOFF"`
+	Breaker     Breaker       `yaml:"breaker"`
+}
+
+// Breaker son los umbrales de los circuit breakers hacia metadata-user (uno
+// por instancia y otro por servicio).
+type Breaker struct {
+	FailureRatio   float64       `yaml:"failure_ratio" env:"METADATA_BREAKER_FAILURE_RATIO"` // entre 0 y 1
+	MinRequests    int           `yaml:"min_requests" env:"METADATA_BREAKER_MIN_REQUESTS"`
+	Window         time.Duration `yaml:"window" env:"METADATA_BREAKER_WINDOW"`
+	OpenTimeout    time.Duration `yaml:"open_timeout" env:"METADATA_BREAKER_OPEN_TIMEOUT"`
+	HalfOpenProbes int           `yaml:"half_open_probes" env:"METADATA_BREAKER_HALF_OPEN_PROBES"`
+}
+
+// Config pasa los umbrales a la configuración de pkg/breaker.
+func (b Breaker) Config() breaker.Config {
+	return breaker.Config{
+		FailureRatio:   b.FailureRatio,
+		MinRequests:    b.MinRequests,
+		Window:         b.Window,
+		OpenTimeout:    b.OpenTimeout,
+		HalfOpenProbes: b.HalfOpenProbes,
+	}
+}
+
+func Default() Config {
+	return Config{
+		Service: service.DefaultConfig(ServiceName, 8082),
+		Storage: storage.DefaultConfig(),
+		JWT: JWT{
+			Alg:             keys.RS256,
+			AccessTokenTTL:  controller.DefaultAccessTokenTTL,
+			RefreshTokenTTL: controller.DefaultRefreshTokenTTL,
+		},
+		Metadata: Metadata{
+			Balancer:    "round-robin",
+			Timeout:     5 * time.Second,
+			MaxAttempts: 3,
+			Backoff:     100 * time.Millisecond,
+			Breaker: Breaker{
+				FailureRatio:   breaker.DefaultConfig.FailureRatio,
+				MinRequests:    breaker.DefaultConfig.MinRequests,
+				Window:         breaker.DefaultConfig.Window,
+				OpenTimeout:    breaker.DefaultConfig.OpenTimeout,
+				HalfOpenProbes: breaker.DefaultConfig.HalfOpenProbes,
+			},
+		},
+		Password: password.DefaultPolicy(),
+		Login:    controller.DefaultLockoutPolicy(),
+		RateLimits: map[string]ratelimit.Limit{
+			"default":  {Algorithm: ratelimit.TokenBucket, By: ratelimit.ByIP, Requests: 120, Window: time.Minute, Burst: 60},
+			"login":    {Algorithm: ratelimit.SlidingWindow, By: ratelimit.ByIP, Requests: 20, Window: time.Minute},
+			"register": {Algorithm: ratelimit.TokenBucket, By: ratelimit.ByIP, Requests: 10, Window: time.Hour, Burst: 5},
+			// Además del mínimo por email (email_verification.resend_interval).
+			"resend-verification": {Algorithm: ratelimit.SlidingWindow, By: ratelimit.ByIP, Requests: 10, Window: time.Hour},
+		},
+		PasswordHash:      password.DefaultHashConfig(),
+		EmailVerification: controller.DefaultVerificationPolicy(),
+		Mail:              mail.DefaultConfig(),
+	}
+}
+
+// ApplyFlags pisa la configuración con los flags que se pasaron en fs
+// (-port y -storage); los que no se pasaron no cambian nada, así el archivo y
+// las variables de entorno mandan sobre sus valores por defecto.
+func (c *Config) ApplyFlags(fs *flag.FlagSet) {
+	fs.Visit(func(f *flag.Flag) {
+		getter, ok := f.Value.(flag.Getter)
+		if !ok {
+			return
+		}
+		switch f.Name {
+		case "port":
+			c.Service.Port, _ = getter.Get().(int)
+		case "storage":
+			c.Storage.Backend, _ = getter.Get().(string)
+		}
+	})
+}
+
+// Validate devuelve todos los errores juntos, para corregirlos de una vez.
+func (c Config) Validate() error {
+	errs := []error{c.Service.Validate(), c.Storage.Validate(), c.Password.Validate(), c.Login.Validate()}
+	switch c.JWT.Alg {
+	case keys.RS256, keys.EdDSA:
+		// Con postgres puede haber varias instancias detrás del mismo
+		// balanceador: todas tienen que firmar con las mismas llaves o los
+		// tokens de una no se verifican con el JWKS de otra.
+		if c.Storage.Backend == "postgres" && c.JWT.KeyDir == "" {
+			errs = append(errs, errors.New("jwt.key_dir (JWT_KEY_DIR) es obligatorio con storage.backend postgres, para que todas las instancias compartan las llaves"))
+		}
+	case keys.HS256:
+		switch {
+		case c.JWT.KeyDir != "":
+			errs = append(errs, errors.New("jwt.key_dir (JWT_KEY_DIR) no se usa con HS256: la llave es jwt.secret o jwt.secret_file"))
+		case c.JWT.Secret == "" && c.JWT.SecretFile == "":
+			errs = append(errs, errors.New("jwt.secret (JWT_SECRET) o jwt.secret_file (JWT_SECRET_FILE) es obligatorio con HS256"))
+		case c.JWT.Secret != "" && c.JWT.SecretFile != "":
+			errs = append(errs, errors.New("jwt.secret y jwt.secret_file no se pueden usar juntos"))
+		case c.JWT.Secret != "":
+			if err := auth.CheckSecret([]byte(c.JWT.Secret)); err != nil {
+				errs = append(errs, fmt.Errorf("jwt.secret: %w", err))
+			}
+		}
+	default:
+		errs = append(errs, fmt.Errorf("jwt.alg desconocido: %q (RS256, EdDSA o HS256)", c.JWT.Alg))
+	}
+	if c.JWT.AccessTokenTTL <= 0 {
+		errs = append(errs, errors.New("jwt.access_token_ttl debe ser mayor que 0"))
+	}
+	if c.JWT.RefreshTokenTTL <= c.JWT.AccessTokenTTL {
+		errs = append(errs, errors.New("jwt.refresh_token_ttl debe ser mayor que jwt.access_token_ttl"))
+	}
+	for name, l := range c.RateLimits {
+		if err := l.Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("rate_limits.%s: %w", name, err))
+		}
+	}
+	errs = append(errs, c.PasswordHash.Validate(), c.EmailVerification.Validate(), c.Mail.Validate())
+	if c.PasswordHash.Algorithm == password.AlgBcrypt && c.Password.MaxLength > password.BcryptMaxLength {
+		errs = append(errs, fmt.Errorf("password.max_length (PASSWORD_MAX_LENGTH) no puede pasar de %d con bcrypt", password.BcryptMaxLength))
+	}
+	if c.RevocationsToken != "" && len(c.RevocationsToken) < auth.MinSecretLength {
+		errs = append(errs, fmt.Errorf("revocations_token debe tener al menos %d bytes", auth.MinSecretLength))
+	}
+	switch c.Metadata.Balancer {
+	case "round-robin", "least-inflight":
+	default:
+		errs = append(errs, fmt.Errorf("metadata.balancer desconocido: %q (round-robin o least-inflight)", c.Metadata.Balancer))
+	}
+	if c.Metadata.Timeout <= 0 {
+		errs = append(errs, errors.New("metadata.timeout debe ser mayor que 0"))
+	}
+	if c.Metadata.MaxAttempts < 1 {
+		errs = append(errs, errors.New("metadata.max_attempts debe ser al menos 1"))
+	}
+	if err := c.Metadata.Breaker.Config().Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("metadata.breaker: %w", err))
+	}
+	return errors.Join(errs...)
+}
+
+// LoadSecret devuelve el secreto HS256, leyendo SecretFile si se configuró.
+func (j JWT) LoadSecret() ([]byte, error) {
+	if j.SecretFile == "" {
+		return []byte(j.Secret), nil
+	}
+	secret, err := configpkg.ReadSecretFile(j.SecretFile)
+	if err != nil {
+		return nil, fmt.Errorf("jwt.secret_file: %w", err)
+	}
+	return []byte(secret), nil
+}
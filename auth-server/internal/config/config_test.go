@@ -0,0 +1,141 @@
+package config
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	configpkg "proyecto/pkg/config"
+)
+
+func TestDefaultIsValid(t *testing.T) {
+	cfg := Default()
+	cfg.Service.Registry.Backend = "memory"
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Default().Validate() = %v", err)
+	}
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	err := os.WriteFile(path, []byte("service:\n  port: 9000\n  version: v-archivo\nstorage:\n  backend: bolt\n"), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("SERVICE_PORT", "9100")
+	t.Setenv("SERVICE_VERSION", "v-entorno")
+
+	cfg := Default()
+	if err := configpkg.Load(path, &cfg); err != nil {
+		t.Fatal(err)
+	}
+	fs := flag.NewFlagSet("auth-server", flag.ContinueOnError)
+	fs.Int("port", 8082, "")
+	fs.String("storage", "memory", "")
+	if err := fs.Parse([]string{"-port", "9200"}); err != nil {
+		t.Fatal(err)
+	}
+	cfg.ApplyFlags(fs)
+
+	if cfg.Service.Port != 9200 {
+		t.Errorf("port = %d, quería el del flag (pisa al entorno y al archivo)", cfg.Service.Port)
+	}
+	if cfg.Service.Version != "v-entorno" {
+		t.Errorf("version = %q, quería la del entorno", cfg.Service.Version)
+	}
+	// -storage no se pasó: su valor por defecto no pisa al archivo.
+	if cfg.Storage.Backend != "bolt" {
+		t.Errorf("storage = %q, quería el del archivo", cfg.Storage.Backend)
+	}
+}
+
+func TestValidateReportsAllErrors(t *testing.T) {
+	cfg := Default()
+	cfg.Service.Registry.Backend = "memory"
+	cfg.JWT.Alg = "none"
+	cfg.JWT.AccessTokenTTL = 0
+	cfg.Metadata.Balancer = "random"
+	cfg.RevocationsToken = "corto"
+	err := cfg.Validate()
+	for _, want := range []string{"jwt.alg", "jwt.access_token_ttl", "metadata.balancer", "revocations_token"} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Validate = %v, falta el error de %s", err, want)
+		}
+	}
+}
+
+func TestValidateSharedKeys(t *testing.T) {
+	cfg := Default()
+	cfg.Service.Registry.Backend = "memory"
+	cfg.Storage.Backend = "postgres"
+	cfg.Storage.DatabaseURL = "postgres://localhost/auth"
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "jwt.key_dir") {
+		t.Errorf("postgres sin key_dir = %v, quería el error de jwt.key_dir", err)
+	}
+	cfg.JWT.KeyDir = "/run/secrets/jwt_keys"
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("postgres con key_dir = %v", err)
+	}
+}
+
+func TestRedactedHidesSecrets(t *testing.T) {
+	cfg := Default()
+	cfg.JWT.Secret = "secreto-jwt-de-32-bytes-al-menos!!"
+	cfg.RevocationsToken = "token-de-revocaciones-de-32-bytes!"
+	cfg.Storage.DatabaseURL = "postgres://auth:clave@db/auth"
+	out, err := configpkg.Redacted(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, secret := range []string{cfg.JWT.Secret, cfg.RevocationsToken, "clave@db"} {
+		if strings.Contains(out, secret) {
+			t.Errorf("Redacted muestra %q:\n%s", secret, out)
+		}
+	}
+	if !strings.Contains(out, "access_token_ttl: 15m0s") {
+		t.Errorf("Redacted no muestra la configuración normal:\n%s", out)
+	}
+}
+
+func TestValidateHS256KeyDir(t *testing.T) {
+	cfg := Default()
+	cfg.Service.Registry.Backend = "memory"
+	cfg.JWT.Alg = "HS256"
+	cfg.JWT.Secret = strings.Repeat("s", 32)
+	cfg.JWT.KeyDir = "/run/secrets/jwt_keys"
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "jwt.key_dir") {
+		t.Errorf("HS256 con key_dir = %v, quería el error de jwt.key_dir", err)
+	}
+}
+
+func TestLoadSecret(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jwt_secret")
+	if err := os.WriteFile(path, []byte("secreto-del-archivo\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	for name, tc := range map[string]struct {
+		jwt     JWT
+		want    string
+		wantErr bool
+	}{
+		"del entorno":         {JWT{Secret: "secreto-del-entorno"}, "secreto-del-entorno", false},
+		"del archivo":         {JWT{SecretFile: path}, "secreto-del-archivo", false},
+		"sin secreto":         {JWT{}, "", false},
+		"archivo inexistente": {JWT{SecretFile: path + ".no"}, "", true},
+	} {
+		got, err := tc.jwt.LoadSecret()
+		if (err != nil) != tc.wantErr || string(got) != tc.want {
+			t.Errorf("%s: LoadSecret = %q, %v", name, got, err)
+		}
+	}
+
+	// Se relee en cada llamada, para la recarga con SIGHUP.
+	if err := os.WriteFile(path, []byte("secreto-rotado"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := (JWT{SecretFile: path}).LoadSecret(); string(got) != "secreto-rotado" {
+		t.Errorf("LoadSecret tras rotar el archivo = %q", got)
+	}
+}
@@ -1,65 +1,229 @@
-package controller 
+package controller
 
 import (
-    "context"
+	"context"
 	"errors"
-    "time"
-    "github.com/golang-jwt/jwt/v5"
-    "golang.org/x/crypto/bcrypt"
+	"github.com/golang-jwt/jwt/v5"
+	"time"
 
-    "proyecto/auth-server/pkg/model"
-    
-    
+	"proyecto/auth-server/internal/keys"
+	"proyecto/auth-server/internal/mail"
+	"proyecto/auth-server/internal/password"
+	"proyecto/auth-server/pkg/model"
+	"proyecto/pkg/auth"
 )
 
-//error personal 
+// error personal
 var ErrNotFound = errors.New("Not found")
 
+// DefaultAccessTokenTTL es la vida de un access token si no se configura otra;
+// las llaves retiradas se conservan al menos este tiempo para poder verificar lo que firmaron.
+const DefaultAccessTokenTTL = 15 * time.Minute
+
+// AccessTokenAudience son los servicios que aceptan los access tokens.
+var AccessTokenAudience = []string{"auth-server", "metadata-user"}
+
 type AuthRepository interface {
-    GetHashByEmail(ctx context.Context, email string) (*model.AuthUser, error)
-    Put(ctx context.Context, AuthUser *model.AuthUser) (error)
+	GetHashByEmail(ctx context.Context, email string) (*model.AuthUser, error)
+	Put(ctx context.Context, AuthUser *model.AuthUser) error
+	Create(ctx context.Context, AuthUser *model.AuthUser) error
+	Delete(ctx context.Context, email string) error
+
+	// Cambios de un solo campo, para no pisar otros cambios simultáneos del
+	// mismo usuario (p.ej. un rehash en el login y un cambio de rol).
+	// Devuelven repository.ErrNotFound si el usuario no existe.
+	UpdatePasswordHash(ctx context.Context, email, hash string) error
+	SetRole(ctx context.Context, email, role string) error
+	MarkVerified(ctx context.Context, email string) error
+	SetVerificationSentAt(ctx context.Context, email string, at time.Time) error
+}
+
+// RefreshTokenRepository guarda los refresh tokens emitidos (solo su hash).
+type RefreshTokenRepository interface {
+	GetRefreshToken(ctx context.Context, tokenHash string) (*model.RefreshToken, error)
+	PutRefreshToken(ctx context.Context, token *model.RefreshToken) error
+	MarkRefreshTokenUsed(ctx context.Context, tokenHash string) error
+	RevokeFamily(ctx context.Context, familyID string) error
+	RevokeByEmail(ctx context.Context, email string) error
+}
+
+// RevocationRepository lleva los access tokens emitidos y la lista de jti revocados.
+type RevocationRepository interface {
+	TrackIssued(ctx context.Context, token *model.IssuedToken) error
+	ListIssuedByEmail(ctx context.Context, email string) ([]*model.IssuedToken, error)
+	Revoke(ctx context.Context, token *model.RevokedToken) error
+	IsRevoked(ctx context.Context, jti string) (bool, error)
+	ListRevoked(ctx context.Context) ([]*model.RevokedToken, error)
+}
+
+// BreachedPasswords responde si una contraseña aparece en filtraciones conocidas.
+type BreachedPasswords interface {
+	Breached(ctx context.Context, password string) (bool, error)
 }
 
 type Controller struct {
-	repo AuthRepository
+	repo       AuthRepository
+	tokens     RefreshTokenRepository
+	revoked    RevocationRepository
+	keys       *keys.Set
+	accessTTL  time.Duration
+	refreshTTL time.Duration
+	hashers    *password.Hashers
+	policy     password.Policy
+	breached   BreachedPasswords
+	attempts   LoginAttemptRepository
+	lockout    LockoutPolicy
+	dummyHash  string
+
+	mail         mail.Sender
+	verification VerificationPolicy
+	serviceToken string
 }
 
-func New(repo AuthRepository) *Controller {
-	return &Controller{repo: repo}
+type Option func(*Controller)
+
+// WithAccessTokenTTL cambia la vida de los access tokens (DefaultAccessTokenTTL).
+func WithAccessTokenTTL(ttl time.Duration) Option {
+	return func(c *Controller) { c.accessTTL = ttl }
 }
 
+// WithRefreshTokenTTL cambia la vida de los refresh tokens (DefaultRefreshTokenTTL).
+func WithRefreshTokenTTL(ttl time.Duration) Option {
+	return func(c *Controller) { c.refreshTTL = ttl }
+}
 
+// WithPasswordHashers cambia cómo se hashean y verifican las contraseñas
+// (por defecto Argon2id, verificando también las de bcrypt).
+func WithPasswordHashers(h *password.Hashers) Option {
+	return func(c *Controller) { c.hashers = h }
+}
+
+// WithPasswordPolicy cambia la política de contraseñas (password.DefaultPolicy).
+func WithPasswordPolicy(p password.Policy) Option {
+	return func(c *Controller) { c.policy = p }
+}
+
+// WithBreachedPasswords cambia la lista de contraseñas filtradas
+// (password.DefaultBreached); nil desactiva el chequeo.
+func WithBreachedPasswords(b BreachedPasswords) Option {
+	return func(c *Controller) { c.breached = b }
+}
+
+// WithLoginAttempts guarda los logins fallidos en attempts para bloquear los
+// ataques de fuerza bruta (sin esto no hay bloqueo).
+func WithLoginAttempts(attempts LoginAttemptRepository) Option {
+	return func(c *Controller) { c.attempts = attempts }
+}
+
+// WithLockoutPolicy cambia cuándo y cuánto se bloquea un email (DefaultLockoutPolicy).
+func WithLockoutPolicy(p LockoutPolicy) Option {
+	return func(c *Controller) { c.lockout = p }
+}
+
+// WithMailSender cambia por dónde salen los emails (por defecto, al log).
+func WithMailSender(s mail.Sender) Option {
+	return func(c *Controller) { c.mail = s }
+}
+
+// WithVerificationPolicy cambia la verificación del email (DefaultVerificationPolicy).
+func WithVerificationPolicy(p VerificationPolicy) Option {
+	return func(c *Controller) { c.verification = p }
+}
+
+// WithRevocationsToken es la credencial con la que otros servicios bajan la
+// lista de revocación sin un access token.
+func WithRevocationsToken(token string) Option {
+	return func(c *Controller) { c.serviceToken = token }
+}
+
+func New(repo AuthRepository, tokens RefreshTokenRepository, revoked RevocationRepository, keySet *keys.Set, opts ...Option) *Controller {
+	c := &Controller{
+		repo:       repo,
+		tokens:     tokens,
+		revoked:    revoked,
+		keys:       keySet,
+		accessTTL:  DefaultAccessTokenTTL,
+		refreshTTL: DefaultRefreshTokenTTL,
+		hashers:    password.DefaultHashConfig().Hashers(),
+		policy:     password.DefaultPolicy(),
+		breached:   password.DefaultBreached(),
+		lockout:    DefaultLockoutPolicy(),
+
+		mail:         mail.LogSender{},
+		verification: DefaultVerificationPolicy(),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	c.dummyHash = newDummyHash(c.hashers)
+	return c
+}
+
+// AccessTokenTTL es la vida de los access tokens que emite el controlador.
+func (c *Controller) AccessTokenTTL() time.Duration {
+	return c.accessTTL
+}
+
+// ValidatePassword comprueba una contraseña nueva contra la política y la
+// lista de filtradas. Si no cumple devuelve un *password.ValidationError con
+// todas las reglas que falló.
+func (c *Controller) ValidatePassword(ctx context.Context, email, pw string) error {
+	violations := c.policy.Check(email, pw)
+	if c.breached != nil && pw != "" {
+		breached, err := c.breached.Breached(ctx, pw)
+		if err != nil {
+			return err
+		}
+		if breached {
+			violations = append(violations, password.Violation{
+				Rule:    password.RuleBreached,
+				Message: "Aparece en filtraciones de contraseñas conocidas.",
+			})
+		}
+	}
+	if len(violations) > 0 {
+		return &password.ValidationError{Violations: violations}
+	}
+	return nil
+}
+
+// HashPassword hashea una contraseña nueva con el algoritmo actual.
+func (c *Controller) HashPassword(password string) (string, error) {
+	return c.hashers.Hash(password)
+}
+
+// CheckPasswordHash compara password con un hash de cualquiera de los algoritmos conocidos.
 func (c *Controller) CheckPasswordHash(password, hash string) bool {
-    err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
-    return err == nil
-}
-
-func (c *Controller) GenerateAccessToken(secret []byte, sub, email, role, jti string, ttl time.Duration) (string, error) {
-    now := time.Now()
-    claims := AccessClaims{
-        Sub:   sub,
-        Email: email,
-        Role:  role,
-        RegisteredClaims: jwt.RegisteredClaims{
-            ID:        jti,                      // jti
-            Issuer:    "auth-service",          // iss (opcional)
-            IssuedAt:  jwt.NewNumericDate(now), // iat
-            ExpiresAt: jwt.NewNumericDate(now.Add(ttl)), // exp
-            // Audience: []string{"user-service"}, // aud (opcional)
-        },
-    }
-    token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-    return token.SignedString(secret)
+	ok, _, err := c.hashers.Verify(password, hash)
+	return ok && err == nil
 }
 
-// AccessClaims define los claims personalizados de un JWT de acceso.
-type AccessClaims struct {
-	Sub   string `json:"sub"`
-	Email string `json:"email"`
-	Role  string `json:"role"`
-	jwt.RegisteredClaims
+func (c *Controller) GenerateAccessToken(sub, email, role string, emailVerified bool, jti string, ttl time.Duration) (string, error) {
+	now := time.Now()
+	claims := AccessClaims{
+		Sub:           sub,
+		Email:         email,
+		EmailVerified: emailVerified,
+		Role:          role,
+		Permissions:   auth.PermissionsFor(role),
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        jti,                              // jti
+			Issuer:    auth.Issuer,                      // iss
+			IssuedAt:  jwt.NewNumericDate(now),          // iat
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)), // exp
+			Audience:  AccessTokenAudience,              // aud
+		},
+	}
+	key := c.keys.Current()
+	token := jwt.NewWithClaims(key.Method(), claims)
+	token.Header["kid"] = key.ID
+	return token.SignedString(key.SigningKey())
 }
 
+// AccessClaims define los claims personalizados de un JWT de acceso.
+// Viven en pkg/auth para que el resto de servicios los verifique igual.
+type AccessClaims = auth.AccessClaims
+
 func (c *Controller) GetHashByEmail(ctx context.Context, email string) (*model.AuthUser, error) {
 	res, err := c.repo.GetHashByEmail(ctx, email)
 
@@ -78,11 +242,5 @@ func (c *Controller) Put(ctx context.Context, AuthUser *model.AuthUser) (*model.
 	return AuthUser, nil
 }
 
-type MetadataUser struct {
-	Email              string    `json:"email"`
-	FullName           string    `json:"full_name"`
-	AvatarURL          string    `json:"avatar_url"`
-	PhoneNumber        string    `json:"phone_number"`
-	BirthDate          string 	 `json:"birth_date"`
-	LastUpdated        string 	 `json:"last_updated"`
-}
\ No newline at end of file
+// MetadataUser es el perfil que se manda a metadata-user al registrar.
+type MetadataUser = model.MetadataUser
@@ -0,0 +1,49 @@
+package controller
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"proyecto/auth-server/internal/keys"
+	"proyecto/auth-server/internal/repository/memory"
+	"proyecto/auth-server/pkg/model"
+)
+
+// testEnv es un controlador sobre los repositorios en memoria.
+type testEnv struct {
+	ctrl    *Controller
+	users   *memory.Repository
+	tokens  *memory.RefreshTokenRepository
+	revoked *memory.RevocationRepository
+}
+
+func newTestEnv(t *testing.T, opts ...Option) *testEnv {
+	t.Helper()
+	env := &testEnv{
+		users:   memory.New(),
+		tokens:  memory.NewRefreshTokenRepository(),
+		revoked: memory.NewRevocationRepository(),
+	}
+	keySet := keys.NewSet(DefaultAccessTokenTTL, keys.NewHMAC([]byte("secreto-de-prueba-de-32-bytes-!!")))
+	opts = append([]Option{WithBreachedPasswords(nil)}, opts...)
+	env.ctrl = New(env.users, env.tokens, env.revoked, keySet, opts...)
+	return env
+}
+
+// addUser da de alta un usuario verificado con la contraseña pw.
+func (env *testEnv) addUser(t *testing.T, email, pw string) *model.AuthUser {
+	t.Helper()
+	hash, err := env.ctrl.HashPassword(pw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	user := &model.AuthUser{
+		Email: email, PasswordHash: hash, Provider: "local", Role: "user",
+		EmailVerified: true, CreatedAt: time.Now(),
+	}
+	if err := env.users.Create(context.Background(), user); err != nil {
+		t.Fatal(err)
+	}
+	return user
+}
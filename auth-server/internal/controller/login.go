@@ -0,0 +1,195 @@
+package controller
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"proyecto/auth-server/internal/password"
+	"proyecto/auth-server/internal/repository"
+	"proyecto/auth-server/pkg/model"
+	"proyecto/pkg/auth"
+)
+
+// ErrInvalidCredentials es el único error de un login fallido: no distingue
+// un email que no existe de una contraseña incorrecta.
+var ErrInvalidCredentials = errors.New("invalid credentials")
+
+// LoginLockedError indica que el email no acepta intentos hasta Until.
+type LoginLockedError struct {
+	Until time.Time
+}
+
+func (e *LoginLockedError) Error() string {
+	return fmt.Sprintf("login locked until %s", e.Until.Format(time.RFC3339))
+}
+
+// RetryAfter es cuánto falta para poder volver a intentar (al menos 1s).
+func (e *LoginLockedError) RetryAfter() time.Duration {
+	return max(time.Until(e.Until).Round(time.Second), time.Second)
+}
+
+// LoginAttemptRepository lleva los logins fallidos por email.
+type LoginAttemptRepository interface {
+	// GetLoginAttempts devuelve repository.ErrNotFound si no hay fallos recientes.
+	GetLoginAttempts(ctx context.Context, email string) (*model.LoginAttempts, error)
+	// RecordLoginFailure suma un fallo de forma atómica; los fallos más viejos
+	// que window no cuentan.
+	RecordLoginFailure(ctx context.Context, email string, at time.Time, window time.Duration) (*model.LoginAttempts, error)
+	LockLogin(ctx context.Context, email string, until time.Time) error
+	ResetLoginAttempts(ctx context.Context, email string) error
+}
+
+// LockoutPolicy frena los ataques de fuerza bruta sobre una cuenta: tras
+// cada fallo hay que esperar un poco más para volver a intentar (desde
+// Delay, duplicándose hasta MaxDelay) y tras MaxFailures fallos seguidos la
+// cuenta queda bloqueada durante Lockout.
+type LockoutPolicy struct {
+	MaxFailures   int           `yaml:"max_failures" env:"LOGIN_MAX_FAILURES"`
+	FailureWindow time.Duration `yaml:"failure_window" env:"LOGIN_FAILURE_WINDOW"` // los fallos más viejos se olvidan
+	Lockout       time.Duration `yaml:"lockout" env:"LOGIN_LOCKOUT"`
+	Delay         time.Duration `yaml:"delay" env:"LOGIN_DELAY"`
+	MaxDelay      time.Duration `yaml:"max_delay" env:"LOGIN_MAX_DELAY"`
+}
+
+func DefaultLockoutPolicy() LockoutPolicy {
+	return LockoutPolicy{
+		MaxFailures:   5,
+		FailureWindow: 15 * time.Minute,
+		Lockout:       15 * time.Minute,
+		Delay:         time.Second,
+		MaxDelay:      30 * time.Second,
+	}
+}
+
+func (p LockoutPolicy) Validate() error {
+	var errs []error
+	if p.MaxFailures < 1 {
+		errs = append(errs, errors.New("login.max_failures debe ser al menos 1"))
+	}
+	if p.FailureWindow <= 0 || p.Lockout <= 0 {
+		errs = append(errs, errors.New("login.failure_window y login.lockout deben ser mayores que 0"))
+	}
+	if p.Delay < 0 || p.MaxDelay < p.Delay {
+		errs = append(errs, errors.New("login.max_delay debe ser mayor o igual que login.delay"))
+	}
+	return errors.Join(errs...)
+}
+
+// wait es cuánto se bloquea el email tras su fallo número failures.
+func (p LockoutPolicy) wait(failures int) time.Duration {
+	if failures >= p.MaxFailures {
+		return p.Lockout
+	}
+	if p.Delay <= 0 {
+		return 0
+	}
+	d := p.Delay << min(failures-1, 30)
+	if d <= 0 || d > p.MaxDelay {
+		d = p.MaxDelay
+	}
+	return d
+}
+
+// fallbackDummyHash es un Argon2id (parámetros por defecto) de una contraseña
+// aleatoria que se descartó; se usa si no se puede generar el propio.
+const fallbackDummyHash = "$argon2id$v=19$m=19456,t=2,p=1$XVv2o66jnsKPnZ1mS1SZ6Q$EtpvCnjnk0GynaP6T88p3qDxXL9FMFQvVdDa/SD1QP0"
+
+// newDummyHash es el hash contra el que se compara la contraseña cuando el
+// email no existe, para que tarde lo mismo que con un usuario real.
+func newDummyHash(hashers *password.Hashers) string {
+	hash, err := hashers.Hash(rand.Text())
+	if err != nil {
+		log.Printf("No se pudo generar el hash de comparación, se usa uno fijo: %v", err)
+		return fallbackDummyHash
+	}
+	return hash
+}
+
+// userByEmail busca el usuario por el email normalizado y, si no está, por el
+// email tal como se escribió: así se guardaron las cuentas registradas antes
+// de normalizar los emails.
+func (c *Controller) userByEmail(ctx context.Context, email, given string) (*model.AuthUser, error) {
+	user, err := c.repo.GetHashByEmail(ctx, email)
+	if given = strings.TrimSpace(given); errors.Is(err, repository.ErrNotFound) && given != email {
+		return c.repo.GetHashByEmail(ctx, given)
+	}
+	return user, err
+}
+
+// Authenticate comprueba email y contraseña. Un email bloqueado devuelve
+// *LoginLockedError (aunque la contraseña sea correcta); cualquier otro fallo
+// devuelve ErrInvalidCredentials y cuenta para el bloqueo, exista o no el email.
+// Con la contraseña correcta y un email sin verificar que el login exige,
+// devuelve ErrEmailNotVerified.
+func (c *Controller) Authenticate(ctx context.Context, given, pw string) (*model.AuthUser, error) {
+	email := auth.NormalizeEmail(given)
+	if c.attempts != nil {
+		a, err := c.attempts.GetLoginAttempts(ctx, email)
+		if err != nil && !errors.Is(err, repository.ErrNotFound) {
+			return nil, err
+		}
+		if a != nil && time.Now().Before(a.LockedUntil) {
+			return nil, &LoginLockedError{Until: a.LockedUntil}
+		}
+	}
+
+	user, err := c.userByEmail(ctx, email, given)
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {
+		return nil, err
+	}
+	if user == nil {
+		// Se compara igual, para no revelar por el tiempo de respuesta qué emails existen.
+		_, _, _ = c.hashers.Verify(pw, c.dummyHash)
+	} else if ok, rehash, err := c.hashers.Verify(pw, user.PasswordHash); err != nil {
+		// Un hash guardado que no se entiende se trata como contraseña incorrecta.
+		log.Printf("No se pudo verificar la contraseña de %s: %v", user.Email, err)
+	} else if ok {
+		if rehash {
+			c.rehash(ctx, user, pw)
+		}
+		if c.attempts != nil {
+			if err := c.attempts.ResetLoginAttempts(ctx, email); err != nil {
+				return nil, err
+			}
+		}
+		if err := c.checkVerified(user); err != nil {
+			return nil, err
+		}
+		return user, nil
+	}
+
+	if c.attempts != nil {
+		now := time.Now()
+		a, err := c.attempts.RecordLoginFailure(ctx, email, now, c.lockout.FailureWindow)
+		if err != nil {
+			return nil, err
+		}
+		if wait := c.lockout.wait(a.Failures); wait > 0 {
+			if err := c.attempts.LockLogin(ctx, email, now.Add(wait)); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return nil, ErrInvalidCredentials
+}
+
+// rehash reemplaza el hash de user, que es de un algoritmo o con parámetros
+// viejos, por uno actual; solo se puede hacer en el login, cuando se tiene la
+// contraseña. Si falla se sigue con el hash viejo.
+func (c *Controller) rehash(ctx context.Context, user *model.AuthUser, pw string) {
+	hash, err := c.hashers.Hash(pw)
+	if err != nil {
+		log.Printf("Error rehasheando la contraseña de %s: %v", user.Email, err)
+		return
+	}
+	if err := c.repo.UpdatePasswordHash(ctx, user.Email, hash); err != nil {
+		log.Printf("Error guardando el nuevo hash de %s: %v", user.Email, err)
+		return
+	}
+	user.PasswordHash = hash
+}
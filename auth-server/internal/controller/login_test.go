@@ -0,0 +1,170 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"proyecto/auth-server/internal/password"
+	"proyecto/auth-server/internal/repository/memory"
+	"proyecto/pkg/auth"
+)
+
+const testPassword = "una contraseña larga"
+
+// newLoginEnv arma un controlador con bloqueo y bcrypt barato, para que los tests sean rápidos.
+func newLoginEnv(t *testing.T, policy LockoutPolicy) *testEnv {
+	t.Helper()
+	env := newTestEnv(t,
+		WithPasswordHashers(password.NewHashers(password.Bcrypt{Cost: 4})),
+		WithLoginAttempts(memory.NewLoginAttemptRepository()),
+		WithLockoutPolicy(policy),
+	)
+	env.addUser(t, "ana@example.com", testPassword)
+	return env
+}
+
+var testLockout = LockoutPolicy{MaxFailures: 3, FailureWindow: time.Hour, Lockout: time.Hour}
+
+func TestAuthenticateLockout(t *testing.T) {
+	env := newLoginEnv(t, testLockout)
+	ctx := context.Background()
+
+	for i := 1; i <= testLockout.MaxFailures; i++ {
+		if _, err := env.ctrl.Authenticate(ctx, "ana@example.com", "otra"); !errors.Is(err, ErrInvalidCredentials) {
+			t.Fatalf("fallo %d = %v, quería ErrInvalidCredentials", i, err)
+		}
+	}
+
+	// Bloqueada, aunque la contraseña sea correcta y cambie cómo se escribe el email.
+	for _, email := range []string{"ana@example.com", " ANA@Example.com"} {
+		_, err := env.ctrl.Authenticate(ctx, email, testPassword)
+		var locked *LoginLockedError
+		if !errors.As(err, &locked) || time.Until(locked.Until) < 59*time.Minute {
+			t.Errorf("login de %q bloqueado = %v, quería LoginLockedError por 1h", email, err)
+		}
+	}
+}
+
+func TestAuthenticateSuccessResetsFailures(t *testing.T) {
+	env := newLoginEnv(t, testLockout)
+	ctx := context.Background()
+
+	for range testLockout.MaxFailures - 1 {
+		_, _ = env.ctrl.Authenticate(ctx, "ana@example.com", "otra")
+	}
+	if _, err := env.ctrl.Authenticate(ctx, "ana@example.com", testPassword); err != nil {
+		t.Fatalf("login correcto = %v", err)
+	}
+	// Tras el login correcto se empieza a contar de nuevo.
+	for range testLockout.MaxFailures - 1 {
+		_, _ = env.ctrl.Authenticate(ctx, "ana@example.com", "otra")
+	}
+	if _, err := env.ctrl.Authenticate(ctx, "ana@example.com", testPassword); err != nil {
+		t.Errorf("login correcto = %v", err)
+	}
+}
+
+func TestAuthenticateFailureWindow(t *testing.T) {
+	policy := testLockout
+	policy.FailureWindow = 50 * time.Millisecond
+	env := newLoginEnv(t, policy)
+	ctx := context.Background()
+
+	for range policy.MaxFailures - 1 {
+		_, _ = env.ctrl.Authenticate(ctx, "ana@example.com", "otra")
+	}
+	time.Sleep(100 * time.Millisecond)
+	// Los fallos anteriores quedaron fuera de la ventana: este es el primero.
+	_, _ = env.ctrl.Authenticate(ctx, "ana@example.com", "otra")
+	if _, err := env.ctrl.Authenticate(ctx, "ana@example.com", testPassword); err != nil {
+		t.Errorf("login correcto = %v, no tenía que estar bloqueado", err)
+	}
+}
+
+func TestAuthenticateDelay(t *testing.T) {
+	policy := testLockout
+	policy.Delay, policy.MaxDelay = time.Minute, time.Minute
+	env := newLoginEnv(t, policy)
+	ctx := context.Background()
+
+	_, _ = env.ctrl.Authenticate(ctx, "ana@example.com", "otra")
+	var locked *LoginLockedError
+	if _, err := env.ctrl.Authenticate(ctx, "ana@example.com", testPassword); !errors.As(err, &locked) {
+		t.Errorf("login tras un fallo = %v, quería esperar Delay", err)
+	}
+}
+
+func TestAuthenticateUniformError(t *testing.T) {
+	env := newLoginEnv(t, testLockout)
+	ctx := context.Background()
+
+	_, wrongPassword := env.ctrl.Authenticate(ctx, "ana@example.com", "otra")
+	_, unknownEmail := env.ctrl.Authenticate(ctx, "nadie@example.com", "otra")
+	if wrongPassword != ErrInvalidCredentials || unknownEmail != ErrInvalidCredentials {
+		t.Errorf("errores = %v y %v, quería ErrInvalidCredentials en los dos", wrongPassword, unknownEmail)
+	}
+
+	// Un email que no existe también se bloquea, para no revelar que no existe.
+	for range testLockout.MaxFailures - 1 {
+		_, _ = env.ctrl.Authenticate(ctx, "nadie@example.com", "otra")
+	}
+	var locked *LoginLockedError
+	if _, err := env.ctrl.Authenticate(ctx, "nadie@example.com", "otra"); !errors.As(err, &locked) {
+		t.Errorf("email inexistente tras %d fallos = %v, quería LoginLockedError", testLockout.MaxFailures, err)
+	}
+}
+
+func TestAuthenticateNormalizesEmail(t *testing.T) {
+	env := newLoginEnv(t, testLockout)
+	ctx := context.Background()
+
+	if user, err := env.ctrl.Authenticate(ctx, "  Ana@Example.COM ", testPassword); err != nil || user.Email != "ana@example.com" {
+		t.Errorf("login con mayúsculas = %+v, %v", user, err)
+	}
+
+	// Una cuenta guardada antes de normalizar se encuentra como se escribió.
+	legacy := env.addUser(t, "Bob@Example.com", testPassword)
+	if user, err := env.ctrl.Authenticate(ctx, "Bob@Example.com", testPassword); err != nil || user.Email != legacy.Email {
+		t.Errorf("login de cuenta vieja = %+v, %v", user, err)
+	}
+}
+
+func TestNewDummyHashFallback(t *testing.T) {
+	// Un costo inválido hace fallar el hash: se usa el fijo en vez de entrar en pánico.
+	hashers := password.NewHashers(password.Bcrypt{Cost: 100}, password.DefaultArgon2id)
+	hash := newDummyHash(hashers)
+	if hash != fallbackDummyHash {
+		t.Fatalf("newDummyHash = %q, quería el fijo", hash)
+	}
+	if ok, _, err := hashers.Verify(testPassword, hash); ok || err != nil {
+		t.Errorf("Verify contra el hash fijo = %v, %v", ok, err)
+	}
+}
+
+func TestRehashKeepsConcurrentChanges(t *testing.T) {
+	env := newLoginEnv(t, testLockout)
+	ctx := context.Background()
+	// El login leyó el usuario antes de que un admin le cambiara el rol.
+	stale, err := env.users.GetHashByEmail(ctx, "ana@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.ctrl.SetRole(ctx, "ana@example.com", auth.RoleAdmin); err != nil {
+		t.Fatal(err)
+	}
+	oldHash := stale.PasswordHash
+	env.ctrl.rehash(ctx, stale, testPassword)
+
+	got, err := env.users.GetHashByEmail(ctx, "ana@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Role != auth.RoleAdmin {
+		t.Errorf("el rehash pisó el rol: %q", got.Role)
+	}
+	if got.PasswordHash == oldHash {
+		t.Error("el rehash no guardó el hash nuevo")
+	}
+}
@@ -0,0 +1,109 @@
+package controller
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+
+	"proyecto/auth-server/internal/repository"
+	"proyecto/auth-server/pkg/model"
+)
+
+// DefaultRefreshTokenTTL es la vida de cada refresh token si no se configura
+// otra; se renueva en cada rotación.
+const DefaultRefreshTokenTTL = 30 * 24 * time.Hour
+
+var (
+	// ErrInvalidRefreshToken cubre tokens desconocidos, expirados o revocados.
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	// ErrRefreshTokenReused indica que se presentó un token ya canjeado; la familia queda revocada.
+	ErrRefreshTokenReused = errors.New("refresh token reused")
+)
+
+// IssueRefreshToken crea un refresh token nuevo (y una familia nueva) para el usuario.
+func (c *Controller) IssueRefreshToken(ctx context.Context, email string) (string, error) {
+	return c.newRefreshToken(ctx, email, uuid.NewString())
+}
+
+// RotateRefreshToken canjea un refresh token por otro de la misma familia.
+// Si el token ya había sido usado se asume robo y se revoca toda la familia.
+func (c *Controller) RotateRefreshToken(ctx context.Context, raw string) (string, *model.AuthUser, error) {
+	hash := hashRefreshToken(raw)
+
+	current, err := c.tokens.GetRefreshToken(ctx, hash)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return "", nil, ErrInvalidRefreshToken
+		}
+		return "", nil, err
+	}
+	if current.Revoked {
+		return "", nil, ErrInvalidRefreshToken
+	}
+	if current.Used {
+		return "", nil, c.revokeReused(ctx, current)
+	}
+	if time.Now().After(current.ExpiresAt) {
+		return "", nil, ErrInvalidRefreshToken
+	}
+
+	if err := c.tokens.MarkRefreshTokenUsed(ctx, hash); err != nil {
+		if errors.Is(err, repository.ErrAlreadyUsed) {
+			return "", nil, c.revokeReused(ctx, current)
+		}
+		return "", nil, err
+	}
+
+	user, err := c.GetHashByEmail(ctx, current.Email)
+	if err != nil {
+		return "", nil, ErrInvalidRefreshToken
+	}
+	if err := c.checkVerified(user); err != nil {
+		return "", nil, err
+	}
+
+	next, err := c.newRefreshToken(ctx, current.Email, current.FamilyID)
+	if err != nil {
+		return "", nil, err
+	}
+	return next, user, nil
+}
+
+func (c *Controller) revokeReused(ctx context.Context, token *model.RefreshToken) error {
+	if err := c.tokens.RevokeFamily(ctx, token.FamilyID); err != nil {
+		return err
+	}
+	return ErrRefreshTokenReused
+}
+
+func (c *Controller) newRefreshToken(ctx context.Context, email, familyID string) (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	raw := base64.RawURLEncoding.EncodeToString(buf)
+
+	now := time.Now()
+	err := c.tokens.PutRefreshToken(ctx, &model.RefreshToken{
+		TokenHash: hashRefreshToken(raw),
+		FamilyID:  familyID,
+		Email:     email,
+		CreatedAt: now,
+		ExpiresAt: now.Add(c.refreshTTL),
+	})
+	if err != nil {
+		return "", err
+	}
+	return raw, nil
+}
+
+func hashRefreshToken(raw string) string {
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:])
+}
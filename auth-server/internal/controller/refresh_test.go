@@ -0,0 +1,133 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"proyecto/auth-server/pkg/model"
+)
+
+func TestRotateRefreshToken(t *testing.T) {
+	env := newTestEnv(t)
+	env.addUser(t, "ana@example.com", "una contraseña larga")
+	ctx := context.Background()
+
+	first, err := env.ctrl.IssueRefreshToken(ctx, "ana@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, user, err := env.ctrl.RotateRefreshToken(ctx, first)
+	if err != nil {
+		t.Fatalf("RotateRefreshToken: %v", err)
+	}
+	if second == first || user.Email != "ana@example.com" {
+		t.Errorf("rotación = %q, %+v", second, user)
+	}
+
+	a, _ := env.tokens.GetRefreshToken(ctx, hashRefreshToken(first))
+	b, _ := env.tokens.GetRefreshToken(ctx, hashRefreshToken(second))
+	if !a.Used || b.Used || a.FamilyID != b.FamilyID {
+		t.Errorf("tokens tras rotar: viejo %+v, nuevo %+v", a, b)
+	}
+
+	if _, _, err := env.ctrl.RotateRefreshToken(ctx, "inventado"); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Errorf("token desconocido = %v, quería ErrInvalidRefreshToken", err)
+	}
+}
+
+func TestRotateRefreshTokenReuseRevokesFamily(t *testing.T) {
+	env := newTestEnv(t)
+	env.addUser(t, "ana@example.com", "una contraseña larga")
+	ctx := context.Background()
+
+	first, _ := env.ctrl.IssueRefreshToken(ctx, "ana@example.com")
+	other, _ := env.ctrl.IssueRefreshToken(ctx, "ana@example.com")
+	second, _, err := env.ctrl.RotateRefreshToken(ctx, first)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Volver a presentar el primero se toma como robo: cae toda la familia.
+	if _, _, err := env.ctrl.RotateRefreshToken(ctx, first); !errors.Is(err, ErrRefreshTokenReused) {
+		t.Fatalf("reuso = %v, quería ErrRefreshTokenReused", err)
+	}
+	if _, _, err := env.ctrl.RotateRefreshToken(ctx, second); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Errorf("sucesor tras el reuso = %v, quería ErrInvalidRefreshToken", err)
+	}
+	// Las otras sesiones del usuario siguen.
+	if _, _, err := env.ctrl.RotateRefreshToken(ctx, other); err != nil {
+		t.Errorf("otra familia = %v", err)
+	}
+}
+
+func TestRotateRefreshTokenExpired(t *testing.T) {
+	env := newTestEnv(t)
+	env.addUser(t, "ana@example.com", "una contraseña larga")
+	ctx := context.Background()
+
+	const raw = "vencido"
+	err := env.tokens.PutRefreshToken(ctx, &model.RefreshToken{
+		TokenHash: hashRefreshToken(raw),
+		FamilyID:  "f",
+		Email:     "ana@example.com",
+		CreatedAt: time.Now().Add(-2 * time.Hour),
+		ExpiresAt: time.Now().Add(-time.Hour),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := env.ctrl.RotateRefreshToken(ctx, raw); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Errorf("token vencido = %v, quería ErrInvalidRefreshToken", err)
+	}
+	// Un token vencido no cuenta como reuso ni revoca la familia.
+	if tok, err := env.tokens.GetRefreshToken(ctx, hashRefreshToken(raw)); err == nil && (tok.Used || tok.Revoked) {
+		t.Errorf("token vencido quedó %+v", tok)
+	}
+}
+
+func TestRotateRefreshTokenConcurrent(t *testing.T) {
+	env := newTestEnv(t)
+	env.addUser(t, "ana@example.com", "una contraseña larga")
+	ctx := context.Background()
+	raw, _ := env.ctrl.IssueRefreshToken(ctx, "ana@example.com")
+
+	const n = 20
+	var wg sync.WaitGroup
+	errs := make(chan error, n)
+	next := make(chan string, n)
+	for range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			tok, _, err := env.ctrl.RotateRefreshToken(ctx, raw)
+			if err == nil {
+				next <- tok
+			}
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	close(next)
+
+	won := 0
+	for err := range errs {
+		switch {
+		case err == nil:
+			won++
+		case !errors.Is(err, ErrRefreshTokenReused) && !errors.Is(err, ErrInvalidRefreshToken):
+			// Los que llegan después de la revocación ven el token revocado.
+			t.Errorf("canje simultáneo = %v, quería un rechazo", err)
+		}
+	}
+	if won != 1 {
+		t.Fatalf("canjes ganadores = %d, quería 1", won)
+	}
+	// Los perdedores revocaron la familia, así que el token del ganador tampoco sirve.
+	if _, _, err := env.ctrl.RotateRefreshToken(ctx, <-next); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Errorf("token del ganador = %v, quería ErrInvalidRefreshToken", err)
+	}
+}
@@ -0,0 +1,243 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+
+	"proyecto/auth-server/internal/repository"
+	"proyecto/auth-server/pkg/model"
+)
+
+var (
+	// ErrAlreadyRegistered indica que ya existe un usuario con ese email.
+	ErrAlreadyRegistered = errors.New("user already registered")
+	// ErrMetadataUnavailable indica que no se pudieron crear los metadatos y el registro se deshizo.
+	ErrMetadataUnavailable = errors.New("metadata-user unavailable")
+	// ErrMetadataExists lo devuelve un MetadataService cuando los metadatos ya estaban creados.
+	ErrMetadataExists = errors.New("metadata already exists")
+	// ErrMetadataConflict indica que ya había metadatos para el email con otro
+	// perfil (p.ej. de una cuenta borrada); el registro se deshizo.
+	ErrMetadataConflict = errors.New("metadata belongs to another account")
+)
+
+// SagaRepository persiste el progreso de las sagas de registro.
+type SagaRepository interface {
+	PutSaga(ctx context.Context, saga *model.RegistrationSaga) error
+	GetSaga(ctx context.Context, id string) (*model.RegistrationSaga, error)
+	ListPendingSagas(ctx context.Context) ([]*model.RegistrationSaga, error)
+}
+
+// MetadataService es la parte de metadata-user que usa el registro.
+type MetadataService interface {
+	// CreateMetadata crea los metadatos; todos los intentos de una saga mandan
+	// la misma idempotencyKey.
+	CreateMetadata(ctx context.Context, accessToken, idempotencyKey string, profile *MetadataUser) error
+	GetMetadata(ctx context.Context, accessToken, email string) (*MetadataUser, error)
+}
+
+// Registration da de alta usuarios como una saga de dos pasos: usuario de
+// auth y luego metadatos. Si los metadatos fallan se compensa borrando el
+// usuario, así un reintento del cliente no choca con una cuenta huérfana.
+// Cada paso queda guardado para que Resume pueda terminar o deshacer lo que
+// un reinicio dejó a medias.
+type Registration struct {
+	ctrl     *Controller
+	sagas    SagaRepository
+	metadata MetadataService
+}
+
+func NewRegistration(ctrl *Controller, sagas SagaRepository, metadata MetadataService) *Registration {
+	return &Registration{ctrl: ctrl, sagas: sagas, metadata: metadata}
+}
+
+// Register crea el usuario y sus metadatos, o nada.
+func (r *Registration) Register(ctx context.Context, user *model.AuthUser, profile *MetadataUser) (*model.AuthUser, error) {
+	now := time.Now()
+	saga := &model.RegistrationSaga{
+		ID:        uuid.NewString(),
+		Email:     user.Email,
+		State:     model.SagaStarted,
+		Profile:   *profile,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := r.sagas.PutSaga(ctx, saga); err != nil {
+		return nil, err
+	}
+
+	// El usuario queda marcado con la saga, para que Resume y compensate solo
+	// toquen el que creó esta y no otro registro con el mismo email.
+	user.CreatedAt = time.Now()
+	user.SagaID = saga.ID
+	if err := r.ctrl.repo.Create(ctx, user); err != nil {
+		r.advance(ctx, saga, model.SagaFailed, err)
+		if errors.Is(err, repository.ErrAlreadyExists) {
+			return nil, ErrAlreadyRegistered
+		}
+		return nil, err
+	}
+	if err := r.advance(ctx, saga, model.SagaAuthCreated, nil); err != nil {
+		return nil, err
+	}
+
+	if err := r.createMetadata(ctx, saga, user); err != nil {
+		log.Printf("Saga %s: metadatos de %s fallaron, deshaciendo registro: %v", saga.ID, user.Email, err)
+		if cerr := r.compensate(ctx, saga, err); cerr != nil {
+			return nil, fmt.Errorf("%w (compensation pending: %v)", ErrMetadataUnavailable, cerr)
+		}
+		if errors.Is(err, ErrMetadataConflict) {
+			return nil, ErrMetadataConflict
+		}
+		return nil, ErrMetadataUnavailable
+	}
+
+	if err := r.advance(ctx, saga, model.SagaCompleted, nil); err != nil {
+		// Usuario y metadatos ya existen; Resume la cerrará al arrancar.
+		log.Printf("Saga %s: no se pudo marcar como completada: %v", saga.ID, err)
+	}
+	return user, nil
+}
+
+// Resume retoma las sagas que quedaron a medias (p.ej. por un reinicio):
+// reintenta una vez los metadatos y, si fallan, deshace el usuario. Una saga
+// que no se puede retomar se deja para el próximo arranque y se sigue con
+// las demás.
+func (r *Registration) Resume(ctx context.Context) error {
+	pending, err := r.sagas.ListPendingSagas(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, saga := range pending {
+		log.Printf("Saga %s (%s): retomando desde %s", saga.ID, saga.Email, saga.State)
+		if err := r.resume(ctx, saga); err != nil {
+			log.Printf("Saga %s: no se pudo retomar: %v", saga.ID, err)
+		}
+	}
+	return nil
+}
+
+func (r *Registration) resume(ctx context.Context, saga *model.RegistrationSaga) error {
+	switch saga.State {
+	case model.SagaStarted:
+		// Se cayó antes de registrar el paso: solo seguimos si el usuario
+		// existe y lo creó esta saga (no otro registro con el mismo email).
+		user, err := r.ctrl.repo.GetHashByEmail(ctx, saga.Email)
+		if errors.Is(err, repository.ErrNotFound) || (err == nil && !ownedBy(user, saga)) {
+			r.advance(ctx, saga, model.SagaFailed, errors.New("interrupted before creating auth user"))
+			return nil
+		} else if err != nil {
+			return err
+		}
+		r.advance(ctx, saga, model.SagaAuthCreated, nil)
+		fallthrough
+
+	case model.SagaAuthCreated:
+		user, err := r.ctrl.repo.GetHashByEmail(ctx, saga.Email)
+		if errors.Is(err, repository.ErrNotFound) {
+			r.advance(ctx, saga, model.SagaCompensated, errors.New("auth user no longer exists"))
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if !ownedBy(user, saga) {
+			// El usuario de esta saga se borró y el email se volvió a registrar.
+			r.advance(ctx, saga, model.SagaCompensated, errors.New("auth user replaced by another registration"))
+			return nil
+		}
+		if err := r.createMetadata(ctx, saga, user); err != nil {
+			if cerr := r.compensate(ctx, saga, err); cerr != nil {
+				log.Printf("Saga %s: compensación pendiente: %v", saga.ID, cerr)
+			}
+			return nil
+		}
+		r.advance(ctx, saga, model.SagaCompleted, nil)
+
+	case model.SagaCompensating:
+		if err := r.compensate(ctx, saga, errors.New(saga.LastError)); err != nil {
+			log.Printf("Saga %s: compensación pendiente: %v", saga.ID, err)
+		}
+	}
+	return nil
+}
+
+// ownedBy responde si user lo creó saga. Los usuarios de antes de SagaID solo
+// se distinguen por fecha: los creados antes que la saga no son suyos.
+func ownedBy(user *model.AuthUser, saga *model.RegistrationSaga) bool {
+	if user.SagaID != "" {
+		return user.SagaID == saga.ID
+	}
+	return !user.CreatedAt.Before(saga.CreatedAt)
+}
+
+// createMetadata crea los metadatos del perfil de la saga. Todos sus intentos
+// usan la misma Idempotency-Key, así que si uno anterior llegó a crearlos
+// metadata-user responde como entonces.
+func (r *Registration) createMetadata(ctx context.Context, saga *model.RegistrationSaga, user *model.AuthUser) error {
+	// metadata-user exige token: se firma uno corto a nombre del usuario recién creado.
+	token, err := r.ctrl.IssueAccessToken(ctx, user, time.Minute)
+	if err != nil {
+		return err
+	}
+	profile := saga.Profile
+	err = r.metadata.CreateMetadata(ctx, token, "registration-"+saga.ID, &profile)
+	if !errors.Is(err, ErrMetadataExists) {
+		return err
+	}
+	// Ya existían y la clave no los reconoce (venció o los creó otro): solo
+	// son de esta saga si tienen el mismo perfil.
+	existing, err := r.metadata.GetMetadata(ctx, token, profile.Email)
+	if err != nil {
+		return err
+	}
+	if !sameProfile(existing, &profile) {
+		return ErrMetadataConflict
+	}
+	return nil
+}
+
+func sameProfile(a, b *MetadataUser) bool {
+	return a.FullName == b.FullName && a.AvatarURL == b.AvatarURL &&
+		a.PhoneNumber == b.PhoneNumber && a.BirthDate == b.BirthDate
+}
+
+// compensate borra el usuario de auth si lo creó esta saga; si no se puede,
+// la saga queda en compensating para que Resume lo reintente.
+func (r *Registration) compensate(ctx context.Context, saga *model.RegistrationSaga, cause error) error {
+	if err := r.advance(ctx, saga, model.SagaCompensating, cause); err != nil {
+		return err
+	}
+	user, err := r.ctrl.repo.GetHashByEmail(ctx, saga.Email)
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+	case err != nil:
+		return err
+	case ownedBy(user, saga):
+		if err := r.ctrl.repo.Delete(ctx, saga.Email); err != nil && !errors.Is(err, repository.ErrNotFound) {
+			return err
+		}
+	default:
+		log.Printf("Saga %s: %s es de otro registro, no se borra", saga.ID, saga.Email)
+	}
+	return r.advance(ctx, saga, model.SagaCompensated, cause)
+}
+
+// advance guarda el nuevo estado de la saga. Los errores al marcar estados
+// terminales solo se registran: Resume volverá a pasar por esa saga.
+func (r *Registration) advance(ctx context.Context, saga *model.RegistrationSaga, state model.SagaState, cause error) error {
+	saga.State = state
+	saga.UpdatedAt = time.Now()
+	if cause != nil {
+		saga.LastError = cause.Error()
+	}
+	if err := r.sagas.PutSaga(ctx, saga); err != nil {
+		log.Printf("Saga %s: no se pudo guardar el estado %s: %v", saga.ID, state, err)
+		return err
+	}
+	return nil
+}
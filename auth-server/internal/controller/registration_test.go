@@ -0,0 +1,435 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"proyecto/auth-server/internal/repository"
+	"proyecto/auth-server/internal/repository/memory"
+	"proyecto/auth-server/pkg/model"
+)
+
+// fakeSagas guarda las sagas en memoria.
+type fakeSagas struct {
+	mu   sync.Mutex
+	data map[string]model.RegistrationSaga
+}
+
+func (s *fakeSagas) PutSaga(_ context.Context, saga *model.RegistrationSaga) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data[saga.ID] = *saga
+	return nil
+}
+
+func (s *fakeSagas) GetSaga(_ context.Context, id string) (*model.RegistrationSaga, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	saga, ok := s.data[id]
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+	return &saga, nil
+}
+
+func (s *fakeSagas) ListPendingSagas(_ context.Context) ([]*model.RegistrationSaga, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var res []*model.RegistrationSaga
+	for _, saga := range s.data {
+		if saga.State.Pending() {
+			res = append(res, &saga)
+		}
+	}
+	return res, nil
+}
+
+// only devuelve la única saga guardada.
+func (s *fakeSagas) only(t *testing.T) model.RegistrationSaga {
+	t.Helper()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.data) != 1 {
+		t.Fatalf("hay %d sagas, quería 1", len(s.data))
+	}
+	for _, saga := range s.data {
+		return saga
+	}
+	panic("inalcanzable")
+}
+
+// fakeMetadata imita metadata-user: responde lo mismo a una Idempotency-Key
+// repetida y ErrMetadataExists si el email ya tiene metadatos.
+type fakeMetadata struct {
+	mu      sync.Mutex
+	err     error // si no es nil, CreateMetadata falla con él
+	records map[string]MetadataUser
+	keys    map[string]bool
+}
+
+func (m *fakeMetadata) CreateMetadata(_ context.Context, _, key string, profile *MetadataUser) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.err != nil {
+		return m.err
+	}
+	if m.keys[key] {
+		return nil
+	}
+	if _, ok := m.records[profile.Email]; ok {
+		return ErrMetadataExists
+	}
+	m.records[profile.Email] = *profile
+	m.keys[key] = true
+	return nil
+}
+
+func (m *fakeMetadata) GetMetadata(_ context.Context, _, email string) (*MetadataUser, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	rec, ok := m.records[email]
+	if !ok {
+		return nil, errors.New("metadata: no existe")
+	}
+	return &rec, nil
+}
+
+func (m *fakeMetadata) has(email string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	_, ok := m.records[email]
+	return ok
+}
+
+// flakyUsers es el repositorio en memoria con fallas a pedido.
+type flakyUsers struct {
+	*memory.Repository
+	mu        sync.Mutex
+	deleteErr error
+	getErr    map[string]error
+}
+
+func (u *flakyUsers) Delete(ctx context.Context, email string) error {
+	u.mu.Lock()
+	err := u.deleteErr
+	u.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	return u.Repository.Delete(ctx, email)
+}
+
+func (u *flakyUsers) GetHashByEmail(ctx context.Context, email string) (*model.AuthUser, error) {
+	u.mu.Lock()
+	err := u.getErr[email]
+	u.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	return u.Repository.GetHashByEmail(ctx, email)
+}
+
+type registrationEnv struct {
+	*testEnv
+	reg      *Registration
+	sagas    *fakeSagas
+	metadata *fakeMetadata
+	flaky    *flakyUsers
+}
+
+func newRegistrationEnv(t *testing.T) *registrationEnv {
+	t.Helper()
+	env := &registrationEnv{
+		testEnv:  newTestEnv(t),
+		sagas:    &fakeSagas{data: map[string]model.RegistrationSaga{}},
+		metadata: &fakeMetadata{records: map[string]MetadataUser{}, keys: map[string]bool{}},
+	}
+	env.flaky = &flakyUsers{Repository: env.users, getErr: map[string]error{}}
+	env.ctrl.repo = env.flaky
+	env.reg = NewRegistration(env.ctrl, env.sagas, env.metadata)
+	return env
+}
+
+func (env *registrationEnv) register(email string) (*model.AuthUser, error) {
+	user := &model.AuthUser{Email: email, PasswordHash: "h", Provider: "local", Role: "user"}
+	return env.reg.Register(context.Background(), user, &MetadataUser{Email: email, FullName: "Ana Pérez"})
+}
+
+// pendingSaga guarda una saga en state como si un reinicio la hubiera cortado.
+func (env *registrationEnv) pendingSaga(t *testing.T, email string, state model.SagaState) *model.RegistrationSaga {
+	t.Helper()
+	saga := &model.RegistrationSaga{
+		ID: uuid.NewString(), Email: email, State: state,
+		Profile:   MetadataUser{Email: email, FullName: "Ana Pérez"},
+		CreatedAt: time.Now(), UpdatedAt: time.Now(),
+	}
+	if err := env.sagas.PutSaga(context.Background(), saga); err != nil {
+		t.Fatal(err)
+	}
+	return saga
+}
+
+// createUser da de alta el usuario de auth de sagaID (vacío = de otro origen).
+func (env *registrationEnv) createUser(t *testing.T, email, sagaID string) {
+	t.Helper()
+	user := &model.AuthUser{Email: email, Role: "user", CreatedAt: time.Now(), SagaID: sagaID}
+	if err := env.users.Create(context.Background(), user); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func (env *registrationEnv) userExists(t *testing.T, email string) bool {
+	t.Helper()
+	_, err := env.users.GetHashByEmail(context.Background(), email)
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {
+		t.Fatal(err)
+	}
+	return err == nil
+}
+
+func (env *registrationEnv) state(t *testing.T, id string) model.SagaState {
+	t.Helper()
+	saga, err := env.sagas.GetSaga(context.Background(), id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return saga.State
+}
+
+func TestRegister(t *testing.T) {
+	env := newRegistrationEnv(t)
+	user, err := env.register("ana@example.com")
+	if err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	saga := env.sagas.only(t)
+	if saga.State != model.SagaCompleted {
+		t.Errorf("saga en %s, quería completed", saga.State)
+	}
+	if user.SagaID != saga.ID {
+		t.Errorf("SagaID = %q, quería %q", user.SagaID, saga.ID)
+	}
+	if !env.metadata.has("ana@example.com") || !env.metadata.keys["registration-"+saga.ID] {
+		t.Error("los metadatos no se crearon con la clave de la saga")
+	}
+}
+
+func TestRegisterCompensatesOnMetadataFailure(t *testing.T) {
+	env := newRegistrationEnv(t)
+	env.metadata.err = errors.New("metadata-user caído")
+
+	if _, err := env.register("ana@example.com"); !errors.Is(err, ErrMetadataUnavailable) {
+		t.Fatalf("Register = %v, quería ErrMetadataUnavailable", err)
+	}
+	if env.userExists(t, "ana@example.com") {
+		t.Error("el usuario de auth no se borró")
+	}
+	if saga := env.sagas.only(t); saga.State != model.SagaCompensated || saga.LastError == "" {
+		t.Errorf("saga = %s (%q), quería compensated con el error", saga.State, saga.LastError)
+	}
+
+	// Borrado el usuario, se puede volver a registrar.
+	env.metadata.err = nil
+	if _, err := env.register("ana@example.com"); err != nil {
+		t.Errorf("Register tras la compensación = %v", err)
+	}
+}
+
+func TestRegisterCompensationFailure(t *testing.T) {
+	env := newRegistrationEnv(t)
+	env.metadata.err = errors.New("metadata-user caído")
+	env.flaky.deleteErr = errors.New("base caída")
+
+	_, err := env.register("ana@example.com")
+	if !errors.Is(err, ErrMetadataUnavailable) {
+		t.Fatalf("Register = %v, quería ErrMetadataUnavailable", err)
+	}
+	saga := env.sagas.only(t)
+	if saga.State != model.SagaCompensating {
+		t.Fatalf("saga en %s, quería compensating", saga.State)
+	}
+	if !env.userExists(t, "ana@example.com") {
+		t.Fatal("el usuario no debería haberse borrado")
+	}
+
+	// Al arrancar de nuevo, Resume termina la compensación.
+	env.flaky.deleteErr = nil
+	if err := env.reg.Resume(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got := env.state(t, saga.ID); got != model.SagaCompensated {
+		t.Errorf("saga en %s tras Resume, quería compensated", got)
+	}
+	if env.userExists(t, "ana@example.com") {
+		t.Error("Resume no borró el usuario")
+	}
+}
+
+func TestRegisterMetadataOfAnotherAccount(t *testing.T) {
+	env := newRegistrationEnv(t)
+	// Metadatos que dejó una cuenta borrada con el mismo email.
+	env.metadata.records["ana@example.com"] = MetadataUser{Email: "ana@example.com", FullName: "Otra Persona"}
+
+	if _, err := env.register("ana@example.com"); !errors.Is(err, ErrMetadataConflict) {
+		t.Fatalf("Register = %v, quería ErrMetadataConflict", err)
+	}
+	if env.userExists(t, "ana@example.com") {
+		t.Error("el usuario de auth no se borró")
+	}
+	if got := env.metadata.records["ana@example.com"].FullName; got != "Otra Persona" {
+		t.Errorf("los metadatos ajenos cambiaron: %q", got)
+	}
+
+	// Si son iguales a los de la saga (p.ej. la clave ya venció), se aceptan.
+	env.metadata.records["bea@example.com"] = MetadataUser{Email: "bea@example.com", FullName: "Ana Pérez"}
+	if _, err := env.register("bea@example.com"); err != nil {
+		t.Errorf("Register con los mismos metadatos = %v", err)
+	}
+}
+
+func TestResumeFromStarted(t *testing.T) {
+	env := newRegistrationEnv(t)
+	ctx := context.Background()
+
+	// Se cayó antes de crear el usuario.
+	noUser := env.pendingSaga(t, "ana@example.com", model.SagaStarted)
+	// Se cayó con el usuario creado y sin marcar el paso.
+	created := env.pendingSaga(t, "bea@example.com", model.SagaStarted)
+	env.createUser(t, "bea@example.com", created.ID)
+	// El usuario es de otro registro con el mismo email.
+	other := env.pendingSaga(t, "caro@example.com", model.SagaStarted)
+	env.createUser(t, "caro@example.com", uuid.NewString())
+
+	if err := env.reg.Resume(ctx); err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		saga     *model.RegistrationSaga
+		state    model.SagaState
+		user     bool
+		metadata bool
+	}{
+		{noUser, model.SagaFailed, false, false},
+		{created, model.SagaCompleted, true, true},
+		{other, model.SagaFailed, true, false},
+	} {
+		if got := env.state(t, tc.saga.ID); got != tc.state {
+			t.Errorf("%s: saga en %s, quería %s", tc.saga.Email, got, tc.state)
+		}
+		if got := env.userExists(t, tc.saga.Email); got != tc.user {
+			t.Errorf("%s: usuario existe = %v, quería %v", tc.saga.Email, got, tc.user)
+		}
+		if got := env.metadata.has(tc.saga.Email); got != tc.metadata {
+			t.Errorf("%s: metadatos = %v, quería %v", tc.saga.Email, got, tc.metadata)
+		}
+	}
+}
+
+func TestResumeFromAuthCreated(t *testing.T) {
+	env := newRegistrationEnv(t)
+	ctx := context.Background()
+
+	// Los metadatos no llegaron a crearse.
+	pending := env.pendingSaga(t, "ana@example.com", model.SagaAuthCreated)
+	env.createUser(t, "ana@example.com", pending.ID)
+	// Un intento anterior los creó con la clave de la saga.
+	done := env.pendingSaga(t, "bea@example.com", model.SagaAuthCreated)
+	env.createUser(t, "bea@example.com", done.ID)
+	if err := env.metadata.CreateMetadata(ctx, "", "registration-"+done.ID, &done.Profile); err != nil {
+		t.Fatal(err)
+	}
+	// El usuario de la saga se borró y el email se volvió a registrar.
+	replaced := env.pendingSaga(t, "caro@example.com", model.SagaAuthCreated)
+	env.createUser(t, "caro@example.com", uuid.NewString())
+	// El usuario ya no existe.
+	gone := env.pendingSaga(t, "dani@example.com", model.SagaAuthCreated)
+
+	if err := env.reg.Resume(ctx); err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		saga  *model.RegistrationSaga
+		state model.SagaState
+		user  bool
+	}{
+		{pending, model.SagaCompleted, true},
+		{done, model.SagaCompleted, true},
+		{replaced, model.SagaCompensated, true},
+		{gone, model.SagaCompensated, false},
+	} {
+		if got := env.state(t, tc.saga.ID); got != tc.state {
+			t.Errorf("%s: saga en %s, quería %s", tc.saga.Email, got, tc.state)
+		}
+		if got := env.userExists(t, tc.saga.Email); got != tc.user {
+			t.Errorf("%s: usuario existe = %v, quería %v", tc.saga.Email, got, tc.user)
+		}
+	}
+	if env.metadata.has("caro@example.com") {
+		t.Error("se crearon metadatos para el usuario de otro registro")
+	}
+}
+
+func TestResumeFromAuthCreatedCompensates(t *testing.T) {
+	env := newRegistrationEnv(t)
+	saga := env.pendingSaga(t, "ana@example.com", model.SagaAuthCreated)
+	env.createUser(t, "ana@example.com", saga.ID)
+	env.metadata.err = errors.New("metadata-user caído")
+
+	if err := env.reg.Resume(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got := env.state(t, saga.ID); got != model.SagaCompensated {
+		t.Errorf("saga en %s, quería compensated", got)
+	}
+	if env.userExists(t, "ana@example.com") {
+		t.Error("el usuario no se borró")
+	}
+}
+
+func TestResumeFromCompensating(t *testing.T) {
+	env := newRegistrationEnv(t)
+	own := env.pendingSaga(t, "ana@example.com", model.SagaCompensating)
+	env.createUser(t, "ana@example.com", own.ID)
+	// Se borró el usuario pero no se llegó a marcar la saga, y después alguien
+	// se registró con el mismo email: no se puede borrar su cuenta.
+	other := env.pendingSaga(t, "bea@example.com", model.SagaCompensating)
+	env.createUser(t, "bea@example.com", uuid.NewString())
+
+	if err := env.reg.Resume(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	for _, saga := range []*model.RegistrationSaga{own, other} {
+		if got := env.state(t, saga.ID); got != model.SagaCompensated {
+			t.Errorf("%s: saga en %s, quería compensated", saga.Email, got)
+		}
+	}
+	if env.userExists(t, "ana@example.com") {
+		t.Error("no se borró el usuario de la saga")
+	}
+	if !env.userExists(t, "bea@example.com") {
+		t.Error("se borró el usuario de otro registro")
+	}
+}
+
+func TestResumeContinuesAfterError(t *testing.T) {
+	env := newRegistrationEnv(t)
+	broken := env.pendingSaga(t, "ana@example.com", model.SagaAuthCreated)
+	env.flaky.getErr["ana@example.com"] = errors.New("base caída")
+	ok := env.pendingSaga(t, "bea@example.com", model.SagaAuthCreated)
+	env.createUser(t, "bea@example.com", ok.ID)
+
+	if err := env.reg.Resume(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got := env.state(t, broken.ID); got != model.SagaAuthCreated {
+		t.Errorf("saga con error en %s, quería que siguiera en auth_created", got)
+	}
+	if got := env.state(t, ok.ID); got != model.SagaCompleted {
+		t.Errorf("la otra saga quedó en %s, quería completed", got)
+	}
+}
@@ -0,0 +1,148 @@
+package controller
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+
+	"proyecto/auth-server/internal/repository"
+	"proyecto/auth-server/pkg/model"
+	"proyecto/pkg/auth"
+)
+
+var (
+	// ErrInvalidAccessToken cubre firma inválida, token expirado o claims mal formados.
+	ErrInvalidAccessToken = errors.New("invalid access token")
+	// ErrTokenRevoked indica que el jti del token está en la lista de revocación.
+	ErrTokenRevoked = errors.New("access token revoked")
+)
+
+// IssueAccessToken firma un access token nuevo para el usuario y lo registra
+// como emitido, para poder revocarlo luego junto con el resto de sus sesiones.
+func (c *Controller) IssueAccessToken(ctx context.Context, user *model.AuthUser, ttl time.Duration) (string, error) {
+	jti := uuid.NewString()
+	token, err := c.GenerateAccessToken(user.Email, user.Email, user.Role, user.EmailVerified, jti, ttl)
+	if err != nil {
+		return "", err
+	}
+
+	err = c.revoked.TrackIssued(ctx, &model.IssuedToken{
+		JTI:       jti,
+		Email:     user.Email,
+		ExpiresAt: time.Now().Add(ttl),
+	})
+	if err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// ParseAccessToken valida firma (según el kid), exp e iss del token y que su jti no esté revocado.
+func (c *Controller) ParseAccessToken(ctx context.Context, raw string) (*AccessClaims, error) {
+	claims := &AccessClaims{}
+	_, err := jwt.ParseWithClaims(raw, claims, c.verifyKey,
+		jwt.WithValidMethods(c.keys.Algorithms()), jwt.WithIssuer(auth.Issuer), jwt.WithAudience("auth-server"), jwt.WithExpirationRequired())
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidAccessToken, err)
+	}
+	if claims.ID == "" {
+		return nil, fmt.Errorf("%w: missing jti", ErrInvalidAccessToken)
+	}
+
+	revoked, err := c.IsRevoked(ctx, claims.ID)
+	if err != nil {
+		return nil, err
+	}
+	if revoked {
+		return nil, ErrTokenRevoked
+	}
+	return claims, nil
+}
+
+// RevokeAccessToken agrega el jti del token a la lista de revocación hasta su exp.
+func (c *Controller) RevokeAccessToken(ctx context.Context, claims *AccessClaims) error {
+	token := &model.RevokedToken{
+		JTI:       claims.ID,
+		Email:     claims.Email,
+		RevokedAt: time.Now(),
+	}
+	if claims.ExpiresAt != nil {
+		token.ExpiresAt = claims.ExpiresAt.Time
+	}
+	return c.revoked.Revoke(ctx, token)
+}
+
+// RevokeRefreshToken revoca la familia del refresh token dado (p.ej. en logout).
+func (c *Controller) RevokeRefreshToken(ctx context.Context, raw string) error {
+	token, err := c.tokens.GetRefreshToken(ctx, hashRefreshToken(raw))
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return ErrInvalidRefreshToken
+		}
+		return err
+	}
+	return c.tokens.RevokeFamily(ctx, token.FamilyID)
+}
+
+// RevokeAllSessions revoca todos los access tokens vigentes del usuario y sus
+// refresh tokens. Devuelve cuántos access tokens quedaron revocados. El email
+// se normaliza como en el login; si es de una cuenta de antes de normalizar,
+// se usa el que tiene guardado.
+func (c *Controller) RevokeAllSessions(ctx context.Context, given string) (int, error) {
+	email := auth.NormalizeEmail(given)
+	if user, err := c.userByEmail(ctx, email, given); err == nil {
+		email = user.Email
+	} else if !errors.Is(err, repository.ErrNotFound) {
+		return 0, err
+	}
+
+	n, err := c.revokeAccessTokens(ctx, email)
+	if err != nil {
+		return 0, err
+	}
+	if err := c.tokens.RevokeByEmail(ctx, email); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// revokeAccessTokens revoca solo los access tokens vigentes del usuario.
+func (c *Controller) revokeAccessTokens(ctx context.Context, email string) (int, error) {
+	issued, err := c.revoked.ListIssuedByEmail(ctx, email)
+	if err != nil {
+		return 0, err
+	}
+
+	now := time.Now()
+	for _, t := range issued {
+		err := c.revoked.Revoke(ctx, &model.RevokedToken{
+			JTI:       t.JTI,
+			Email:     t.Email,
+			RevokedAt: now,
+			ExpiresAt: t.ExpiresAt,
+		})
+		if err != nil {
+			return 0, err
+		}
+	}
+	return len(issued), nil
+}
+
+func (c *Controller) IsRevoked(ctx context.Context, jti string) (bool, error) {
+	return c.revoked.IsRevoked(ctx, jti)
+}
+
+// IsServiceToken indica si raw es la credencial de servicio de la lista de
+// revocación (WithRevocationsToken).
+func (c *Controller) IsServiceToken(raw string) bool {
+	return c.serviceToken != "" && subtle.ConstantTimeCompare([]byte(raw), []byte(c.serviceToken)) == 1
+}
+
+func (c *Controller) ListRevoked(ctx context.Context) ([]*model.RevokedToken, error) {
+	return c.revoked.ListRevoked(ctx)
+}
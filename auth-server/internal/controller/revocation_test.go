@@ -0,0 +1,150 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"proyecto/auth-server/pkg/model"
+)
+
+// issue emite un access token y un refresh token para user, como el login.
+func (env *testEnv) issue(t *testing.T, user *model.AuthUser) (access, refresh string) {
+	t.Helper()
+	ctx := context.Background()
+	access, err := env.ctrl.IssueAccessToken(ctx, user, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	refresh, err = env.ctrl.IssueRefreshToken(ctx, user.Email)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return access, refresh
+}
+
+func TestRevokeAccessToken(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+	access, _ := env.issue(t, env.addUser(t, "ana@example.com", testPassword))
+
+	claims, err := env.ctrl.ParseAccessToken(ctx, access)
+	if err != nil {
+		t.Fatalf("ParseAccessToken: %v", err)
+	}
+	if _, err := env.ctrl.ParseAccessToken(ctx, "no-es-un-jwt"); !errors.Is(err, ErrInvalidAccessToken) {
+		t.Errorf("token inválido = %v, quería ErrInvalidAccessToken", err)
+	}
+
+	if err := env.ctrl.RevokeAccessToken(ctx, claims); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := env.ctrl.ParseAccessToken(ctx, access); !errors.Is(err, ErrTokenRevoked) {
+		t.Errorf("token revocado = %v, quería ErrTokenRevoked", err)
+	}
+	list, err := env.ctrl.ListRevoked(ctx)
+	if err != nil || len(list) != 1 || list[0].JTI != claims.ID || !list[0].ExpiresAt.Equal(claims.ExpiresAt.Time) {
+		t.Errorf("ListRevoked = %+v, %v; quería el jti %s hasta su exp", list, err, claims.ID)
+	}
+}
+
+func TestLogoutRevokesRefreshFamily(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+	user := env.addUser(t, "ana@example.com", testPassword)
+	_, first := env.issue(t, user)
+	_, other := env.issue(t, user) // otra sesión del mismo usuario
+	second, _, err := env.ctrl.RotateRefreshToken(ctx, first)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// El logout manda el último refresh token de la sesión.
+	if err := env.ctrl.RevokeRefreshToken(ctx, second); err != nil {
+		t.Fatal(err)
+	}
+	for name, raw := range map[string]string{"último": second, "anterior": first} {
+		if _, _, err := env.ctrl.RotateRefreshToken(ctx, raw); err == nil {
+			t.Errorf("refresh %s tras el logout = nil, quería un error", name)
+		}
+	}
+	if _, _, err := env.ctrl.RotateRefreshToken(ctx, other); err != nil {
+		t.Errorf("la otra sesión tras el logout = %v", err)
+	}
+	if err := env.ctrl.RevokeRefreshToken(ctx, "inventado"); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Errorf("logout con refresh desconocido = %v, quería ErrInvalidRefreshToken", err)
+	}
+}
+
+func TestRevokeAllSessions(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+	ana := env.addUser(t, "ana@example.com", testPassword)
+	access1, refresh1 := env.issue(t, ana)
+	access2, refresh2 := env.issue(t, ana)
+	otherAccess, otherRefresh := env.issue(t, env.addUser(t, "bea@example.com", testPassword))
+
+	// El email llega como lo escribió el admin.
+	n, err := env.ctrl.RevokeAllSessions(ctx, " Ana@Example.com")
+	if err != nil || n != 2 {
+		t.Fatalf("RevokeAllSessions = %d, %v; quería 2", n, err)
+	}
+	for _, access := range []string{access1, access2} {
+		if _, err := env.ctrl.ParseAccessToken(ctx, access); !errors.Is(err, ErrTokenRevoked) {
+			t.Errorf("access token tras revocar todo = %v, quería ErrTokenRevoked", err)
+		}
+	}
+	for _, refresh := range []string{refresh1, refresh2} {
+		if _, _, err := env.ctrl.RotateRefreshToken(ctx, refresh); !errors.Is(err, ErrInvalidRefreshToken) {
+			t.Errorf("refresh tras revocar todo = %v, quería ErrInvalidRefreshToken", err)
+		}
+	}
+
+	// Las sesiones de otros usuarios siguen.
+	if _, err := env.ctrl.ParseAccessToken(ctx, otherAccess); err != nil {
+		t.Errorf("access token de otro usuario = %v", err)
+	}
+	if _, _, err := env.ctrl.RotateRefreshToken(ctx, otherRefresh); err != nil {
+		t.Errorf("refresh de otro usuario = %v", err)
+	}
+}
+
+func TestRevokeAllSessionsLegacyEmail(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+	legacy := &model.AuthUser{Email: "Bea@Example.com", Role: "user", EmailVerified: true, CreatedAt: time.Now()}
+	if err := env.users.Create(ctx, legacy); err != nil {
+		t.Fatal(err)
+	}
+	access, _ := env.issue(t, legacy)
+
+	if n, err := env.ctrl.RevokeAllSessions(ctx, "Bea@Example.com"); err != nil || n != 1 {
+		t.Fatalf("RevokeAllSessions = %d, %v; quería 1", n, err)
+	}
+	if _, err := env.ctrl.ParseAccessToken(ctx, access); !errors.Is(err, ErrTokenRevoked) {
+		t.Errorf("access token = %v, quería ErrTokenRevoked", err)
+	}
+}
+
+func TestServiceToken(t *testing.T) {
+	secret := strings.Repeat("s", 32)
+	env := newTestEnv(t, WithRevocationsToken(secret))
+	for raw, want := range map[string]bool{
+		secret:                  true,
+		"":                      false,
+		secret[:31]:             false,
+		strings.Repeat("x", 32): false,
+		secret + "x":            false,
+	} {
+		if got := env.ctrl.IsServiceToken(raw); got != want {
+			t.Errorf("IsServiceToken(%q) = %v, quería %v", raw, got, want)
+		}
+	}
+
+	// Sin credencial configurada no hay ninguna que sirva.
+	if newTestEnv(t).ctrl.IsServiceToken("") {
+		t.Error("IsServiceToken(\"\") sin credencial = true")
+	}
+}
@@ -0,0 +1,39 @@
+package controller
+
+import (
+	"context"
+	"errors"
+
+	"proyecto/auth-server/internal/repository"
+	"proyecto/pkg/auth"
+)
+
+// ErrInvalidRole se devuelve cuando el rol no está definido en pkg/auth.
+var ErrInvalidRole = errors.New("invalid role")
+
+// SetRole cambia el rol de un usuario. Los access tokens vigentes llevan los
+// permisos del rol anterior, así que se revocan; los refresh tokens se
+// conservan y el próximo refresh ya sale con el rol nuevo. El email se
+// normaliza como en el login.
+func (c *Controller) SetRole(ctx context.Context, email, role string) error {
+	if !auth.ValidRole(role) {
+		return ErrInvalidRole
+	}
+
+	user, err := c.userByEmail(ctx, auth.NormalizeEmail(email), email)
+	if errors.Is(err, repository.ErrNotFound) {
+		return ErrNotFound
+	} else if err != nil {
+		return err
+	}
+	if user.Role == role {
+		return nil
+	}
+
+	if err := c.repo.SetRole(ctx, user.Email, role); err != nil {
+		return err
+	}
+
+	_, err = c.revokeAccessTokens(ctx, user.Email)
+	return err
+}
@@ -0,0 +1,59 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"proyecto/auth-server/pkg/model"
+	"proyecto/pkg/auth"
+)
+
+func TestSetRole(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+	user := env.addUser(t, "ana@example.com", testPassword)
+	token, err := env.ctrl.IssueAccessToken(ctx, user, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// El email se normaliza como en el login.
+	if err := env.ctrl.SetRole(ctx, " Ana@Example.COM ", auth.RoleAdmin); err != nil {
+		t.Fatalf("SetRole: %v", err)
+	}
+	got, _ := env.users.GetHashByEmail(ctx, "ana@example.com")
+	if got.Role != auth.RoleAdmin {
+		t.Errorf("rol = %q, quería admin", got.Role)
+	}
+	// El token tenía los permisos del rol anterior.
+	if _, err := env.ctrl.ParseAccessToken(ctx, token); !errors.Is(err, ErrTokenRevoked) {
+		t.Errorf("ParseAccessToken tras el cambio de rol = %v, quería ErrTokenRevoked", err)
+	}
+
+	if err := env.ctrl.SetRole(ctx, "ana@example.com", "superusuario"); !errors.Is(err, ErrInvalidRole) {
+		t.Errorf("SetRole con rol inválido = %v, quería ErrInvalidRole", err)
+	}
+	if err := env.ctrl.SetRole(ctx, "nadie@example.com", auth.RoleAdmin); !errors.Is(err, ErrNotFound) {
+		t.Errorf("SetRole de un email desconocido = %v, quería ErrNotFound", err)
+	}
+}
+
+func TestSetRoleLegacyEmail(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+	// Registrado antes de normalizar los emails.
+	legacy := &model.AuthUser{Email: "Bea@Example.com", Role: auth.RoleUser, CreatedAt: time.Now()}
+	if err := env.users.Create(ctx, legacy); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := env.ctrl.SetRole(ctx, "Bea@Example.com", auth.RoleSupport); err != nil {
+		t.Fatalf("SetRole: %v", err)
+	}
+	got, _ := env.users.GetHashByEmail(ctx, "Bea@Example.com")
+	if got.Role != auth.RoleSupport {
+		t.Errorf("rol = %q, quería support", got.Role)
+	}
+}
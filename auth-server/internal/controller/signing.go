@@ -0,0 +1,54 @@
+package controller
+
+import (
+	"errors"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"proyecto/auth-server/internal/keys"
+	"proyecto/pkg/jwks"
+)
+
+var ErrUnknownKey = errors.New("unknown signing key")
+
+// verifyKey es el jwt.Keyfunc de los access tokens: elige la llave por el kid
+// del header y exige que el alg del token coincida con el de la llave.
+func (c *Controller) verifyKey(t *jwt.Token) (any, error) {
+	return keyFor(t, c.keys.Lookup)
+}
+
+// verificationKey es verifyKey para los tokens de verificación del email, que
+// pueden venir firmados con una llave retirada hace hasta TokenTTL.
+func (c *Controller) verificationKey(t *jwt.Token) (any, error) {
+	return keyFor(t, func(kid string) (*keys.Key, bool) {
+		return c.keys.LookupWithin(kid, c.verification.TokenTTL)
+	})
+}
+
+func keyFor(t *jwt.Token, lookup func(kid string) (*keys.Key, bool)) (any, error) {
+	kid, _ := t.Header["kid"].(string)
+	key, ok := lookup(kid)
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+	if t.Method.Alg() != key.Algorithm {
+		return nil, jwt.ErrTokenSignatureInvalid
+	}
+	return key.VerifyKey(), nil
+}
+
+// RotateSigningKey genera una llave nueva del mismo algoritmo y la pone como
+// actual. La anterior sigue verificando hasta que venzan sus tokens.
+func (c *Controller) RotateSigningKey() (*keys.Key, error) {
+	next, err := keys.Generate(c.keys.Current().Algorithm)
+	if err != nil {
+		return nil, err
+	}
+	c.keys.Rotate(next)
+	return next, nil
+}
+
+// JWKS devuelve las llaves públicas vigentes.
+func (c *Controller) JWKS() jwks.Set {
+	return c.keys.JWKS()
+}
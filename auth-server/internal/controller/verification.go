@@ -0,0 +1,176 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+
+	"proyecto/auth-server/internal/mail"
+	"proyecto/auth-server/internal/repository"
+	"proyecto/auth-server/pkg/model"
+	"proyecto/pkg/auth"
+)
+
+var (
+	// ErrEmailNotVerified indica que el login exige el email verificado y este no lo está.
+	ErrEmailNotVerified = errors.New("email not verified")
+	// ErrInvalidVerificationToken cubre firma inválida, token expirado, de un
+	// envío anterior al último o de un usuario que ya no existe.
+	ErrInvalidVerificationToken = errors.New("invalid verification token")
+	// ErrAlreadyVerified indica que no hace falta reenviar la verificación.
+	ErrAlreadyVerified = errors.New("email already verified")
+	// ErrResendTooSoon indica que la última verificación se mandó hace menos de ResendInterval.
+	ErrResendTooSoon = errors.New("verification sent too recently")
+)
+
+// VerificationAudience es el "aud" de los tokens de verificación, para que no
+// sirvan como access tokens ni al revés.
+const VerificationAudience = "email-verification"
+
+// VerifyEmailPath es la ruta del enlace que llega en el email.
+const VerifyEmailPath = "/Auth-Server/verify-email"
+
+// VerificationPolicy configura la verificación del email.
+type VerificationPolicy struct {
+	// RequireForLogin rechaza el login de los usuarios sin verificar; si no,
+	// entran igual y el access token lo indica con email_verified=false.
+	RequireForLogin bool          `yaml:"require_for_login" env:"EMAIL_VERIFICATION_REQUIRED"`
+	TokenTTL        time.Duration `yaml:"token_ttl" env:"EMAIL_VERIFICATION_TTL"`
+	// ResendInterval es el tiempo mínimo entre dos envíos al mismo email.
+	ResendInterval time.Duration `yaml:"resend_interval" env:"EMAIL_VERIFICATION_RESEND_INTERVAL"`
+	// PublicURL es la URL con la que se llega a auth-server desde afuera (p.ej.
+	// https://auth.example.com); con ella se arma el enlace del email.
+	PublicURL string `yaml:"public_url" env:"PUBLIC_URL"`
+}
+
+func DefaultVerificationPolicy() VerificationPolicy {
+	return VerificationPolicy{
+		TokenTTL:       24 * time.Hour,
+		ResendInterval: 5 * time.Minute,
+	}
+}
+
+func (p VerificationPolicy) Validate() error {
+	var errs []error
+	if p.TokenTTL <= 0 {
+		errs = append(errs, errors.New("email_verification.token_ttl debe ser mayor que 0"))
+	}
+	if p.ResendInterval < 0 {
+		errs = append(errs, errors.New("email_verification.resend_interval no puede ser negativo"))
+	}
+	if p.PublicURL != "" {
+		if u, err := url.Parse(p.PublicURL); err != nil || u.Scheme == "" || u.Host == "" {
+			errs = append(errs, fmt.Errorf("email_verification.public_url inválida: %q", p.PublicURL))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// SendVerification manda a user el enlace para verificar su email y guarda
+// cuándo se mandó. Los enlaces de envíos anteriores dejan de servir.
+func (c *Controller) SendVerification(ctx context.Context, user *model.AuthUser) error {
+	// Con la precisión del iat, para que VerifyEmail pueda compararlos.
+	now := time.Now().Truncate(time.Second)
+	token, err := c.generateVerificationToken(user.Email, now)
+	if err != nil {
+		return err
+	}
+	link := strings.TrimSuffix(c.verification.PublicURL, "/") + VerifyEmailPath + "?token=" + url.QueryEscape(token)
+	err = c.mail.Send(ctx, mail.Message{
+		To:      user.Email,
+		Subject: "Verificá tu email",
+		Body: "Para confirmar que este email es tuyo, abrí este enlace:\n\n" + link +
+			"\n\nVence en " + c.verification.TokenTTL.String() + ". Si no creaste una cuenta, ignorá este mensaje.\n",
+	})
+	if err != nil {
+		return fmt.Errorf("mandando verificación a %s: %w", user.Email, err)
+	}
+
+	if err := c.repo.SetVerificationSentAt(ctx, user.Email, now); err != nil {
+		return err
+	}
+	user.VerificationSentAt = now
+	return nil
+}
+
+// ResendVerification vuelve a mandar la verificación, como mucho una vez cada
+// ResendInterval por email.
+func (c *Controller) ResendVerification(ctx context.Context, email string) error {
+	user, err := c.userByEmail(ctx, auth.NormalizeEmail(email), email)
+	if errors.Is(err, repository.ErrNotFound) {
+		return ErrNotFound
+	} else if err != nil {
+		return err
+	}
+	if user.EmailVerified {
+		return ErrAlreadyVerified
+	}
+	if time.Since(user.VerificationSentAt) < c.verification.ResendInterval {
+		return ErrResendTooSoon
+	}
+	return c.SendVerification(ctx, user)
+}
+
+// VerifyEmail marca como verificado el email del token. El token tiene que
+// ser del último envío a esa cuenta: si el usuario se borró y se volvió a
+// registrar con el mismo email, los enlaces de la cuenta anterior no sirven.
+// Usar un token de un email ya verificado no es un error.
+func (c *Controller) VerifyEmail(ctx context.Context, raw string) (*model.AuthUser, error) {
+	claims := &jwt.RegisteredClaims{}
+	_, err := jwt.ParseWithClaims(raw, claims, c.verificationKey,
+		jwt.WithValidMethods(c.keys.AlgorithmsWithin(c.verification.TokenTTL)), jwt.WithIssuer(auth.Issuer), jwt.WithAudience(VerificationAudience), jwt.WithExpirationRequired())
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidVerificationToken, err)
+	}
+
+	user, err := c.repo.GetHashByEmail(ctx, claims.Subject)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrInvalidVerificationToken
+	} else if err != nil {
+		return nil, err
+	}
+	// Truncate: los envíos anteriores a este chequeo guardaban la hora exacta.
+	if claims.IssuedAt == nil || claims.IssuedAt.Before(user.VerificationSentAt.Truncate(time.Second)) {
+		return nil, fmt.Errorf("%w: superseded by a later verification email", ErrInvalidVerificationToken)
+	}
+	if user.EmailVerified {
+		return user, nil
+	}
+	if err := c.repo.MarkVerified(ctx, user.Email); err != nil {
+		return nil, err
+	}
+	user.EmailVerified = true
+	return user, nil
+}
+
+// generateVerificationToken firma con la llave actual un token de
+// verificación para email emitido en now. Si la llave se rota, el token sirve
+// mientras el llavero conserve la anterior (ver keys.Set.KeepRetired).
+func (c *Controller) generateVerificationToken(email string, now time.Time) (string, error) {
+	claims := jwt.RegisteredClaims{
+		ID:        uuid.NewString(),
+		Subject:   email,
+		Issuer:    auth.Issuer,
+		IssuedAt:  jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(now.Add(c.verification.TokenTTL)),
+		Audience:  jwt.ClaimStrings{VerificationAudience},
+	}
+	key := c.keys.Current()
+	token := jwt.NewWithClaims(key.Method(), claims)
+	token.Header["kid"] = key.ID
+	return token.SignedString(key.SigningKey())
+}
+
+// checkVerified aplica RequireForLogin a un usuario que ya se autenticó.
+func (c *Controller) checkVerified(user *model.AuthUser) error {
+	if c.verification.RequireForLogin && !user.EmailVerified {
+		return ErrEmailNotVerified
+	}
+	return nil
+}
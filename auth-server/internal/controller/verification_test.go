@@ -0,0 +1,203 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"proyecto/auth-server/internal/mail"
+	"proyecto/auth-server/pkg/model"
+)
+
+// fakeMail guarda los emails en vez de mandarlos.
+type fakeMail struct {
+	mu   sync.Mutex
+	sent []mail.Message
+}
+
+func (m *fakeMail) Send(_ context.Context, msg mail.Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sent = append(m.sent, msg)
+	return nil
+}
+
+// lastToken devuelve el token del enlace del último email.
+func (m *fakeMail) lastToken(t *testing.T) string {
+	t.Helper()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.sent) == 0 {
+		t.Fatal("no se mandó ningún email")
+	}
+	body := m.sent[len(m.sent)-1].Body
+	_, rest, ok := strings.Cut(body, "?token=")
+	if !ok {
+		t.Fatalf("el email no tiene enlace: %q", body)
+	}
+	raw, _, _ := strings.Cut(rest, "\n")
+	token, err := url.QueryUnescape(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func (m *fakeMail) count() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.sent)
+}
+
+func newVerificationEnv(t *testing.T) (*testEnv, *fakeMail) {
+	t.Helper()
+	sender := &fakeMail{}
+	policy := DefaultVerificationPolicy()
+	policy.ResendInterval = time.Hour
+	policy.PublicURL = "https://auth.example.com"
+	return newTestEnv(t, WithMailSender(sender), WithVerificationPolicy(policy)), sender
+}
+
+// addUnverified da de alta un usuario sin verificar.
+func (env *testEnv) addUnverified(t *testing.T, email string) *model.AuthUser {
+	t.Helper()
+	user := &model.AuthUser{Email: email, Provider: "local", Role: "user", CreatedAt: time.Now()}
+	if err := env.users.Create(context.Background(), user); err != nil {
+		t.Fatal(err)
+	}
+	return user
+}
+
+func TestVerifyEmail(t *testing.T) {
+	env, sender := newVerificationEnv(t)
+	ctx := context.Background()
+	user := env.addUnverified(t, "ana@example.com")
+
+	if err := env.ctrl.SendVerification(ctx, user); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(sender.sent[0].Body, "https://auth.example.com"+VerifyEmailPath+"?token=") {
+		t.Errorf("enlace = %q", sender.sent[0].Body)
+	}
+	token := sender.lastToken(t)
+
+	got, err := env.ctrl.VerifyEmail(ctx, token)
+	if err != nil || !got.EmailVerified {
+		t.Fatalf("VerifyEmail = %+v, %v", got, err)
+	}
+	stored, _ := env.users.GetHashByEmail(ctx, user.Email)
+	if !stored.EmailVerified {
+		t.Error("el usuario guardado no quedó verificado")
+	}
+	// Usar el enlace de nuevo no es un error.
+	if _, err := env.ctrl.VerifyEmail(ctx, token); err != nil {
+		t.Errorf("VerifyEmail repetido = %v", err)
+	}
+}
+
+func TestVerifyEmailRejects(t *testing.T) {
+	env, _ := newVerificationEnv(t)
+	ctx := context.Background()
+	user := env.addUnverified(t, "ana@example.com")
+	user.VerificationSentAt = time.Now().Truncate(time.Second)
+	if err := env.users.Put(ctx, user); err != nil {
+		t.Fatal(err)
+	}
+
+	token := func(email string, issuedAt time.Time) string {
+		t.Helper()
+		raw, err := env.ctrl.generateVerificationToken(email, issuedAt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return raw
+	}
+	access, err := env.ctrl.GenerateAccessToken(user.Email, user.Email, user.Role, false, "jti", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, raw := range map[string]string{
+		"vencido":        token(user.Email, time.Now().Add(-48*time.Hour)),
+		"access token":   access,
+		"otro usuario":   token("nadie@example.com", time.Now()),
+		"envío anterior": token(user.Email, user.VerificationSentAt.Add(-time.Minute)),
+		"basura":         "no-es-un-jwt",
+	} {
+		if _, err := env.ctrl.VerifyEmail(ctx, raw); !errors.Is(err, ErrInvalidVerificationToken) {
+			t.Errorf("VerifyEmail con token %s = %v, quería ErrInvalidVerificationToken", name, err)
+		}
+	}
+	if stored, _ := env.users.GetHashByEmail(ctx, user.Email); stored.EmailVerified {
+		t.Error("un token rechazado verificó el email")
+	}
+}
+
+func TestVerifyEmailRecreatedAccount(t *testing.T) {
+	env, sender := newVerificationEnv(t)
+	ctx := context.Background()
+	old := env.addUnverified(t, "ana@example.com")
+	if err := env.ctrl.SendVerification(ctx, old); err != nil {
+		t.Fatal(err)
+	}
+	oldToken := sender.lastToken(t)
+
+	// La cuenta se borra y alguien se registra con el mismo email más tarde.
+	if err := env.users.Delete(ctx, old.Email); err != nil {
+		t.Fatal(err)
+	}
+	recreated := env.addUnverified(t, old.Email)
+	recreated.VerificationSentAt = old.VerificationSentAt.Add(time.Minute)
+	if err := env.users.Put(ctx, recreated); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := env.ctrl.VerifyEmail(ctx, oldToken); !errors.Is(err, ErrInvalidVerificationToken) {
+		t.Errorf("VerifyEmail con el enlace de la cuenta anterior = %v, quería ErrInvalidVerificationToken", err)
+	}
+}
+
+func TestResendVerification(t *testing.T) {
+	env, sender := newVerificationEnv(t)
+	ctx := context.Background()
+	user := env.addUnverified(t, "ana@example.com")
+	if err := env.ctrl.SendVerification(ctx, user); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := env.ctrl.ResendVerification(ctx, "Ana@Example.com "); !errors.Is(err, ErrResendTooSoon) {
+		t.Errorf("reenvío inmediato = %v, quería ErrResendTooSoon", err)
+	}
+	if n := sender.count(); n != 1 {
+		t.Errorf("emails mandados = %d, quería 1", n)
+	}
+
+	user.VerificationSentAt = time.Now().Add(-2 * time.Hour)
+	if err := env.users.Put(ctx, user); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.ctrl.ResendVerification(ctx, user.Email); err != nil {
+		t.Fatalf("reenvío pasado el intervalo = %v", err)
+	}
+	if n := sender.count(); n != 2 {
+		t.Errorf("emails mandados = %d, quería 2", n)
+	}
+	stored, _ := env.users.GetHashByEmail(ctx, user.Email)
+	if time.Since(stored.VerificationSentAt) > time.Minute {
+		t.Errorf("VerificationSentAt = %v, no se actualizó", stored.VerificationSentAt)
+	}
+
+	if _, err := env.ctrl.VerifyEmail(ctx, sender.lastToken(t)); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.ctrl.ResendVerification(ctx, user.Email); !errors.Is(err, ErrAlreadyVerified) {
+		t.Errorf("reenvío verificado = %v, quería ErrAlreadyVerified", err)
+	}
+	if err := env.ctrl.ResendVerification(ctx, "nadie@example.com"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("reenvío a email desconocido = %v, quería ErrNotFound", err)
+	}
+}
@@ -0,0 +1,52 @@
+package metadatauser
+
+import (
+	"context"
+	"errors"
+
+	"proyecto/auth-server/internal/controller"
+	"proyecto/metadataUser/client"
+	model "proyecto/metadataUser/pkg"
+)
+
+// Gateway adapta el cliente de metadata-user a lo que necesita el registro.
+type Gateway struct {
+	client *client.Client
+}
+
+func New(c *client.Client) *Gateway {
+	return &Gateway{client: c}
+}
+
+// CreateMetadata crea los metadatos del perfil con la Idempotency-Key de la
+// saga. Que ya existan se informa como controller.ErrMetadataExists para que
+// la saga decida si son suyos.
+func (g *Gateway) CreateMetadata(ctx context.Context, accessToken, idempotencyKey string, profile *controller.MetadataUser) error {
+	_, err := g.client.CreateWithKey(ctx, accessToken, idempotencyKey, &model.MetadataUser{
+		Email:       profile.Email,
+		FullName:    profile.FullName,
+		AvatarURL:   profile.AvatarURL,
+		PhoneNumber: profile.PhoneNumber,
+		BirthDate:   profile.BirthDate,
+	})
+	if errors.Is(err, client.ErrAlreadyExists) {
+		return controller.ErrMetadataExists
+	}
+	return err
+}
+
+// GetMetadata devuelve los metadatos guardados de email.
+func (g *Gateway) GetMetadata(ctx context.Context, accessToken, email string) (*controller.MetadataUser, error) {
+	m, err := g.client.Get(ctx, accessToken, email)
+	if err != nil {
+		return nil, err
+	}
+	return &controller.MetadataUser{
+		Email:       m.Email,
+		FullName:    m.FullName,
+		AvatarURL:   m.AvatarURL,
+		PhoneNumber: m.PhoneNumber,
+		BirthDate:   m.BirthDate,
+		LastUpdated: m.LastUpdated,
+	}, nil
+}
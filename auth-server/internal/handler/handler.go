@@ -4,68 +4,81 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
 	"log"
-	"math/rand"
 	"net/http"
-	"net/url"
-	"os"
-	"strings"
+	"net/mail"
+	"strconv"
 	"time"
 
-	"github.com/google/uuid"
-	"golang.org/x/crypto/bcrypt"
-
 	"proyecto/auth-server/internal/controller"
-	"proyecto/auth-server/internal/repository"
+	"proyecto/auth-server/internal/password"
 	"proyecto/auth-server/pkg/model"
-	registry "proyecto/pkg/registry" // <-- usa este nombre 'registry'
+	"proyecto/pkg/auth"
+	"proyecto/pkg/ratelimit"
 )
 
 type Handler struct {
-	ctrl     *controller.Controller
-	registry registry.Registry
+	ctrl         *controller.Controller
+	registration *controller.Registration
 }
 
-func New(ctrl *controller.Controller, reg registry.Registry) *Handler {
-	return &Handler{ctrl: ctrl, registry: reg}
+func New(ctrl *controller.Controller, registration *controller.Registration) *Handler {
+	return &Handler{ctrl: ctrl, registration: registration}
 }
 
 func (h *Handler) RegisterUser(w http.ResponseWriter, req *http.Request) {
 	ctx := req.Context()
 
-	password := req.FormValue("password")
-	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
-	if err != nil {
-		log.Printf("Error al hashear la contraseña: %v", err)
-		http.Error(w, "Error interno al registrar usuario", http.StatusInternalServerError)
+	// El auto-registro solo puede crear usuarios con el rol por defecto;
+	// los demás roles los asigna un admin con /Auth-Server/admin/role.
+	if role := req.FormValue("role"); role != "" && role != auth.DefaultRole {
+		http.Error(w, "Solo se puede registrar con el rol 'user'.", http.StatusForbidden)
 		return
 	}
 
-	user := model.AuthUser{
-		Email:        req.FormValue("email"),
-		PasswordHash: string(hash),
-		Provider:     req.FormValue("provider"),
-		Role:         req.FormValue("role"),
-		CreatedAt:    time.Now(),
-	}
-	if user.Email == "" {
+	email := auth.NormalizeEmail(req.FormValue("email"))
+	if email == "" {
 		http.Error(w, "El campo 'email' es obligatorio.", http.StatusBadRequest)
 		return
 	}
+	// Solo la dirección, sin nombre ni <>: "Oscar <oscar@example.com>" no se acepta.
+	if addr, err := mail.ParseAddress(email); err != nil || addr.Address != email {
+		http.Error(w, "El email no es válido.", http.StatusBadRequest)
+		return
+	}
 
-	createdUser, err := h.ctrl.Put(ctx, &user)
-	if err != nil && errors.Is(err, repository.ErrNotFound) {
-		http.Error(w, "No encontrado", http.StatusNotFound)
+	pw := req.FormValue("password")
+	var invalid *password.ValidationError
+	if err := h.ctrl.ValidatePassword(ctx, email, pw); errors.As(err, &invalid) {
+		// Se informan todas las reglas que fallaron, para corregirlas de una vez.
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"status":     "invalid_password",
+			"violations": invalid.Violations,
+		})
 		return
 	} else if err != nil {
-		log.Printf("Repository error: %v\n", err)
-		http.Error(w, "Error interno", http.StatusInternalServerError)
+		log.Printf("Error validando la contraseña: %v", err)
+		http.Error(w, "Error interno al registrar usuario", http.StatusInternalServerError)
 		return
 	}
+	hash, err := h.ctrl.HashPassword(pw)
+	if err != nil {
+		log.Printf("Error al hashear la contraseña: %v", err)
+		http.Error(w, "Error interno al registrar usuario", http.StatusInternalServerError)
+		return
+	}
+
+	user := model.AuthUser{
+		Email:        email,
+		PasswordHash: hash,
+		Provider:     req.FormValue("provider"),
+		Role:         auth.DefaultRole,
+	}
 
 	profile := controller.MetadataUser{
-		Email:       req.FormValue("email"),
+		Email:       email,
 		FullName:    req.FormValue("full_name"),
 		AvatarURL:   req.FormValue("avatar_url"),
 		PhoneNumber: req.FormValue("phone_number"),
@@ -73,44 +86,28 @@ func (h *Handler) RegisterUser(w http.ResponseWriter, req *http.Request) {
 		LastUpdated: time.Now().Format(time.RFC3339),
 	}
 
-	form := url.Values{}
-	form.Add("email", profile.Email)
-	form.Add("full_name", profile.FullName)
-	form.Add("avatar_url", profile.AvatarURL)
-	form.Add("phone_number", profile.PhoneNumber)
-	form.Add("birth_date", profile.BirthDate)
-	form.Add("last_updated", profile.LastUpdated)
-
-	addrs, err := h.registry.ServiceAddress(ctx, "metadata-user")
-	if err != nil {
-		log.Printf("Service discovery error: %v", err)
-		http.Error(w, "No se pudo resolver metadata-user", http.StatusBadGateway)
+	// Usuario + metadatos como saga: si metadata-user falla, el usuario se borra
+	// y el cliente puede reintentar el registro sin chocar con una cuenta huérfana.
+	createdUser, err := h.registration.Register(ctx, &user, &profile)
+	if errors.Is(err, controller.ErrAlreadyRegistered) {
+		http.Error(w, "Ya existe un usuario con ese email.", http.StatusConflict)
 		return
-	}
-	target := "http://" + addrs[rand.Intn(len(addrs))] + "/MetadataUser"
-
-	reqOut, err := http.NewRequestWithContext(ctx, http.MethodPost, target, strings.NewReader(form.Encode()))
-	if err != nil {
-		log.Printf("Error creando request a metadata-user: %v", err)
-		http.Error(w, "Error creando petición a metadata", http.StatusInternalServerError)
+	} else if errors.Is(err, controller.ErrMetadataConflict) {
+		log.Printf("Registro de %s deshecho: %v", user.Email, err)
+		http.Error(w, "Ya existen metadatos de otra cuenta con ese email.", http.StatusConflict)
 		return
-	}
-	reqOut.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-
-	client := &http.Client{Timeout: 5 * time.Second}
-	resp, err := client.Do(reqOut)
-	if err != nil {
-		log.Printf("Error llamando a metadata-user (%s): %v", target, err)
-		http.Error(w, "Error comunicando con metadata", http.StatusBadGateway)
-		return
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
-		body, _ := io.ReadAll(resp.Body)
-		log.Printf("Respuesta inesperada de metadata-user (%d): %s", resp.StatusCode, string(body))
+	} else if errors.Is(err, controller.ErrMetadataUnavailable) {
+		log.Printf("Registro de %s deshecho: %v", user.Email, err)
 		http.Error(w, "Error al crear metadatos", http.StatusBadGateway)
 		return
+	} else if err != nil {
+		log.Printf("Repository error: %v\n", err)
+		http.Error(w, "Error interno", http.StatusInternalServerError)
+		return
+	}
+	// Si el email no sale el registro vale igual: se puede pedir que se reenvíe.
+	if err := h.ctrl.SendVerification(ctx, createdUser); err != nil {
+		log.Printf("Error mandando la verificación: %v", err)
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -124,34 +121,82 @@ func (h *Handler) RegisterUser(w http.ResponseWriter, req *http.Request) {
 
 func (h *Handler) Login(w http.ResponseWriter, req *http.Request) {
 	email := req.FormValue("email")
-	password := req.FormValue("password")
+	pw := req.FormValue("password")
 
-	if email == "" || password == "" {
+	if email == "" || pw == "" {
 		http.Error(w, "Email y password son obligatorios.", http.StatusBadRequest)
 		return
 	}
 
 	ctx := req.Context()
-	user, err := h.ctrl.GetHashByEmail(ctx, email)
+	// Email inexistente y contraseña incorrecta responden igual, para no revelar qué emails existen.
+	user, err := h.ctrl.Authenticate(ctx, email, pw)
+	var locked *controller.LoginLockedError
+	if errors.As(err, &locked) {
+		w.Header().Set("Retry-After", strconv.Itoa(int(locked.RetryAfter().Seconds())))
+		http.Error(w, "Demasiados intentos; probá de nuevo más tarde.", http.StatusTooManyRequests)
+		return
+	} else if errors.Is(err, controller.ErrInvalidCredentials) {
+		log.Printf("Login fallido para %s desde %s", email, ratelimit.ClientIP(req))
+		http.Error(w, "Credenciales inválidas", http.StatusUnauthorized)
+		return
+	} else if errors.Is(err, controller.ErrEmailNotVerified) {
+		http.Error(w, "Tenés que verificar tu email antes de entrar.", http.StatusForbidden)
+		return
+	} else if err != nil {
+		log.Printf("Error en login: %v", err)
+		http.Error(w, "Error interno", http.StatusInternalServerError)
+		return
+	}
+
+	refreshToken, err := h.ctrl.IssueRefreshToken(ctx, user.Email)
 	if err != nil {
-		if errors.Is(err, repository.ErrNotFound) {
-			http.Error(w, "Usuario no encontrado", http.StatusUnauthorized)
-			return
-		}
+		log.Printf("Error generando refresh token: %v", err)
 		http.Error(w, "Error interno", http.StatusInternalServerError)
 		return
 	}
 
-	if !h.ctrl.CheckPasswordHash(password, user.PasswordHash) {
-		log.Printf("Login fallido: contraseña inválida para el usuario %s", email)
-		http.Error(w, "Credenciales inválidas", http.StatusUnauthorized)
+	h.writeTokens(w, req, user, refreshToken)
+}
+
+// Refresh canjea un refresh token por un par nuevo (access + refresh).
+// Cada refresh token sirve una sola vez; reusarlo revoca toda su familia.
+func (h *Handler) Refresh(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		http.Error(w, "Método no permitido", http.StatusMethodNotAllowed)
+		return
+	}
+
+	refreshToken := req.FormValue("refresh_token")
+	if refreshToken == "" {
+		http.Error(w, "El campo 'refresh_token' es obligatorio.", http.StatusBadRequest)
 		return
 	}
 
-	jti := uuid.NewString()
-	secret := []byte(os.Getenv("JWT_SECRET"))
+	next, user, err := h.ctrl.RotateRefreshToken(req.Context(), refreshToken)
+	if errors.Is(err, controller.ErrRefreshTokenReused) {
+		log.Printf("Refresh token reutilizado, familia revocada")
+		http.Error(w, "Refresh token inválido", http.StatusUnauthorized)
+		return
+	} else if errors.Is(err, controller.ErrInvalidRefreshToken) {
+		http.Error(w, "Refresh token inválido", http.StatusUnauthorized)
+		return
+	} else if errors.Is(err, controller.ErrEmailNotVerified) {
+		http.Error(w, "Tenés que verificar tu email antes de entrar.", http.StatusForbidden)
+		return
+	} else if err != nil {
+		log.Printf("Error rotando refresh token: %v", err)
+		http.Error(w, "Error interno", http.StatusInternalServerError)
+		return
+	}
+
+	h.writeTokens(w, req, user, next)
+}
 
-	accessToken, err := h.ctrl.GenerateAccessToken(secret, user.Email, user.Email, user.Role, jti, 15*time.Minute)
+// writeTokens firma un access token nuevo y responde con el par de tokens.
+func (h *Handler) writeTokens(w http.ResponseWriter, req *http.Request, user *model.AuthUser, refreshToken string) {
+	ttl := h.ctrl.AccessTokenTTL()
+	accessToken, err := h.ctrl.IssueAccessToken(req.Context(), user, ttl)
 	if err != nil {
 		log.Printf("Error generando token: %v", err)
 		http.Error(w, "Error interno", http.StatusInternalServerError)
@@ -160,8 +205,9 @@ func (h *Handler) Login(w http.ResponseWriter, req *http.Request) {
 
 	w.Header().Set("Content-Type", "application/json")
 	_ = json.NewEncoder(w).Encode(map[string]string{
-		"access_token": accessToken,
-		"token_type":   "Bearer",
-		"expires_in":   fmt.Sprintf("%d", int((15 * time.Minute).Seconds())),
+		"access_token":  accessToken,
+		"refresh_token": refreshToken,
+		"token_type":    "Bearer",
+		"expires_in":    fmt.Sprintf("%d", int(ttl.Seconds())),
 	})
 }
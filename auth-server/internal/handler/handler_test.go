@@ -0,0 +1,76 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"proyecto/auth-server/internal/controller"
+	"proyecto/auth-server/internal/keys"
+	"proyecto/auth-server/internal/password"
+	"proyecto/auth-server/internal/repository/memory"
+	"proyecto/auth-server/pkg/model"
+)
+
+func newTestHandler(t *testing.T) *Handler {
+	t.Helper()
+	users := memory.New()
+	hashers := password.NewHashers(password.Bcrypt{Cost: 4})
+	hash, err := hashers.Hash("una contraseña larga")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = users.Create(context.Background(), &model.AuthUser{
+		Email: "ana@example.com", PasswordHash: hash, Role: "user", EmailVerified: true, CreatedAt: time.Now(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctrl := controller.New(users, memory.NewRefreshTokenRepository(), memory.NewRevocationRepository(),
+		keys.NewSet(controller.DefaultAccessTokenTTL, keys.NewHMAC([]byte("secreto-de-prueba-de-32-bytes-!!"))),
+		controller.WithPasswordHashers(hashers),
+		controller.WithBreachedPasswords(nil),
+		controller.WithLoginAttempts(memory.NewLoginAttemptRepository()),
+		controller.WithLockoutPolicy(controller.LockoutPolicy{MaxFailures: 3, FailureWindow: time.Hour, Lockout: time.Hour}),
+	)
+	return New(ctrl, nil)
+}
+
+func login(h *Handler, email, pw string) *httptest.ResponseRecorder {
+	form := url.Values{"email": {email}, "password": {pw}}
+	req := httptest.NewRequest(http.MethodPost, "/Auth-Server/login", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rec := httptest.NewRecorder()
+	h.Login(rec, req)
+	return rec
+}
+
+func TestLoginUniformUnauthorized(t *testing.T) {
+	h := newTestHandler(t)
+
+	wrong := login(h, "ana@example.com", "otra")
+	unknown := login(h, "nadie@example.com", "otra")
+	if wrong.Code != http.StatusUnauthorized || unknown.Code != wrong.Code || unknown.Body.String() != wrong.Body.String() {
+		t.Errorf("contraseña incorrecta = %d %q, email inexistente = %d %q; tenían que ser el mismo 401",
+			wrong.Code, wrong.Body.String(), unknown.Code, unknown.Body.String())
+	}
+
+	if rec := login(h, "ana@example.com", "una contraseña larga"); rec.Code != http.StatusOK {
+		t.Errorf("login correcto = %d %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestLoginLocked(t *testing.T) {
+	h := newTestHandler(t)
+	for range 3 {
+		login(h, "ana@example.com", "otra")
+	}
+	rec := login(h, "ana@example.com", "una contraseña larga")
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") == "" {
+		t.Errorf("login bloqueado = %d (Retry-After %q), quería 429 con Retry-After", rec.Code, rec.Header().Get("Retry-After"))
+	}
+}
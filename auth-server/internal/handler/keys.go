@@ -0,0 +1,48 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"proyecto/auth-server/internal/keys"
+	"proyecto/pkg/auth"
+)
+
+// JWKS publica las llaves públicas vigentes para que otros servicios validen tokens.
+func (h *Handler) JWKS(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	_ = json.NewEncoder(w).Encode(h.ctrl.JWKS())
+}
+
+// RotateKey (requiere keys:rotate) cambia la llave de firma actual por una nueva.
+func (h *Handler) RotateKey(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		http.Error(w, "Método no permitido", http.StatusMethodNotAllowed)
+		return
+	}
+
+	claims, ok := h.authorize(w, req, auth.PermKeysRotate)
+	if !ok {
+		return
+	}
+
+	key, err := h.ctrl.RotateSigningKey()
+	if errors.Is(err, keys.ErrUnsupportedAlgorithm) {
+		http.Error(w, "La llave actual no se puede rotar desde la API", http.StatusConflict)
+		return
+	} else if err != nil {
+		log.Printf("Error rotando llave de firma: %v", err)
+		http.Error(w, "Error interno", http.StatusInternalServerError)
+		return
+	}
+	log.Printf("Llave de firma rotada por %s, nuevo kid=%s", claims.Email, key.ID)
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]string{
+		"kid": key.ID,
+		"alg": key.Algorithm,
+	})
+}
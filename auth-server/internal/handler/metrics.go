@@ -0,0 +1,22 @@
+package handler
+
+import (
+	"expvar"
+	"net/http"
+	"strings"
+
+	"proyecto/pkg/auth"
+)
+
+// Metrics publica las métricas de expvar (estado de los circuit breakers,
+// memoria, ...). Como Revoked, requiere la credencial de servicio o un token
+// con metrics:read: dicen más del servicio de lo que debería ver cualquiera.
+func (h *Handler) Metrics(w http.ResponseWriter, req *http.Request) {
+	raw, _ := strings.CutPrefix(req.Header.Get("Authorization"), "Bearer ")
+	if !h.ctrl.IsServiceToken(raw) {
+		if _, ok := h.authorize(w, req, auth.PermMetricsRead); !ok {
+			return
+		}
+	}
+	expvar.Handler().ServeHTTP(w, req)
+}
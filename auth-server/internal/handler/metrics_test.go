@@ -0,0 +1,44 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"proyecto/auth-server/pkg/model"
+	"proyecto/pkg/auth"
+)
+
+func TestMetricsRequiresPermission(t *testing.T) {
+	h := newTestHandler(t)
+	bearer := func(role string) string {
+		token, err := h.ctrl.IssueAccessToken(context.Background(), &model.AuthUser{Email: role + "@example.com", Role: role}, time.Minute)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return "Bearer " + token
+	}
+
+	for name, tc := range map[string]struct {
+		authorization string
+		want          int
+	}{
+		"sin token": {"", http.StatusUnauthorized},
+		"usuario":   {bearer(auth.RoleUser), http.StatusForbidden},
+		"soporte":   {bearer(auth.RoleSupport), http.StatusForbidden},
+		"admin":     {bearer(auth.RoleAdmin), http.StatusOK},
+		"inventado": {"Bearer no-es-un-jwt", http.StatusUnauthorized},
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/debug/vars", nil)
+		if tc.authorization != "" {
+			req.Header.Set("Authorization", tc.authorization)
+		}
+		rec := httptest.NewRecorder()
+		h.Metrics(rec, req)
+		if rec.Code != tc.want {
+			t.Errorf("%s: /debug/vars = %d, quería %d", name, rec.Code, tc.want)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"proyecto/auth-server/internal/controller"
+	"proyecto/pkg/auth"
+)
+
+// SetRole (requiere users:role:write) cambia el rol de un usuario.
+func (h *Handler) SetRole(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		http.Error(w, "Método no permitido", http.StatusMethodNotAllowed)
+		return
+	}
+
+	claims, ok := h.authorize(w, req, auth.PermUsersRoleWrite)
+	if !ok {
+		return
+	}
+
+	email := req.FormValue("email")
+	role := req.FormValue("role")
+	if email == "" || role == "" {
+		http.Error(w, "Los campos 'email' y 'role' son obligatorios.", http.StatusBadRequest)
+		return
+	}
+
+	err := h.ctrl.SetRole(req.Context(), email, role)
+	if errors.Is(err, controller.ErrInvalidRole) {
+		http.Error(w, "Rol inválido", http.StatusBadRequest)
+		return
+	} else if errors.Is(err, controller.ErrNotFound) {
+		http.Error(w, "Usuario no encontrado", http.StatusNotFound)
+		return
+	} else if err != nil {
+		log.Printf("Error cambiando rol de %s: %v", email, err)
+		http.Error(w, "Error interno", http.StatusInternalServerError)
+		return
+	}
+	log.Printf("Rol de %s cambiado a %s por %s", email, role, claims.Email)
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"email":       email,
+		"role":        role,
+		"permissions": auth.PermissionsFor(role),
+	})
+}
+
+// authorize autentica la petición y exige el permiso perm; si falla ya respondió 401/403.
+func (h *Handler) authorize(w http.ResponseWriter, req *http.Request, perm string) (*controller.AccessClaims, bool) {
+	claims, ok := h.authenticate(w, req)
+	if !ok {
+		return nil, false
+	}
+	if !claims.Has(perm) {
+		http.Error(w, "Acceso denegado", http.StatusForbidden)
+		return nil, false
+	}
+	return claims, true
+}
@@ -0,0 +1,147 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"proyecto/auth-server/internal/controller"
+	"proyecto/pkg/auth"
+)
+
+// Logout revoca el access token con el que se llama y, si se envía,
+// la familia del refresh token.
+func (h *Handler) Logout(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		http.Error(w, "Método no permitido", http.StatusMethodNotAllowed)
+		return
+	}
+
+	claims, ok := h.authenticate(w, req)
+	if !ok {
+		return
+	}
+
+	ctx := req.Context()
+	if err := h.ctrl.RevokeAccessToken(ctx, claims); err != nil {
+		log.Printf("Error revocando token %s: %v", claims.ID, err)
+		http.Error(w, "Error interno", http.StatusInternalServerError)
+		return
+	}
+
+	if refreshToken := req.FormValue("refresh_token"); refreshToken != "" {
+		err := h.ctrl.RevokeRefreshToken(ctx, refreshToken)
+		if err != nil && !errors.Is(err, controller.ErrInvalidRefreshToken) {
+			log.Printf("Error revocando refresh token: %v", err)
+			http.Error(w, "Error interno", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RevokeSessions (requiere sessions:revoke:any) revoca todas las sesiones vigentes de un email.
+func (h *Handler) RevokeSessions(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		http.Error(w, "Método no permitido", http.StatusMethodNotAllowed)
+		return
+	}
+
+	claims, ok := h.authorize(w, req, auth.PermSessionsRevokeAny)
+	if !ok {
+		return
+	}
+
+	email := req.FormValue("email")
+	if email == "" {
+		http.Error(w, "El campo 'email' es obligatorio.", http.StatusBadRequest)
+		return
+	}
+
+	n, err := h.ctrl.RevokeAllSessions(req.Context(), email)
+	if err != nil {
+		log.Printf("Error revocando sesiones de %s: %v", email, err)
+		http.Error(w, "Error interno", http.StatusInternalServerError)
+		return
+	}
+	log.Printf("Sesiones de %s revocadas por %s (%d access tokens)", email, claims.Email, n)
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"email":   email,
+		"revoked": n,
+	})
+}
+
+// Revoked permite a otros servicios consultar la lista de revocación:
+// con ?jti=... responde si ese jti está revocado, sin parámetros devuelve
+// la lista completa de jti vigentes (con su expiración) para cachearla.
+// Requiere la credencial de servicio o un token con revocations:read; la
+// lista no dice de quién es cada token.
+func (h *Handler) Revoked(w http.ResponseWriter, req *http.Request) {
+	raw, _ := strings.CutPrefix(req.Header.Get("Authorization"), "Bearer ")
+	if !h.ctrl.IsServiceToken(raw) {
+		if _, ok := h.authorize(w, req, auth.PermRevocationsRead); !ok {
+			return
+		}
+	}
+
+	ctx := req.Context()
+	w.Header().Set("Content-Type", "application/json")
+
+	if jti := req.FormValue("jti"); jti != "" {
+		revoked, err := h.ctrl.IsRevoked(ctx, jti)
+		if err != nil {
+			log.Printf("Error consultando revocación de %s: %v", jti, err)
+			http.Error(w, "Error interno", http.StatusInternalServerError)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"jti":     jti,
+			"revoked": revoked,
+		})
+		return
+	}
+
+	list, err := h.ctrl.ListRevoked(ctx)
+	if err != nil {
+		log.Printf("Error listando tokens revocados: %v", err)
+		http.Error(w, "Error interno", http.StatusInternalServerError)
+		return
+	}
+	type revokedJTI struct {
+		JTI       string    `json:"jti"`
+		ExpiresAt time.Time `json:"expires_at"`
+	}
+	published := make([]revokedJTI, 0, len(list))
+	for _, t := range list {
+		published = append(published, revokedJTI{JTI: t.JTI, ExpiresAt: t.ExpiresAt})
+	}
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"revoked": published,
+	})
+}
+
+// authenticate valida el bearer token de la petición; si falla ya respondió 401.
+func (h *Handler) authenticate(w http.ResponseWriter, req *http.Request) (*controller.AccessClaims, bool) {
+	raw, ok := strings.CutPrefix(req.Header.Get("Authorization"), "Bearer ")
+	if !ok || raw == "" {
+		http.Error(w, "Falta el token de acceso", http.StatusUnauthorized)
+		return nil, false
+	}
+
+	claims, err := h.ctrl.ParseAccessToken(req.Context(), raw)
+	if errors.Is(err, controller.ErrInvalidAccessToken) || errors.Is(err, controller.ErrTokenRevoked) {
+		http.Error(w, "Token de acceso inválido", http.StatusUnauthorized)
+		return nil, false
+	} else if err != nil {
+		log.Printf("Error validando token: %v", err)
+		http.Error(w, "Error interno", http.StatusInternalServerError)
+		return nil, false
+	}
+	return claims, true
+}
@@ -0,0 +1,76 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"proyecto/auth-server/internal/controller"
+)
+
+// VerifyEmail marca el email como verificado con el token del enlace que se mandó al registrarse.
+func (h *Handler) VerifyEmail(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet && req.Method != http.MethodPost {
+		http.Error(w, "Método no permitido", http.StatusMethodNotAllowed)
+		return
+	}
+
+	token := req.FormValue("token")
+	if token == "" {
+		http.Error(w, "El campo 'token' es obligatorio.", http.StatusBadRequest)
+		return
+	}
+
+	user, err := h.ctrl.VerifyEmail(req.Context(), token)
+	if errors.Is(err, controller.ErrInvalidVerificationToken) {
+		http.Error(w, "Enlace de verificación inválido o vencido.", http.StatusBadRequest)
+		return
+	} else if err != nil {
+		log.Printf("Error verificando email: %v", err)
+		http.Error(w, "Error interno", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"status":         "verified",
+		"email":          user.Email,
+		"email_verified": true,
+	})
+}
+
+// ResendVerification vuelve a mandar el email de verificación. Responde 202
+// siempre, exista o no el email y se haya mandado o no, para no revelar qué
+// emails están registrados.
+func (h *Handler) ResendVerification(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		http.Error(w, "Método no permitido", http.StatusMethodNotAllowed)
+		return
+	}
+
+	email := req.FormValue("email")
+	if email == "" {
+		http.Error(w, "El campo 'email' es obligatorio.", http.StatusBadRequest)
+		return
+	}
+
+	err := h.ctrl.ResendVerification(req.Context(), email)
+	switch {
+	case err == nil:
+		log.Printf("Verificación reenviada a %s", email)
+	case errors.Is(err, controller.ErrNotFound), errors.Is(err, controller.ErrAlreadyVerified):
+	case errors.Is(err, controller.ErrResendTooSoon):
+		log.Printf("Reenvío de verificación a %s ignorado: %v", email, err)
+	default:
+		log.Printf("Error reenviando la verificación a %s: %v", email, err)
+		http.Error(w, "Error interno", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	_ = json.NewEncoder(w).Encode(map[string]string{
+		"status": "accepted",
+	})
+}
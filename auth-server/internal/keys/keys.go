@@ -0,0 +1,207 @@
+package keys
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"proyecto/pkg/auth"
+	"proyecto/pkg/jwks"
+)
+
+// Algoritmos de firma soportados.
+const (
+	RS256 = "RS256"
+	EdDSA = "EdDSA"
+	HS256 = "HS256"
+)
+
+var ErrUnsupportedAlgorithm = errors.New("unsupported signing algorithm")
+
+// Key es una llave de firma de access tokens identificada por su kid.
+type Key struct {
+	ID        string
+	Algorithm string
+	CreatedAt time.Time
+
+	private any // *rsa.PrivateKey, ed25519.PrivateKey o []byte (HS256)
+	public  any // *rsa.PublicKey, ed25519.PublicKey o []byte (HS256)
+}
+
+// Method devuelve el método de firma de jwt que corresponde a la llave.
+func (k *Key) Method() jwt.SigningMethod {
+	return jwt.GetSigningMethod(k.Algorithm)
+}
+
+// SigningKey es la llave privada (o el secreto) con la que se firma.
+func (k *Key) SigningKey() any { return k.private }
+
+// VerifyKey es la llave con la que se verifica la firma.
+func (k *Key) VerifyKey() any { return k.public }
+
+// Generate crea una llave nueva en memoria para el algoritmo dado.
+func Generate(alg string) (*Key, error) {
+	switch alg {
+	case RS256:
+		priv, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			return nil, err
+		}
+		return fromPrivate(priv, time.Now())
+	case EdDSA:
+		_, priv, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		return fromPrivate(priv, time.Now())
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedAlgorithm, alg)
+	}
+}
+
+// NewHMAC envuelve un secreto compartido como llave HS256. No se publica en el JWKS.
+func NewHMAC(secret []byte) *Key {
+	sum := sha256.Sum256(secret)
+	return &Key{
+		ID:        "hs-" + base64.RawURLEncoding.EncodeToString(sum[:8]),
+		Algorithm: HS256,
+		CreatedAt: time.Now(),
+		private:   secret,
+		public:    secret,
+	}
+}
+
+// LoadPEM lee una llave privada RSA o Ed25519 (PKCS#8 o PKCS#1) de un archivo PEM.
+func LoadPEM(path string) (*Key, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s: no PEM block found", path)
+	}
+
+	var priv any
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		priv, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	default:
+		priv, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	return fromPrivate(priv, info.ModTime())
+}
+
+// LoadDir carga todos los *.pem de un directorio, ordenados del más viejo al
+// más nuevo según su fecha de modificación (el último queda como llave actual).
+func LoadDir(dir string) ([]*Key, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return nil, err
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no *.pem keys found in %s", dir)
+	}
+
+	var res []*Key
+	for _, p := range paths {
+		k, err := LoadPEM(p)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, k)
+	}
+	sort.SliceStable(res, func(i, j int) bool { return res[i].CreatedAt.Before(res[j].CreatedAt) })
+	return res, nil
+}
+
+// Load resuelve las llaves de firma iniciales: desde dir si se indicó, un
+// secreto compartido para HS256 (de al menos auth.MinSecretLength bytes; dir
+// tiene que estar vacío), o una llave generada al arrancar.
+func Load(alg, dir string, secret []byte) ([]*Key, error) {
+	if alg == "" {
+		alg = RS256
+	}
+	switch {
+	case alg == HS256 && dir != "":
+		return nil, errors.New("HS256 keys come from the shared secret, not from a key directory")
+	case alg == HS256:
+		if err := auth.CheckSecret(secret); err != nil {
+			return nil, err
+		}
+		return []*Key{NewHMAC(secret)}, nil
+	case dir != "":
+		loaded, err := LoadDir(dir)
+		if err != nil {
+			return nil, err
+		}
+		for _, k := range loaded {
+			if k.Algorithm != alg {
+				return nil, fmt.Errorf("key %s is %s, expected %s", k.ID, k.Algorithm, alg)
+			}
+		}
+		return loaded, nil
+	default:
+		k, err := Generate(alg)
+		if err != nil {
+			return nil, err
+		}
+		return []*Key{k}, nil
+	}
+}
+
+func fromPrivate(priv any, createdAt time.Time) (*Key, error) {
+	k := &Key{CreatedAt: createdAt, private: priv}
+	switch p := priv.(type) {
+	case *rsa.PrivateKey:
+		k.Algorithm, k.public = RS256, &p.PublicKey
+	case ed25519.PrivateKey:
+		k.Algorithm, k.public = EdDSA, p.Public()
+	default:
+		return nil, fmt.Errorf("%w: key type %T", ErrUnsupportedAlgorithm, priv)
+	}
+	jwk, _ := k.JWK()
+	k.ID = jwk.Thumbprint()
+	return k, nil
+}
+
+// JWK devuelve la llave pública en formato JWK; false para llaves HS256.
+func (k *Key) JWK() (jwks.JWK, bool) {
+	switch pub := k.public.(type) {
+	case *rsa.PublicKey:
+		return jwks.JWK{
+			Kty: "RSA", Kid: k.ID, Use: "sig", Alg: RS256,
+			N: base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+			E: base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+		}, true
+	case ed25519.PublicKey:
+		return jwks.JWK{
+			Kty: "OKP", Kid: k.ID, Use: "sig", Alg: EdDSA,
+			Crv: "Ed25519",
+			X:   base64.RawURLEncoding.EncodeToString(pub),
+		}, true
+	default:
+		return jwks.JWK{}, false
+	}
+}
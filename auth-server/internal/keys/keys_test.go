@@ -0,0 +1,147 @@
+package keys
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"proyecto/pkg/auth"
+)
+
+// writePEM guarda la llave privada de k en dir/name con fecha de modificación mtime.
+func writePEM(t *testing.T, dir, name string, k *Key, mtime time.Time) {
+	t.Helper()
+	der, err := x509.MarshalPKCS8PrivateKey(k.SigningKey())
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadDirOrder(t *testing.T) {
+	dir := t.TempDir()
+	base := time.Unix(1_700_000_000, 0)
+	oldest, middle, newest := generate(t, EdDSA), generate(t, EdDSA), generate(t, EdDSA)
+	// El nombre no importa: manda la fecha de modificación.
+	writePEM(t, dir, "a.pem", newest, base.Add(2*time.Hour))
+	writePEM(t, dir, "b.pem", oldest, base)
+	writePEM(t, dir, "c.pem", middle, base.Add(time.Hour))
+
+	loaded, err := LoadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{oldest.ID, middle.ID, newest.ID}
+	if len(loaded) != len(want) {
+		t.Fatalf("LoadDir cargó %d llaves, quería %d", len(loaded), len(want))
+	}
+	for i, k := range loaded {
+		if k.ID != want[i] {
+			t.Errorf("llave %d = %s, quería %s", i, k.ID, want[i])
+		}
+	}
+	// La última queda como actual.
+	if s := NewSet(time.Minute, loaded...); s.Current().ID != newest.ID {
+		t.Errorf("Current = %s, quería la más nueva %s", s.Current().ID, newest.ID)
+	}
+
+	if _, err := LoadDir(t.TempDir()); err == nil {
+		t.Error("LoadDir de un directorio sin *.pem = nil, quería un error")
+	}
+}
+
+func TestLoadRejectsOtherAlgorithm(t *testing.T) {
+	dir := t.TempDir()
+	writePEM(t, dir, "ed.pem", generate(t, EdDSA), time.Now())
+	if _, err := Load(RS256, dir, nil); err == nil {
+		t.Error("Load(RS256) con una llave EdDSA = nil, quería un error")
+	}
+}
+
+func TestHMACKeyID(t *testing.T) {
+	secret := []byte(strings.Repeat("s", 32))
+	a, b := NewHMAC(secret), NewHMAC(append([]byte(nil), secret...))
+	// El kid sale del secreto: todas las instancias con el mismo secreto coinciden.
+	if a.ID != b.ID || !strings.HasPrefix(a.ID, "hs-") {
+		t.Errorf("kid = %q y %q, quería el mismo con prefijo hs-", a.ID, b.ID)
+	}
+	if other := NewHMAC([]byte(strings.Repeat("t", 32))); other.ID == a.ID {
+		t.Error("dos secretos distintos dan el mismo kid")
+	}
+	if strings.Contains(a.ID, string(secret[:8])) {
+		t.Error("el kid deja ver el secreto")
+	}
+
+	loaded, err := Load(HS256, "", secret)
+	if err != nil || len(loaded) != 1 || loaded[0].ID != a.ID {
+		t.Fatalf("Load(HS256) = %v, %v; quería la llave %s", loaded, err, a.ID)
+	}
+	if _, err := Load(HS256, "", []byte("corto")); !errors.Is(err, auth.ErrWeakSecret) {
+		t.Errorf("Load(HS256) con secreto corto = %v, quería ErrWeakSecret", err)
+	}
+}
+
+func TestLoadHS256RejectsKeyDir(t *testing.T) {
+	if _, err := Load(HS256, t.TempDir(), []byte(strings.Repeat("s", 32))); err == nil {
+		t.Error("Load(HS256) con directorio de llaves = nil, quería un error")
+	}
+}
+
+func TestReloadKeyDir(t *testing.T) {
+	dir := t.TempDir()
+	base := time.Unix(1_700_000_000, 0)
+	old := generate(t, EdDSA)
+	writePEM(t, dir, "old.pem", old, base)
+	s, _ := newTestSet(time.Hour)
+	s.Rotate(old)
+
+	// Se agrega una llave más nueva al directorio y llega el SIGHUP.
+	next := generate(t, EdDSA)
+	writePEM(t, dir, "next.pem", next, base.Add(time.Hour))
+	got, err := s.Reload(EdDSA, dir, nil)
+	if err != nil || got.ID != next.ID || s.Current().ID != next.ID {
+		t.Fatalf("Reload = %v, %v; quería %s como actual", got, err, next.ID)
+	}
+	if _, ok := s.Lookup(old.ID); !ok {
+		t.Error("tras Reload ya no se acepta la llave anterior")
+	}
+
+	// Una recarga que falla deja el llavero como estaba.
+	if err := os.WriteFile(filepath.Join(dir, "roto.pem"), []byte("no es PEM"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Reload(EdDSA, dir, nil); err == nil {
+		t.Error("Reload con un PEM roto = nil, quería un error")
+	}
+	if s.Current().ID != next.ID {
+		t.Errorf("Current tras la recarga fallida = %s, quería %s", s.Current().ID, next.ID)
+	}
+}
+
+func TestReloadHS256Secret(t *testing.T) {
+	first, second := []byte(strings.Repeat("a", 32)), []byte(strings.Repeat("b", 32))
+	s, _ := newTestSet(time.Hour)
+	s.Rotate(NewHMAC(first))
+
+	got, err := s.Reload(HS256, "", second)
+	if err != nil || got.ID != NewHMAC(second).ID || s.Current().ID != got.ID {
+		t.Fatalf("Reload = %v, %v; quería la llave del secreto nuevo", got, err)
+	}
+	if _, ok := s.Lookup(NewHMAC(first).ID); !ok {
+		t.Error("tras Reload ya no se acepta el secreto anterior")
+	}
+	if _, err := s.Reload(HS256, "", []byte("corto")); !errors.Is(err, auth.ErrWeakSecret) {
+		t.Errorf("Reload con secreto corto = %v, quería ErrWeakSecret", err)
+	}
+}
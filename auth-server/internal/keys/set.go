@@ -0,0 +1,160 @@
+package keys
+
+import (
+	"sync"
+	"time"
+
+	"proyecto/pkg/jwks"
+)
+
+// Set es el llavero de auth-server: una llave actual con la que se firma y las
+// llaves anteriores, que siguen sirviendo para verificar hasta que expiren los
+// tokens que firmaron.
+type Set struct {
+	mu        sync.RWMutex
+	current   *Key
+	retired   map[string]retiredKey
+	retention time.Duration // lo que se acepta (y publica) una llave retirada
+	keep      time.Duration // lo que se guarda, para LookupWithin
+	now       func() time.Time
+}
+
+type retiredKey struct {
+	key       *Key
+	retiredAt time.Time
+}
+
+// NewSet crea el llavero. retention debe ser al menos la vida de un access token.
+// La última llave de keys queda como actual; las demás se tratan como retiradas.
+func NewSet(retention time.Duration, keys ...*Key) *Set {
+	s := &Set{retired: map[string]retiredKey{}, retention: retention, keep: retention, now: time.Now}
+	for _, k := range keys {
+		s.Rotate(k)
+	}
+	return s
+}
+
+// KeepRetired guarda las llaves retiradas durante d, si es más que la
+// retención, para verificar con LookupWithin tokens que duran más que un
+// access token (p.ej. los de verificación del email). Lookup y JWKS siguen
+// usando la retención.
+func (s *Set) KeepRetired(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keep = max(d, s.retention)
+}
+
+// Current devuelve la llave con la que se firman los tokens nuevos.
+func (s *Set) Current() *Key {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.current
+}
+
+// Lookup busca una llave vigente (actual o retirada) por kid.
+func (s *Set) Lookup(kid string) (*Key, bool) {
+	return s.LookupWithin(kid, s.retention)
+}
+
+// LookupWithin busca por kid la llave actual o una retirada hace menos de d
+// (como mucho lo que indica KeepRetired).
+func (s *Set) LookupWithin(kid string, d time.Duration) (*Key, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.current != nil && s.current.ID == kid {
+		return s.current, true
+	}
+	r, ok := s.retired[kid]
+	if !ok || !s.valid(r, d) {
+		return nil, false
+	}
+	return r.key, true
+}
+
+// valid responde si r se retiró hace menos de d.
+func (s *Set) valid(r retiredKey, d time.Duration) bool {
+	return s.now().Before(r.retiredAt.Add(d))
+}
+
+// Rotate pone next como llave actual; la anterior se sigue aceptando durante retention.
+func (s *Set) Rotate(next *Key) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for kid, r := range s.retired {
+		if !s.valid(r, s.keep) {
+			delete(s.retired, kid)
+		}
+	}
+	if s.current != nil && s.current.ID != next.ID {
+		s.retired[s.current.ID] = retiredKey{key: s.current, retiredAt: s.now()}
+	}
+	delete(s.retired, next.ID)
+	s.current = next
+}
+
+// Reload vuelve a resolver las llaves con Load y pone la más nueva como
+// actual; la anterior se sigue aceptando durante la retención. Si falla, el
+// llavero queda como estaba.
+func (s *Set) Reload(alg, dir string, secret []byte) (*Key, error) {
+	loaded, err := Load(alg, dir, secret)
+	if err != nil {
+		return nil, err
+	}
+	next := loaded[len(loaded)-1]
+	s.Rotate(next)
+	return next, nil
+}
+
+// Algorithms devuelve los algoritmos de las llaves vigentes, para restringir
+// qué "alg" se acepta al verificar.
+func (s *Set) Algorithms() []string {
+	return s.AlgorithmsWithin(s.retention)
+}
+
+// AlgorithmsWithin es Algorithms con las llaves que acepta LookupWithin(kid, d).
+func (s *Set) AlgorithmsWithin(d time.Duration) []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	seen := map[string]bool{}
+	var res []string
+	add := func(k *Key) {
+		if !seen[k.Algorithm] {
+			seen[k.Algorithm] = true
+			res = append(res, k.Algorithm)
+		}
+	}
+	if s.current != nil {
+		add(s.current)
+	}
+	for _, r := range s.retired {
+		if s.valid(r, d) {
+			add(r.key)
+		}
+	}
+	return res
+}
+
+// JWKS devuelve las llaves públicas vigentes. Las llaves HS256 nunca se publican.
+func (s *Set) JWKS() jwks.Set {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	res := jwks.Set{Keys: []jwks.JWK{}}
+	if s.current != nil {
+		if jwk, ok := s.current.JWK(); ok {
+			res.Keys = append(res.Keys, jwk)
+		}
+	}
+	for _, r := range s.retired {
+		if !s.valid(r, s.retention) {
+			continue
+		}
+		if jwk, ok := r.key.JWK(); ok {
+			res.Keys = append(res.Keys, jwk)
+		}
+	}
+	return res
+}
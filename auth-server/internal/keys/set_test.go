@@ -0,0 +1,142 @@
+package keys
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testClock es un reloj que solo avanza cuando el test lo pide.
+type testClock struct{ t time.Time }
+
+func (c *testClock) now() time.Time          { return c.t }
+func (c *testClock) advance(d time.Duration) { c.t = c.t.Add(d) }
+
+// newTestSet crea un llavero vacío con el reloj del test.
+func newTestSet(retention time.Duration) (*Set, *testClock) {
+	clock := &testClock{t: time.Unix(1_700_000_000, 0)}
+	s := NewSet(retention)
+	s.now = clock.now
+	return s, clock
+}
+
+func generate(t *testing.T, alg string) *Key {
+	t.Helper()
+	k, err := Generate(alg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return k
+}
+
+func TestKeepRetired(t *testing.T) {
+	s, clock := newTestSet(15 * time.Minute)
+	s.KeepRetired(24 * time.Hour)
+	old, next := generate(t, EdDSA), generate(t, EdDSA)
+	s.Rotate(old)
+	s.Rotate(next)
+
+	clock.advance(16 * time.Minute)
+	if _, ok := s.Lookup(old.ID); ok {
+		t.Error("Lookup acepta la llave retirada pasada la retención de los access tokens")
+	}
+	if len(s.JWKS().Keys) != 1 {
+		t.Errorf("JWKS publica %d llaves, quería solo la actual", len(s.JWKS().Keys))
+	}
+	if _, ok := s.LookupWithin(old.ID, 24*time.Hour); !ok {
+		t.Error("LookupWithin no encuentra la llave guardada por KeepRetired")
+	}
+	if _, ok := s.LookupWithin(old.ID, 10*time.Minute); ok {
+		t.Error("LookupWithin acepta una llave retirada hace más de d")
+	}
+
+	clock.advance(24 * time.Hour)
+	if _, ok := s.LookupWithin(old.ID, 24*time.Hour); ok {
+		t.Error("LookupWithin acepta la llave pasado KeepRetired")
+	}
+	s.Rotate(generate(t, EdDSA))
+	if _, ok := s.retired[old.ID]; ok {
+		t.Error("Rotate no borró la llave vencida")
+	}
+}
+
+func TestRotate(t *testing.T) {
+	s, clock := newTestSet(15 * time.Minute)
+	old, next := generate(t, RS256), generate(t, EdDSA)
+	s.Rotate(old)
+	s.Rotate(next)
+
+	if s.Current() != next {
+		t.Errorf("Current = %s, quería la llave nueva %s", s.Current().ID, next.ID)
+	}
+	// La anterior sigue verificando los tokens que firmó.
+	if k, ok := s.Lookup(old.ID); !ok || k != old {
+		t.Error("Lookup no encuentra la llave retirada dentro de la retención")
+	}
+	if got := s.Algorithms(); len(got) != 2 {
+		t.Errorf("Algorithms = %v, quería EdDSA y RS256", got)
+	}
+	// Rotar a la llave actual no la retira.
+	s.Rotate(next)
+	if _, ok := s.retired[next.ID]; ok {
+		t.Error("Rotate con la llave actual la marcó como retirada")
+	}
+
+	clock.advance(15 * time.Minute)
+	if _, ok := s.Lookup(old.ID); ok {
+		t.Error("Lookup acepta la llave retirada pasada la retención")
+	}
+	if got := s.Algorithms(); len(got) != 1 || got[0] != EdDSA {
+		t.Errorf("Algorithms = %v, quería solo EdDSA", got)
+	}
+	if _, ok := s.Lookup("desconocido"); ok {
+		t.Error("Lookup acepta un kid desconocido")
+	}
+}
+
+func TestJWKS(t *testing.T) {
+	s, clock := newTestSet(15 * time.Minute)
+	rsaKey, edKey := generate(t, RS256), generate(t, EdDSA)
+	s.Rotate(rsaKey)
+	s.Rotate(edKey)
+
+	data, err := json.Marshal(s.JWKS())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc struct {
+		Keys []map[string]any `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{rsaKey.ID: RS256, edKey.ID: EdDSA}
+	if len(doc.Keys) != len(want) {
+		t.Fatalf("JWKS = %s, quería %d llaves", data, len(want))
+	}
+	for _, k := range doc.Keys {
+		if kid, _ := k["kid"].(string); want[kid] == "" || k["alg"] != want[kid] {
+			t.Errorf("llave publicada con kid %v y alg %v, no es ninguna del llavero", k["kid"], k["alg"])
+		}
+		// Los parámetros privados de RSA y de OKP no se publican nunca.
+		for _, private := range []string{"d", "p", "q", "dp", "dq", "qi", "k"} {
+			if _, ok := k[private]; ok {
+				t.Errorf("la llave %v publica el parámetro privado %q", k["kid"], private)
+			}
+		}
+	}
+
+	// La retirada deja de publicarse al vencer su retención.
+	clock.advance(15 * time.Minute)
+	if got := s.JWKS().Keys; len(got) != 1 || got[0].Kid != edKey.ID {
+		t.Errorf("JWKS tras la retención = %+v, quería solo %s", got, edKey.ID)
+	}
+
+	// Las llaves HS256 no se publican.
+	hs, _ := newTestSet(15 * time.Minute)
+	hs.Rotate(NewHMAC([]byte(strings.Repeat("s", 32))))
+	if got := hs.JWKS().Keys; len(got) != 0 {
+		t.Errorf("JWKS con HS256 = %+v, quería vacío", got)
+	}
+}
@@ -0,0 +1,106 @@
+// Package mail manda los emails de auth-server (por ahora, el de verificación).
+// No hay SMTP: LogSender los escribe en el log y FileSender los guarda como
+// .eml en un directorio; otro envío se agrega implementando Sender.
+package mail
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Backends de envío.
+const (
+	BackendLog  = "log"
+	BackendFile = "file"
+)
+
+// Message es un email de texto plano.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Sender manda un email.
+type Sender interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// LogSender escribe los emails en el log; sirve para desarrollo.
+type LogSender struct{}
+
+func (LogSender) Send(_ context.Context, msg Message) error {
+	log.Printf("Email para %s: %s\n%s", msg.To, msg.Subject, msg.Body)
+	return nil
+}
+
+// FileSender guarda cada email como un .eml en Dir, que se crea si no existe.
+type FileSender struct {
+	Dir  string
+	From string
+}
+
+func (s FileSender) Send(_ context.Context, msg Message) error {
+	if err := os.MkdirAll(s.Dir, 0o700); err != nil {
+		return err
+	}
+	now := time.Now()
+	name := now.UTC().Format("20060102T150405") + "-" + uuid.NewString() + ".eml"
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", s.From)
+	fmt.Fprintf(&b, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", msg.Subject)
+	fmt.Fprintf(&b, "Date: %s\r\n", now.Format(time.RFC1123Z))
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	b.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+	// Se escribe aparte y se renombra, para que quien lea el directorio no vea emails a medias.
+	tmp := filepath.Join(s.Dir, "."+name)
+	if err := os.WriteFile(tmp, []byte(b.String()), 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(s.Dir, name))
+}
+
+// Config elige cómo se mandan los emails.
+type Config struct {
+	Backend string `yaml:"backend" env:"MAIL_BACKEND"` // log (por defecto) o file
+	Dir     string `yaml:"dir" env:"MAIL_DIR"`         // solo file
+	From    string `yaml:"from" env:"MAIL_FROM"`       // remitente (solo file)
+}
+
+func DefaultConfig() Config {
+	return Config{Backend: BackendLog, From: "no-reply@localhost"}
+}
+
+func (c Config) Validate() error {
+	var errs []error
+	switch c.Backend {
+	case BackendLog:
+	case BackendFile:
+		if c.Dir == "" {
+			errs = append(errs, errors.New("mail.dir (MAIL_DIR) es obligatorio con el backend file"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("mail.backend desconocido: %q (log o file)", c.Backend))
+	}
+	if c.From == "" {
+		errs = append(errs, errors.New("mail.from es obligatorio"))
+	}
+	return errors.Join(errs...)
+}
+
+// Sender arma el Sender de la config (ya validada).
+func (c Config) Sender() Sender {
+	if c.Backend == BackendFile {
+		return FileSender{Dir: c.Dir, From: c.From}
+	}
+	return LogSender{}
+}
@@ -0,0 +1,88 @@
+package password
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha1"
+	_ "embed"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// prefixLength es cuántos caracteres del SHA-1 identifican un rango, como en
+// la API de rangos de Have I Been Pwned: solo el prefijo sale de quien consulta.
+const prefixLength = 5
+
+//go:embed breached-sha1.txt
+var defaultBreached string
+
+// Breached es una lista de SHA-1 de contraseñas filtradas agrupada por prefijo.
+type Breached struct {
+	ranges map[string]map[string]struct{} // prefijo -> sufijos
+}
+
+// DefaultBreached es la lista que viene con el servicio.
+func DefaultBreached() *Breached {
+	b, err := ReadBreached(strings.NewReader(defaultBreached))
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+// OpenBreached lee una lista con el formato de las descargas de Have I Been
+// Pwned: una línea HASH o HASH:VECES por contraseña; # comenta.
+func OpenBreached(path string) (*Breached, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	b, err := ReadBreached(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return b, nil
+}
+
+func ReadBreached(r io.Reader) (*Breached, error) {
+	b := &Breached{ranges: map[string]map[string]struct{}{}}
+	sc := bufio.NewScanner(r)
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		hash, _, _ := strings.Cut(line, ":")
+		hash = strings.ToUpper(hash)
+		if _, err := hex.DecodeString(hash); err != nil || len(hash) != 2*sha1.Size {
+			return nil, fmt.Errorf("line %d: invalid SHA-1 %q", n, hash)
+		}
+		prefix, suffix := hash[:prefixLength], hash[prefixLength:]
+		if b.ranges[prefix] == nil {
+			b.ranges[prefix] = map[string]struct{}{}
+		}
+		b.ranges[prefix][suffix] = struct{}{}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// Range devuelve los sufijos de los hashes que empiezan con prefix.
+func (b *Breached) Range(prefix string) map[string]struct{} {
+	return b.ranges[strings.ToUpper(prefix)]
+}
+
+// Breached responde si password está en la lista. Solo se busca por el
+// prefijo del hash, así la lista se puede cambiar por un servicio remoto de rangos.
+func (b *Breached) Breached(_ context.Context, password string) (bool, error) {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	_, ok := b.Range(hash[:prefixLength])[hash[prefixLength:]]
+	return ok, nil
+}
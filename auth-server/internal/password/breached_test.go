@@ -0,0 +1,47 @@
+package password
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+// SHA-1 de "password".
+const passwordSHA1 = "5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8"
+
+func TestReadBreached(t *testing.T) {
+	list := "# comentario\n\n" + strings.ToLower(passwordSHA1) + ":3861493\n" +
+		"7C4A8D09CA3762AF61E59520943DC26494F8941B\n" // "123456"
+	b, err := ReadBreached(strings.NewReader(list))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for pw, want := range map[string]bool{"password": true, "123456": true, "Password": false, "otra-clave": false} {
+		got, err := b.Breached(context.Background(), pw)
+		if err != nil || got != want {
+			t.Errorf("Breached(%q) = %v, %v; quería %v", pw, got, err, want)
+		}
+	}
+
+	// El rango se consulta con el prefijo en cualquier caso y solo trae los sufijos.
+	r := b.Range(strings.ToLower(passwordSHA1[:prefixLength]))
+	if _, ok := r[passwordSHA1[prefixLength:]]; !ok || len(r) != 1 {
+		t.Errorf("Range = %v", r)
+	}
+}
+
+func TestReadBreachedRejectsInvalidHash(t *testing.T) {
+	for _, line := range []string{"no-es-hex", passwordSHA1[:39], passwordSHA1 + "00", "ZZAA61E4C9B93F3F0682250B6CF8331B7EE68FD8"} {
+		if _, err := ReadBreached(strings.NewReader(line + "\n")); err == nil {
+			t.Errorf("ReadBreached aceptó %q", line)
+		}
+	}
+}
+
+func TestDefaultBreached(t *testing.T) {
+	got, err := DefaultBreached().Breached(context.Background(), "password")
+	if err != nil || !got {
+		t.Errorf("la lista por defecto no tiene \"password\": %v, %v", got, err)
+	}
+}
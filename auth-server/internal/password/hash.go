@@ -0,0 +1,210 @@
+package password
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Algoritmos de hash (HashConfig.Algorithm).
+const (
+	AlgArgon2id = "argon2id"
+	AlgBcrypt   = "bcrypt"
+)
+
+var ErrUnknownHash = errors.New("unknown password hash format")
+
+// Hasher es un algoritmo de hash de contraseñas. Cada uno reconoce sus
+// hashes por el prefijo ($argon2id$, $2a$...).
+type Hasher interface {
+	Hash(password string) (string, error)
+	// Verify devuelve false si password no corresponde a hash.
+	Verify(password, hash string) (bool, error)
+	// Handles responde si hash es de este algoritmo.
+	Handles(hash string) bool
+	// Outdated responde si hash (de este algoritmo) usa otros parámetros que los actuales.
+	Outdated(hash string) bool
+}
+
+// Bcrypt hashea con bcrypt; solo usa los primeros 72 bytes de la contraseña.
+type Bcrypt struct {
+	Cost int
+}
+
+func (b Bcrypt) Hash(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), b.Cost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+func (b Bcrypt) Verify(password, hash string) (bool, error) {
+	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (b Bcrypt) Handles(hash string) bool {
+	return strings.HasPrefix(hash, "$2a$") || strings.HasPrefix(hash, "$2b$") || strings.HasPrefix(hash, "$2y$")
+}
+
+func (b Bcrypt) Outdated(hash string) bool {
+	cost, err := bcrypt.Cost([]byte(hash))
+	return err != nil || cost != b.Cost
+}
+
+// Argon2id hashea con Argon2id (RFC 9106) en el formato PHC:
+// $argon2id$v=19$m=<KiB>,t=<iteraciones>,p=<hilos>$<sal>$<hash>.
+type Argon2id struct {
+	Memory      uint32 // en KiB
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+// DefaultArgon2id son los parámetros mínimos que recomienda OWASP (19 MiB, 2 iteraciones).
+var DefaultArgon2id = Argon2id{Memory: 19 * 1024, Iterations: 2, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+
+const argon2Prefix = "$argon2id$"
+
+func (a Argon2id) Hash(password string) (string, error) {
+	salt := make([]byte, a.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := argon2.IDKey([]byte(password), salt, a.Iterations, a.Memory, a.Parallelism, a.KeyLength)
+	b64 := base64.RawStdEncoding
+	return fmt.Sprintf("%sv=%d$m=%d,t=%d,p=%d$%s$%s", argon2Prefix, argon2.Version,
+		a.Memory, a.Iterations, a.Parallelism, b64.EncodeToString(salt), b64.EncodeToString(key)), nil
+}
+
+func (a Argon2id) Verify(password, hash string) (bool, error) {
+	params, salt, key, err := parseArgon2id(hash)
+	if err != nil {
+		return false, err
+	}
+	other := argon2.IDKey([]byte(password), salt, params.Iterations, params.Memory, params.Parallelism, uint32(len(key)))
+	return subtle.ConstantTimeCompare(key, other) == 1, nil
+}
+
+func (a Argon2id) Handles(hash string) bool {
+	return strings.HasPrefix(hash, argon2Prefix)
+}
+
+func (a Argon2id) Outdated(hash string) bool {
+	params, salt, key, err := parseArgon2id(hash)
+	return err != nil || params.Memory != a.Memory || params.Iterations != a.Iterations ||
+		params.Parallelism != a.Parallelism || uint32(len(salt)) != a.SaltLength || uint32(len(key)) != a.KeyLength
+}
+
+func parseArgon2id(hash string) (params Argon2id, salt, key []byte, err error) {
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return params, nil, nil, ErrUnknownHash
+	}
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return params, nil, nil, fmt.Errorf("%w: argon2 version %q", ErrUnknownHash, parts[2])
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Iterations, &params.Parallelism); err != nil {
+		return params, nil, nil, fmt.Errorf("%w: %v", ErrUnknownHash, err)
+	}
+	b64 := base64.RawStdEncoding
+	if salt, err = b64.DecodeString(parts[4]); err != nil {
+		return params, nil, nil, fmt.Errorf("%w: %v", ErrUnknownHash, err)
+	}
+	if key, err = b64.DecodeString(parts[5]); err != nil {
+		return params, nil, nil, fmt.Errorf("%w: %v", ErrUnknownHash, err)
+	}
+	return params, salt, key, nil
+}
+
+// Hashers hashea las contraseñas nuevas con un algoritmo y verifica las
+// guardadas con cualquiera de los conocidos, para poder migrar de uno a otro.
+type Hashers struct {
+	current Hasher
+	all     []Hasher
+}
+
+// NewHashers usa current para las contraseñas nuevas; old son los algoritmos
+// que solo se verifican (y se reemplazan por current en el próximo login).
+func NewHashers(current Hasher, old ...Hasher) *Hashers {
+	return &Hashers{current: current, all: append([]Hasher{current}, old...)}
+}
+
+func (h *Hashers) Hash(password string) (string, error) {
+	return h.current.Hash(password)
+}
+
+// Verify comprueba password contra hash. rehash es true si la contraseña es
+// correcta pero el hash es de otro algoritmo o con otros parámetros que los
+// actuales, y conviene reemplazarlo.
+func (h *Hashers) Verify(password, hash string) (ok, rehash bool, err error) {
+	for _, hasher := range h.all {
+		if !hasher.Handles(hash) {
+			continue
+		}
+		ok, err := hasher.Verify(password, hash)
+		if err != nil || !ok {
+			return false, false, err
+		}
+		return true, hasher != h.current || hasher.Outdated(hash), nil
+	}
+	return false, false, ErrUnknownHash
+}
+
+// HashConfig elige el algoritmo de las contraseñas nuevas y sus parámetros.
+type HashConfig struct {
+	Algorithm  string `yaml:"algorithm" env:"PASSWORD_HASH"` // argon2id (por defecto) o bcrypt
+	BcryptCost int    `yaml:"bcrypt_cost" env:"BCRYPT_COST"`
+	// Parámetros de Argon2id: memoria en KiB, iteraciones e hilos.
+	Argon2Memory      uint32 `yaml:"argon2_memory" env:"ARGON2_MEMORY"`
+	Argon2Iterations  uint32 `yaml:"argon2_iterations" env:"ARGON2_ITERATIONS"`
+	Argon2Parallelism uint8  `yaml:"argon2_parallelism" env:"ARGON2_PARALLELISM"`
+}
+
+func DefaultHashConfig() HashConfig {
+	return HashConfig{
+		Algorithm:         AlgArgon2id,
+		BcryptCost:        bcrypt.DefaultCost,
+		Argon2Memory:      DefaultArgon2id.Memory,
+		Argon2Iterations:  DefaultArgon2id.Iterations,
+		Argon2Parallelism: DefaultArgon2id.Parallelism,
+	}
+}
+
+func (c HashConfig) Validate() error {
+	var errs []error
+	if c.Algorithm != AlgArgon2id && c.Algorithm != AlgBcrypt {
+		errs = append(errs, fmt.Errorf("password_hash.algorithm desconocido: %q (argon2id o bcrypt)", c.Algorithm))
+	}
+	if c.BcryptCost < bcrypt.MinCost || c.BcryptCost > bcrypt.MaxCost {
+		errs = append(errs, fmt.Errorf("password_hash.bcrypt_cost debe estar entre %d y %d", bcrypt.MinCost, bcrypt.MaxCost))
+	}
+	if c.Argon2Memory < 8*uint32(c.Argon2Parallelism) || c.Argon2Iterations < 1 || c.Argon2Parallelism < 1 {
+		errs = append(errs, errors.New("password_hash: argon2 necesita iterations >= 1, parallelism >= 1 y memory >= 8*parallelism KiB"))
+	}
+	return errors.Join(errs...)
+}
+
+// Hashers arma los hashers: el algoritmo configurado para las contraseñas
+// nuevas y el otro para verificar las que ya estaban guardadas con él.
+func (c HashConfig) Hashers() *Hashers {
+	argon := DefaultArgon2id
+	argon.Memory, argon.Iterations, argon.Parallelism = c.Argon2Memory, c.Argon2Iterations, c.Argon2Parallelism
+	bc := Bcrypt{Cost: c.BcryptCost}
+	if c.Algorithm == AlgBcrypt {
+		return NewHashers(bc, argon)
+	}
+	return NewHashers(argon, bc)
+}
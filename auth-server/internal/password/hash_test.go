@@ -0,0 +1,138 @@
+package password
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// testArgon2id son parámetros bajos para que los tests no tarden.
+var testArgon2id = Argon2id{Memory: 64, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+
+func TestHashersMigrateBcryptToArgon2id(t *testing.T) {
+	bc := Bcrypt{Cost: 4}
+	old, err := bc.Hash("Clave-vieja-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := NewHashers(testArgon2id, bc)
+
+	if ok, rehash, err := h.Verify("otra", old); ok || rehash || err != nil {
+		t.Errorf("Verify con contraseña incorrecta = %v, %v, %v; quería false, false, nil", ok, rehash, err)
+	}
+	ok, rehash, err := h.Verify("Clave-vieja-1", old)
+	if !ok || !rehash || err != nil {
+		t.Fatalf("Verify de bcrypt = %v, %v, %v; quería true, true, nil", ok, rehash, err)
+	}
+
+	updated, err := h.Hash("Clave-vieja-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(updated, "$argon2id$v=19$m=64,t=1,p=1$") {
+		t.Fatalf("hash nuevo = %q, quería argon2id", updated)
+	}
+	if ok, rehash, err := h.Verify("Clave-vieja-1", updated); !ok || rehash || err != nil {
+		t.Errorf("Verify de argon2id = %v, %v, %v; quería true, false, nil", ok, rehash, err)
+	}
+
+	// Volviendo a bcrypt, los hashes de argon2id se siguen aceptando.
+	back := NewHashers(bc, testArgon2id)
+	if ok, rehash, err := back.Verify("Clave-vieja-1", updated); !ok || !rehash || err != nil {
+		t.Errorf("Verify de argon2id con bcrypt actual = %v, %v, %v; quería true, true, nil", ok, rehash, err)
+	}
+}
+
+func TestOutdated(t *testing.T) {
+	hash, err := testArgon2id.Hash("Clave-123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if testArgon2id.Outdated(hash) {
+		t.Error("Outdated con los mismos parámetros = true")
+	}
+	for name, change := range map[string]func(*Argon2id){
+		"memory":      func(a *Argon2id) { a.Memory *= 2 },
+		"iterations":  func(a *Argon2id) { a.Iterations++ },
+		"parallelism": func(a *Argon2id) { a.Parallelism++ },
+		"salt":        func(a *Argon2id) { a.SaltLength = 32 },
+		"key":         func(a *Argon2id) { a.KeyLength = 64 },
+	} {
+		a := testArgon2id
+		change(&a)
+		if !a.Outdated(hash) {
+			t.Errorf("Outdated cambiando %s = false", name)
+		}
+		// El hash viejo se sigue verificando con sus propios parámetros.
+		if ok, err := a.Verify("Clave-123", hash); !ok || err != nil {
+			t.Errorf("Verify cambiando %s = %v, %v", name, ok, err)
+		}
+		h := NewHashers(a)
+		if _, rehash, _ := h.Verify("Clave-123", hash); !rehash {
+			t.Errorf("Hashers.Verify cambiando %s no pide rehash", name)
+		}
+	}
+
+	bcHash, err := Bcrypt{Cost: 4}.Hash("Clave-123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if (Bcrypt{Cost: 4}).Outdated(bcHash) || !(Bcrypt{Cost: 5}).Outdated(bcHash) {
+		t.Error("Bcrypt.Outdated no compara el costo")
+	}
+}
+
+func TestMalformedHashes(t *testing.T) {
+	valid, err := testArgon2id.Hash("Clave-123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts := strings.Split(valid, "$")
+	replace := func(i int, s string) string {
+		p := append([]string(nil), parts...)
+		p[i] = s
+		return strings.Join(p, "$")
+	}
+
+	h := NewHashers(testArgon2id, Bcrypt{Cost: 4})
+	for _, tc := range []struct {
+		name, hash string
+	}{
+		{"vacío", ""},
+		{"texto plano", "Clave-123"},
+		{"otro algoritmo", "$scrypt$ln=15,r=8,p=1$c2Fs$aGFzaA"},
+		{"faltan partes", strings.Join(parts[:5], "$")},
+		{"sobran partes", valid + "$extra"},
+		{"otra versión", replace(2, "v=16")},
+		{"sin versión", replace(2, "x")},
+		{"parámetros inválidos", replace(3, "m=x,t=1,p=1")},
+		{"sal inválida", replace(4, "no*es*base64")},
+		{"hash inválido", replace(5, "no*es*base64")},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ok, rehash, err := h.Verify("Clave-123", tc.hash)
+			if ok || rehash || !errors.Is(err, ErrUnknownHash) {
+				t.Errorf("Verify = %v, %v, %v; quería ErrUnknownHash", ok, rehash, err)
+			}
+			if testArgon2id.Handles(tc.hash) && !testArgon2id.Outdated(tc.hash) {
+				t.Error("Outdated de un hash inválido = false")
+			}
+		})
+	}
+
+	// bcrypt reconoce el prefijo pero el resto está roto.
+	if ok, _, err := h.Verify("Clave-123", "$2a$04$corto"); ok || err == nil {
+		t.Errorf("Verify de bcrypt roto = %v, %v; quería un error", ok, err)
+	}
+}
+
+func TestDefaultPolicyAllowsLongPasswords(t *testing.T) {
+	p := DefaultPolicy()
+	if p.MaxLength <= BcryptMaxLength {
+		t.Fatalf("DefaultPolicy().MaxLength = %d, quería más que %d (argon2id usa la contraseña entera)", p.MaxLength, BcryptMaxLength)
+	}
+	long := "Clave-1" + strings.Repeat("x", 100)
+	if v := p.Check("", long); len(v) != 0 {
+		t.Errorf("contraseña de %d bytes: %v", len(long), v)
+	}
+}
@@ -0,0 +1,129 @@
+// Package password valida las contraseñas nuevas: la política (largo, tipos
+// de caracteres, no usar el email) y la lista de contraseñas filtradas.
+package password
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// BcryptMaxLength es el máximo de bytes que bcrypt tiene en cuenta; lo que
+// sigue se ignora, así que una contraseña más larga no es más segura.
+const BcryptMaxLength = 72
+
+// DefaultMaxLength es el máximo por defecto con argon2id, que usa la
+// contraseña entera; el límite solo evita hashear textos enormes.
+const DefaultMaxLength = 128
+
+// Reglas que puede incumplir una contraseña (Violation.Rule).
+const (
+	RuleMinLength = "min_length"
+	RuleMaxLength = "max_length"
+	RuleUpper     = "upper"
+	RuleLower     = "lower"
+	RuleDigit     = "digit"
+	RuleSymbol    = "symbol"
+	RuleEmail     = "not_email"
+	RuleBreached  = "not_breached"
+)
+
+type Policy struct {
+	MinLength     int  `yaml:"min_length" env:"PASSWORD_MIN_LENGTH"` // en caracteres
+	MaxLength     int  `yaml:"max_length" env:"PASSWORD_MAX_LENGTH"` // en bytes; con bcrypt, como mucho BcryptMaxLength
+	RequireUpper  bool `yaml:"require_upper" env:"PASSWORD_REQUIRE_UPPER"`
+	RequireLower  bool `yaml:"require_lower" env:"PASSWORD_REQUIRE_LOWER"`
+	RequireDigit  bool `yaml:"require_digit" env:"PASSWORD_REQUIRE_DIGIT"`
+	RequireSymbol bool `yaml:"require_symbol" env:"PASSWORD_REQUIRE_SYMBOL"`
+	// DisallowEmail rechaza el email (o la parte antes de la @) como contraseña.
+	DisallowEmail bool `yaml:"disallow_email" env:"PASSWORD_DISALLOW_EMAIL"`
+}
+
+func DefaultPolicy() Policy {
+	return Policy{
+		MinLength:     10,
+		MaxLength:     DefaultMaxLength,
+		RequireUpper:  true,
+		RequireLower:  true,
+		RequireDigit:  true,
+		DisallowEmail: true,
+	}
+}
+
+func (p Policy) Validate() error {
+	var errs []error
+	if p.MinLength < 1 {
+		errs = append(errs, errors.New("password.min_length debe ser al menos 1"))
+	}
+	if p.MaxLength < p.MinLength {
+		errs = append(errs, errors.New("password.max_length debe ser mayor o igual que password.min_length"))
+	}
+	return errors.Join(errs...)
+}
+
+// Violation es una regla de la política que la contraseña no cumple.
+type Violation struct {
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// ValidationError lista todas las reglas incumplidas, para informarlas de una vez.
+type ValidationError struct {
+	Violations []Violation
+}
+
+func (e *ValidationError) Error() string {
+	rules := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		rules[i] = v.Rule
+	}
+	return "password policy violated: " + strings.Join(rules, ", ")
+}
+
+// Check devuelve las reglas que incumple password (nil si cumple todas).
+func (p Policy) Check(email, password string) []Violation {
+	var res []Violation
+	add := func(rule, msg string) { res = append(res, Violation{Rule: rule, Message: msg}) }
+
+	if n := len([]rune(password)); n < p.MinLength {
+		add(RuleMinLength, fmt.Sprintf("Debe tener al menos %d caracteres.", p.MinLength))
+	}
+	if len(password) > p.MaxLength {
+		add(RuleMaxLength, fmt.Sprintf("Debe tener como mucho %d bytes.", p.MaxLength))
+	}
+
+	var upper, lower, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r) || unicode.IsSpace(r):
+			symbol = true
+		}
+	}
+	if p.RequireUpper && !upper {
+		add(RuleUpper, "Debe tener al menos una mayúscula.")
+	}
+	if p.RequireLower && !lower {
+		add(RuleLower, "Debe tener al menos una minúscula.")
+	}
+	if p.RequireDigit && !digit {
+		add(RuleDigit, "Debe tener al menos un número.")
+	}
+	if p.RequireSymbol && !symbol {
+		add(RuleSymbol, "Debe tener al menos un símbolo.")
+	}
+
+	if p.DisallowEmail && email != "" {
+		local, _, _ := strings.Cut(email, "@")
+		if strings.EqualFold(password, email) || strings.EqualFold(password, local) {
+			add(RuleEmail, "No puede ser el email.")
+		}
+	}
+	return res
+}
@@ -0,0 +1,83 @@
+package password
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func rules(vs []Violation) []string {
+	res := make([]string, len(vs))
+	for i, v := range vs {
+		res[i] = v.Rule
+	}
+	return res
+}
+
+func TestPolicyCheck(t *testing.T) {
+	strict := Policy{
+		MinLength: 8, MaxLength: 20,
+		RequireUpper: true, RequireLower: true, RequireDigit: true, RequireSymbol: true,
+		DisallowEmail: true,
+	}
+	lax := Policy{MinLength: 4, MaxLength: 64}
+
+	tests := []struct {
+		name     string
+		policy   Policy
+		email    string
+		password string
+		want     []string
+	}{
+		{"cumple todo", strict, "ana@example.com", "Clave-123", nil},
+		{"corta", strict, "", "Cl-1a", []string{RuleMinLength}},
+		{"larga", strict, "", "Clave-123" + strings.Repeat("x", 12), []string{RuleMaxLength}},
+		{"sin mayúscula", strict, "", "clave-123", []string{RuleUpper}},
+		{"sin minúscula", strict, "", "CLAVE-123", []string{RuleLower}},
+		{"sin número", strict, "", "Clave-abc", []string{RuleDigit}},
+		{"sin símbolo", strict, "", "Clave1234", []string{RuleSymbol}},
+		{"el espacio cuenta como símbolo", strict, "", "Clave 123", nil},
+		{"varias a la vez", strict, "", "abc", []string{RuleMinLength, RuleUpper, RuleDigit, RuleSymbol}},
+		{"vacía", lax, "", "", []string{RuleMinLength}},
+
+		// MinLength cuenta caracteres y MaxLength bytes.
+		{"multibyte alcanza el mínimo", Policy{MinLength: 4, MaxLength: 64}, "", "ñáéí", nil},
+		{"multibyte bajo el mínimo", Policy{MinLength: 5, MaxLength: 64}, "", "ñáéí", []string{RuleMinLength}},
+		{"multibyte pasa el máximo en bytes", Policy{MinLength: 1, MaxLength: 6}, "", "ñáéí", []string{RuleMaxLength}},
+		{"emoji es un carácter", Policy{MinLength: 2, MaxLength: 64}, "", "🔑", []string{RuleMinLength}},
+
+		{"el email", strict, "ana.perez1!@ex.com", "Ana.Perez1!@EX.com", []string{RuleEmail}},
+		{"la parte local del email", strict, "Ana.Perez1!@example.com", "ana.PEREZ1!", []string{RuleEmail}},
+		{"contiene el email pero no es igual", strict, "ana@example.com", "Ana-12345", nil},
+		{"sin DisallowEmail", lax, "ana@example.com", "ana", []string{RuleMinLength}},
+		{"sin DisallowEmail la parte local vale", lax, "anita@example.com", "anita", nil},
+		{"sin email no se compara", Policy{MinLength: 1, MaxLength: 64, DisallowEmail: true}, "", "", []string{RuleMinLength}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := rules(tt.policy.Check(tt.email, tt.password))
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Check(%q, %q) = %v, quería %v", tt.email, tt.password, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPolicyValidate(t *testing.T) {
+	if err := DefaultPolicy().Validate(); err != nil {
+		t.Errorf("DefaultPolicy: %v", err)
+	}
+	if err := (Policy{MinLength: 0, MaxLength: 10}).Validate(); err == nil {
+		t.Error("min_length 0 aceptado")
+	}
+	if err := (Policy{MinLength: 10, MaxLength: 5}).Validate(); err == nil {
+		t.Error("max_length menor que min_length aceptado")
+	}
+}
+
+func TestValidationError(t *testing.T) {
+	err := &ValidationError{Violations: Policy{MinLength: 8, MaxLength: 64, RequireDigit: true}.Check("", "abc")}
+	if got, want := err.Error(), "password policy violated: min_length, digit"; got != want {
+		t.Errorf("Error() = %q, quería %q", got, want)
+	}
+}
@@ -0,0 +1,93 @@
+package bolt
+
+import (
+	"context"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+
+	"proyecto/auth-server/internal/repository"
+	"proyecto/auth-server/pkg/model"
+	"proyecto/pkg/storage/boltdb"
+)
+
+// Los logins fallidos van por email; las entradas vencidas se borran al escribir.
+
+func (r *Repository) GetLoginAttempts(_ context.Context, email string) (*model.LoginAttempts, error) {
+	var a model.LoginAttempts
+	err := r.db.View(func(tx *bolt.Tx) error {
+		found, err := boltdb.GetJSON(tx.Bucket([]byte(loginAttemptsBucket)), email, &a)
+		if err != nil {
+			return err
+		}
+		if !found || !time.Now().Before(a.ExpiresAt) {
+			return repository.ErrNotFound
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+// RecordLoginFailure suma un fallo dentro de una transacción de escritura,
+// para que intentos simultáneos no se pisen la cuenta. Si el último fallo es
+// más viejo que window se empieza a contar de nuevo.
+func (r *Repository) RecordLoginFailure(_ context.Context, email string, at time.Time, window time.Duration) (*model.LoginAttempts, error) {
+	var a model.LoginAttempts
+	err := r.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(loginAttemptsBucket))
+		if err := purgeExpired(b, at, func(a *model.LoginAttempts) time.Time { return a.ExpiresAt }); err != nil {
+			return err
+		}
+		found, err := boltdb.GetJSON(b, email, &a)
+		if err != nil {
+			return err
+		}
+		if !found {
+			a = model.LoginAttempts{Email: email}
+		} else if at.Sub(a.LastFailure) > window {
+			a.Failures = 0
+		}
+		a.Failures++
+		a.LastFailure = at
+		a.ExpiresAt = later(at.Add(window), a.LockedUntil)
+		return boltdb.PutJSON(b, email, &a)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+// LockLogin no acepta intentos de email hasta until.
+func (r *Repository) LockLogin(_ context.Context, email string, until time.Time) error {
+	return r.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(loginAttemptsBucket))
+		var a model.LoginAttempts
+		found, err := boltdb.GetJSON(b, email, &a)
+		if err != nil {
+			return err
+		}
+		if !found {
+			return repository.ErrNotFound
+		}
+		a.LockedUntil = until
+		a.ExpiresAt = later(a.ExpiresAt, until)
+		return boltdb.PutJSON(b, email, &a)
+	})
+}
+
+func (r *Repository) ResetLoginAttempts(_ context.Context, email string) error {
+	return r.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(loginAttemptsBucket)).Delete([]byte(email))
+	})
+}
+
+func later(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,128 @@
+package bolt
+
+import (
+	"context"
+	"encoding/json"
+
+	bolt "go.etcd.io/bbolt"
+
+	"proyecto/auth-server/internal/repository"
+	"proyecto/auth-server/pkg/model"
+	"proyecto/pkg/storage/boltdb"
+)
+
+// Los refresh tokens van indexados por su hash; revocar una familia o un
+// email recorre el bucket, que solo tiene los tokens de las sesiones vivas.
+
+func (r *Repository) GetRefreshToken(_ context.Context, tokenHash string) (*model.RefreshToken, error) {
+	var token model.RefreshToken
+	err := r.db.View(func(tx *bolt.Tx) error {
+		found, err := boltdb.GetJSON(tx.Bucket([]byte(refreshTokensBucket)), tokenHash, &token)
+		if err != nil {
+			return err
+		}
+		if !found {
+			return repository.ErrNotFound
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// PutRefreshToken guarda el token y de paso borra los vencidos.
+func (r *Repository) PutRefreshToken(_ context.Context, token *model.RefreshToken) error {
+	return r.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(refreshTokensBucket))
+		err := updateEach(b, func(t *model.RefreshToken) (bool, bool) {
+			return false, !token.CreatedAt.Before(t.ExpiresAt)
+		})
+		if err != nil {
+			return err
+		}
+		return boltdb.PutJSON(b, token.TokenHash, token)
+	})
+}
+
+// MarkRefreshTokenUsed marca el token como usado dentro de una transacción de
+// escritura (bolt las serializa): de dos canjes simultáneos solo gana uno.
+func (r *Repository) MarkRefreshTokenUsed(_ context.Context, tokenHash string) error {
+	return r.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(refreshTokensBucket))
+		var token model.RefreshToken
+		found, err := boltdb.GetJSON(b, tokenHash, &token)
+		if err != nil {
+			return err
+		}
+		if !found {
+			return repository.ErrNotFound
+		}
+		if token.Used {
+			return repository.ErrAlreadyUsed
+		}
+		token.Used = true
+		return boltdb.PutJSON(b, tokenHash, &token)
+	})
+}
+
+func (r *Repository) RevokeFamily(_ context.Context, familyID string) error {
+	return r.revokeWhere(func(t *model.RefreshToken) bool { return t.FamilyID == familyID })
+}
+
+func (r *Repository) RevokeByEmail(_ context.Context, email string) error {
+	return r.revokeWhere(func(t *model.RefreshToken) bool { return t.Email == email })
+}
+
+func (r *Repository) revokeWhere(match func(*model.RefreshToken) bool) error {
+	return r.db.Update(func(tx *bolt.Tx) error {
+		return updateEach(tx.Bucket([]byte(refreshTokensBucket)), func(t *model.RefreshToken) (bool, bool) {
+			if !match(t) || t.Revoked {
+				return false, false
+			}
+			t.Revoked = true
+			return true, false
+		})
+	})
+}
+
+// updateEach recorre los valores JSON de b; fn devuelve si hay que guardar
+// el valor modificado o borrarlo. Los cambios se aplican al terminar el
+// recorrido, porque bolt no permite modificar el bucket mientras se itera.
+func updateEach[T any](b *bolt.Bucket, fn func(*T) (put, del bool)) error {
+	type change struct {
+		key []byte
+		val *T
+	}
+	var puts []change
+	var dels [][]byte
+	err := b.ForEach(func(k, v []byte) error {
+		var val T
+		if err := json.Unmarshal(v, &val); err != nil {
+			return err
+		}
+		put, del := fn(&val)
+		switch {
+		case del:
+			dels = append(dels, append([]byte(nil), k...))
+		case put:
+			puts = append(puts, change{append([]byte(nil), k...), &val})
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, k := range dels {
+		if err := b.Delete(k); err != nil {
+			return err
+		}
+	}
+	for _, c := range puts {
+		if err := boltdb.PutJSON(b, string(c.key), c.val); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,180 @@
+package bolt
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+
+	"proyecto/auth-server/internal/repository"
+	"proyecto/auth-server/pkg/model"
+	"proyecto/pkg/idempotency"
+	"proyecto/pkg/storage/boltdb"
+)
+
+const (
+	usersBucket         = "auth_users"
+	sagasBucket         = "registration_sagas"
+	refreshTokensBucket = "refresh_tokens"
+	issuedTokensBucket  = "issued_tokens"
+	revokedTokensBucket = "revoked_tokens"
+	loginAttemptsBucket = "login_attempts"
+	idempotencyBucket   = "idempotency_keys"
+)
+
+var migrations = []boltdb.Migration{
+	{Version: 1, Name: "create auth_users", Up: boltdb.CreateBuckets(usersBucket)},
+	{Version: 2, Name: "create registration_sagas", Up: boltdb.CreateBuckets(sagasBucket)},
+	{Version: 3, Name: "create sessions and login attempts", Up: boltdb.CreateBuckets(
+		refreshTokensBucket, issuedTokensBucket, revokedTokensBucket, loginAttemptsBucket)},
+	{Version: 4, Name: "create idempotency_keys", Up: boltdb.CreateBuckets(idempotencyBucket)},
+}
+
+// Repository guarda los usuarios de auth-server, el estado de las sagas de
+// registro, las sesiones (refresh tokens, tokens emitidos y revocados) y los
+// logins fallidos en un archivo BoltDB.
+type Repository struct {
+	db *bolt.DB
+}
+
+// Open abre la base en path y aplica las migraciones pendientes.
+func Open(path string) (*Repository, error) {
+	db, err := boltdb.Open(path, migrations)
+	if err != nil {
+		return nil, err
+	}
+	return &Repository{db: db}, nil
+}
+
+func (r *Repository) Close() error {
+	return r.db.Close()
+}
+
+// IdempotencyStore guarda las claves de idempotencia en la misma base.
+func (r *Repository) IdempotencyStore() *idempotency.BoltStore {
+	return idempotency.NewBoltStore(r.db, idempotencyBucket)
+}
+
+// Ping comprueba que la base sigue abierta (check de salud).
+func (r *Repository) Ping(_ context.Context) error {
+	return r.db.View(func(*bolt.Tx) error { return nil })
+}
+
+func (r *Repository) GetHashByEmail(_ context.Context, email string) (*model.AuthUser, error) {
+	var user model.AuthUser
+	err := r.db.View(func(tx *bolt.Tx) error {
+		found, err := boltdb.GetJSON(tx.Bucket([]byte(usersBucket)), email, &user)
+		if err != nil {
+			return err
+		}
+		if !found {
+			return repository.ErrNotFound
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (r *Repository) Put(_ context.Context, user *model.AuthUser) error {
+	return r.db.Update(func(tx *bolt.Tx) error {
+		return boltdb.PutJSON(tx.Bucket([]byte(usersBucket)), user.Email, user)
+	})
+}
+
+func (r *Repository) UpdatePasswordHash(_ context.Context, email, hash string) error {
+	return r.updateUser(email, func(u *model.AuthUser) { u.PasswordHash = hash })
+}
+
+func (r *Repository) SetRole(_ context.Context, email, role string) error {
+	return r.updateUser(email, func(u *model.AuthUser) { u.Role = role })
+}
+
+func (r *Repository) MarkVerified(_ context.Context, email string) error {
+	return r.updateUser(email, func(u *model.AuthUser) { u.EmailVerified = true })
+}
+
+func (r *Repository) SetVerificationSentAt(_ context.Context, email string, at time.Time) error {
+	return r.updateUser(email, func(u *model.AuthUser) { u.VerificationSentAt = at })
+}
+
+// updateUser lee, cambia y guarda el usuario en una sola transacción.
+func (r *Repository) updateUser(email string, change func(*model.AuthUser)) error {
+	return r.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(usersBucket))
+		var user model.AuthUser
+		found, err := boltdb.GetJSON(b, email, &user)
+		if err != nil {
+			return err
+		}
+		if !found {
+			return repository.ErrNotFound
+		}
+		change(&user)
+		return boltdb.PutJSON(b, email, &user)
+	})
+}
+
+func (r *Repository) Create(_ context.Context, user *model.AuthUser) error {
+	return r.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(usersBucket))
+		if b.Get([]byte(user.Email)) != nil {
+			return repository.ErrAlreadyExists
+		}
+		return boltdb.PutJSON(b, user.Email, user)
+	})
+}
+
+func (r *Repository) Delete(_ context.Context, email string) error {
+	return r.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(usersBucket))
+		if b.Get([]byte(email)) == nil {
+			return repository.ErrNotFound
+		}
+		return b.Delete([]byte(email))
+	})
+}
+
+func (r *Repository) PutSaga(_ context.Context, saga *model.RegistrationSaga) error {
+	return r.db.Update(func(tx *bolt.Tx) error {
+		return boltdb.PutJSON(tx.Bucket([]byte(sagasBucket)), saga.ID, saga)
+	})
+}
+
+func (r *Repository) GetSaga(_ context.Context, id string) (*model.RegistrationSaga, error) {
+	var saga model.RegistrationSaga
+	err := r.db.View(func(tx *bolt.Tx) error {
+		found, err := boltdb.GetJSON(tx.Bucket([]byte(sagasBucket)), id, &saga)
+		if err != nil {
+			return err
+		}
+		if !found {
+			return repository.ErrNotFound
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &saga, nil
+}
+
+func (r *Repository) ListPendingSagas(_ context.Context) ([]*model.RegistrationSaga, error) {
+	var res []*model.RegistrationSaga
+	err := r.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(sagasBucket)).ForEach(func(_, v []byte) error {
+			var saga model.RegistrationSaga
+			if err := json.Unmarshal(v, &saga); err != nil {
+				return err
+			}
+			if saga.State.Pending() {
+				res = append(res, &saga)
+			}
+			return nil
+		})
+	})
+	return res, err
+}
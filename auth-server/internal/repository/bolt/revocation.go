@@ -0,0 +1,89 @@
+package bolt
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+
+	"proyecto/auth-server/pkg/model"
+	"proyecto/pkg/storage/boltdb"
+)
+
+// Los access tokens emitidos y los revocados van por jti; las entradas
+// vencidas se borran al escribir, ya que un token vencido se rechaza igual.
+
+func (r *Repository) TrackIssued(_ context.Context, token *model.IssuedToken) error {
+	return r.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(issuedTokensBucket))
+		if err := purgeExpired(b, time.Now(), func(t *model.IssuedToken) time.Time { return t.ExpiresAt }); err != nil {
+			return err
+		}
+		return boltdb.PutJSON(b, token.JTI, token)
+	})
+}
+
+func (r *Repository) ListIssuedByEmail(_ context.Context, email string) ([]*model.IssuedToken, error) {
+	now := time.Now()
+	var res []*model.IssuedToken
+	err := r.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(issuedTokensBucket)).ForEach(func(_, v []byte) error {
+			var t model.IssuedToken
+			if err := json.Unmarshal(v, &t); err != nil {
+				return err
+			}
+			if t.Email == email && now.Before(t.ExpiresAt) {
+				res = append(res, &t)
+			}
+			return nil
+		})
+	})
+	return res, err
+}
+
+func (r *Repository) Revoke(_ context.Context, token *model.RevokedToken) error {
+	return r.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(revokedTokensBucket))
+		if err := purgeExpired(b, time.Now(), func(t *model.RevokedToken) time.Time { return t.ExpiresAt }); err != nil {
+			return err
+		}
+		return boltdb.PutJSON(b, token.JTI, token)
+	})
+}
+
+func (r *Repository) IsRevoked(_ context.Context, jti string) (bool, error) {
+	var t model.RevokedToken
+	var found bool
+	err := r.db.View(func(tx *bolt.Tx) error {
+		var err error
+		found, err = boltdb.GetJSON(tx.Bucket([]byte(revokedTokensBucket)), jti, &t)
+		return err
+	})
+	return found && time.Now().Before(t.ExpiresAt), err
+}
+
+func (r *Repository) ListRevoked(_ context.Context) ([]*model.RevokedToken, error) {
+	now := time.Now()
+	res := []*model.RevokedToken{}
+	err := r.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(revokedTokensBucket)).ForEach(func(_, v []byte) error {
+			var t model.RevokedToken
+			if err := json.Unmarshal(v, &t); err != nil {
+				return err
+			}
+			if now.Before(t.ExpiresAt) {
+				res = append(res, &t)
+			}
+			return nil
+		})
+	})
+	return res, err
+}
+
+// purgeExpired borra de b las entradas cuya fecha de vencimiento ya pasó.
+func purgeExpired[T any](b *bolt.Bucket, now time.Time, expiresAt func(*T) time.Time) error {
+	return updateEach(b, func(v *T) (bool, bool) {
+		return false, !now.Before(expiresAt(v))
+	})
+}
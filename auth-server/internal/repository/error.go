@@ -2,4 +2,10 @@ package repository
 
 import "errors"
 
-var ErrNotFound = errors.New("Not found")
\ No newline at end of file
+var ErrNotFound = errors.New("Not found")
+
+// ErrAlreadyUsed se devuelve cuando se intenta canjear un refresh token que ya fue usado.
+var ErrAlreadyUsed = errors.New("Already used")
+
+// ErrAlreadyExists se devuelve al crear un usuario con un email ya registrado.
+var ErrAlreadyExists = errors.New("Already exists")
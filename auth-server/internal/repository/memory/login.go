@@ -0,0 +1,92 @@
+package memory
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"proyecto/auth-server/internal/repository"
+	"proyecto/auth-server/pkg/model"
+)
+
+// LoginAttemptRepository guarda en memoria los logins fallidos por email.
+// Las entradas vencidas se limpian al escribir.
+type LoginAttemptRepository struct {
+	sync.RWMutex
+	data map[string]*model.LoginAttempts
+}
+
+func NewLoginAttemptRepository() *LoginAttemptRepository {
+	return &LoginAttemptRepository{data: map[string]*model.LoginAttempts{}}
+}
+
+func (r *LoginAttemptRepository) GetLoginAttempts(_ context.Context, email string) (*model.LoginAttempts, error) {
+	r.RLock()
+	defer r.RUnlock()
+
+	a, ok := r.data[email]
+	if !ok || !time.Now().Before(a.ExpiresAt) {
+		return nil, repository.ErrNotFound
+	}
+	cp := *a
+	return &cp, nil
+}
+
+// RecordLoginFailure suma un fallo de forma atómica, para que intentos
+// simultáneos no se pisen la cuenta. Si el último fallo es más viejo que
+// window se empieza a contar de nuevo.
+func (r *LoginAttemptRepository) RecordLoginFailure(_ context.Context, email string, at time.Time, window time.Duration) (*model.LoginAttempts, error) {
+	r.Lock()
+	defer r.Unlock()
+	r.purgeExpired(at)
+
+	a, ok := r.data[email]
+	if !ok {
+		a = &model.LoginAttempts{Email: email}
+		r.data[email] = a
+	} else if at.Sub(a.LastFailure) > window {
+		a.Failures = 0
+	}
+	a.Failures++
+	a.LastFailure = at
+	a.ExpiresAt = later(at.Add(window), a.LockedUntil)
+	cp := *a
+	return &cp, nil
+}
+
+// LockLogin no acepta intentos de email hasta until.
+func (r *LoginAttemptRepository) LockLogin(_ context.Context, email string, until time.Time) error {
+	r.Lock()
+	defer r.Unlock()
+
+	a, ok := r.data[email]
+	if !ok {
+		return repository.ErrNotFound
+	}
+	a.LockedUntil = until
+	a.ExpiresAt = later(a.ExpiresAt, until)
+	return nil
+}
+
+func (r *LoginAttemptRepository) ResetLoginAttempts(_ context.Context, email string) error {
+	r.Lock()
+	defer r.Unlock()
+	delete(r.data, email)
+	return nil
+}
+
+// purgeExpired debe llamarse con el lock de escritura tomado.
+func (r *LoginAttemptRepository) purgeExpired(now time.Time) {
+	for email, a := range r.data {
+		if !now.Before(a.ExpiresAt) {
+			delete(r.data, email)
+		}
+	}
+}
+
+func later(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,78 @@
+package memory
+
+import (
+	"context"
+	"sync"
+
+	"proyecto/auth-server/internal/repository"
+	"proyecto/auth-server/pkg/model"
+)
+
+// RefreshTokenRepository guarda los refresh tokens en memoria, indexados por su hash.
+type RefreshTokenRepository struct {
+	sync.RWMutex
+	data map[string]*model.RefreshToken
+}
+
+func NewRefreshTokenRepository() *RefreshTokenRepository {
+	return &RefreshTokenRepository{data: map[string]*model.RefreshToken{}}
+}
+
+func (r *RefreshTokenRepository) GetRefreshToken(_ context.Context, tokenHash string) (*model.RefreshToken, error) {
+	r.RLock()
+	defer r.RUnlock()
+
+	t, ok := r.data[tokenHash]
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+	cp := *t
+	return &cp, nil
+}
+
+func (r *RefreshTokenRepository) PutRefreshToken(_ context.Context, token *model.RefreshToken) error {
+	r.Lock()
+	defer r.Unlock()
+	cp := *token
+	r.data[token.TokenHash] = &cp
+	return nil
+}
+
+// MarkRefreshTokenUsed marca el token como usado de forma atómica: si ya estaba
+// usado devuelve repository.ErrAlreadyUsed, así dos canjes simultáneos no pueden ganar ambos.
+func (r *RefreshTokenRepository) MarkRefreshTokenUsed(_ context.Context, tokenHash string) error {
+	r.Lock()
+	defer r.Unlock()
+
+	t, ok := r.data[tokenHash]
+	if !ok {
+		return repository.ErrNotFound
+	}
+	if t.Used {
+		return repository.ErrAlreadyUsed
+	}
+	t.Used = true
+	return nil
+}
+
+func (r *RefreshTokenRepository) RevokeFamily(_ context.Context, familyID string) error {
+	r.Lock()
+	defer r.Unlock()
+	for _, t := range r.data {
+		if t.FamilyID == familyID {
+			t.Revoked = true
+		}
+	}
+	return nil
+}
+
+func (r *RefreshTokenRepository) RevokeByEmail(_ context.Context, email string) error {
+	r.Lock()
+	defer r.Unlock()
+	for _, t := range r.data {
+		if t.Email == email {
+			t.Revoked = true
+		}
+	}
+	return nil
+}
@@ -4,13 +4,14 @@ import (
 	
 	"sync"
 	"time"
-	"errors"
 	"context"
 
+    "proyecto/auth-server/internal/repository"
     "proyecto/auth-server/pkg/model"
 )
 
-var ErrNotFound = errors.New("auth user not found")
+// Mismo error que el resto de repositorios, para que el controlador pueda distinguirlo.
+var ErrNotFound = repository.ErrNotFound
 
 
 type Repository struct {
@@ -25,10 +26,13 @@ func New() *Repository {
 		data: map[string]*model.AuthUser{
 			"oscar@example.com": {
 				Email:        "oscar@example.com",
-				PasswordHash: "$2a$10$abcdefghijklmnopqrstuv", // hash de prueba
-				Provider:     "local",
-				Role:         "user",
-				CreatedAt:    time.Now(),
+				// Usuario de prueba, contraseña "SuperSecreta123!" (la del Makefile). Está
+				// en bcrypt a propósito: en el primer login se pasa a Argon2id.
+				PasswordHash:  "$2a$10$/dHITW7sc9q.G3TUBTri5u0D26VNsepNMBsH.8blkyHKzF.I4ISXS",
+				Provider:      "local",
+				Role:          "user",
+				EmailVerified: true,
+				CreatedAt:     time.Now(),
 			},
 		},
 	}
@@ -42,12 +46,67 @@ func (r* Repository) GetHashByEmail(ctx context.Context, email string) (*model.A
     if !ok {
         return nil, ErrNotFound
     }
-    return user, nil
+    // Una copia: quien la modifique no toca la guardada sin el lock.
+    cp := *user
+    return &cp, nil
 }
 
 func (r *Repository) Put(_ context.Context, AuthUser *model.AuthUser) error {
 	r.Lock()
 	defer r.Unlock()
-	r.data[AuthUser.Email] = AuthUser
+	cp := *AuthUser
+	r.data[AuthUser.Email] = &cp
 	return nil
-}
\ No newline at end of file
+}
+
+// Create guarda un usuario nuevo; falla con repository.ErrAlreadyExists si el email ya existe.
+func (r *Repository) Create(_ context.Context, user *model.AuthUser) error {
+	r.Lock()
+	defer r.Unlock()
+	if _, ok := r.data[user.Email]; ok {
+		return repository.ErrAlreadyExists
+	}
+	cp := *user
+	r.data[user.Email] = &cp
+	return nil
+}
+
+func (r *Repository) UpdatePasswordHash(_ context.Context, email, hash string) error {
+	return r.updateUser(email, func(u *model.AuthUser) { u.PasswordHash = hash })
+}
+
+func (r *Repository) SetRole(_ context.Context, email, role string) error {
+	return r.updateUser(email, func(u *model.AuthUser) { u.Role = role })
+}
+
+func (r *Repository) MarkVerified(_ context.Context, email string) error {
+	return r.updateUser(email, func(u *model.AuthUser) { u.EmailVerified = true })
+}
+
+func (r *Repository) SetVerificationSentAt(_ context.Context, email string, at time.Time) error {
+	return r.updateUser(email, func(u *model.AuthUser) { u.VerificationSentAt = at })
+}
+
+// updateUser reemplaza el usuario por una copia con el cambio.
+func (r *Repository) updateUser(email string, change func(*model.AuthUser)) error {
+	r.Lock()
+	defer r.Unlock()
+	user, ok := r.data[email]
+	if !ok {
+		return ErrNotFound
+	}
+	updated := *user
+	change(&updated)
+	r.data[email] = &updated
+	return nil
+}
+
+func (r *Repository) Delete(_ context.Context, email string) error {
+	r.Lock()
+	defer r.Unlock()
+	if _, ok := r.data[email]; !ok {
+		return repository.ErrNotFound
+	}
+	delete(r.data, email)
+	return nil
+}
@@ -0,0 +1,35 @@
+package memory
+
+import (
+	"context"
+	"testing"
+
+	"proyecto/auth-server/pkg/model"
+)
+
+func TestUsersAreCopied(t *testing.T) {
+	r := New()
+	ctx := context.Background()
+	user := &model.AuthUser{Email: "ana@example.com", PasswordHash: "h1", Role: "user"}
+	if err := r.Create(ctx, user); err != nil {
+		t.Fatal(err)
+	}
+	user.Role = "admin" // después de guardarlo
+
+	got, err := r.GetHashByEmail(ctx, "ana@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got.PasswordHash = "h2" // quien lee lo modifica sin el lock
+
+	again, err := r.GetHashByEmail(ctx, "ana@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.Role != "user" || again.PasswordHash != "h1" {
+		t.Errorf("el usuario guardado cambió desde afuera: %+v", again)
+	}
+	if again == got {
+		t.Error("GetHashByEmail devolvió el mismo puntero dos veces")
+	}
+}
@@ -0,0 +1,93 @@
+package memory
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"proyecto/auth-server/pkg/model"
+)
+
+// RevocationRepository guarda en memoria los access tokens emitidos y los jti revocados.
+// Las entradas expiradas se limpian al escribir, ya que un token vencido se rechaza igual.
+type RevocationRepository struct {
+	sync.RWMutex
+	issued  map[string]*model.IssuedToken
+	revoked map[string]*model.RevokedToken
+}
+
+func NewRevocationRepository() *RevocationRepository {
+	return &RevocationRepository{
+		issued:  map[string]*model.IssuedToken{},
+		revoked: map[string]*model.RevokedToken{},
+	}
+}
+
+func (r *RevocationRepository) TrackIssued(_ context.Context, token *model.IssuedToken) error {
+	r.Lock()
+	defer r.Unlock()
+	r.purgeExpired(time.Now())
+	cp := *token
+	r.issued[token.JTI] = &cp
+	return nil
+}
+
+func (r *RevocationRepository) ListIssuedByEmail(_ context.Context, email string) ([]*model.IssuedToken, error) {
+	r.RLock()
+	defer r.RUnlock()
+
+	now := time.Now()
+	var res []*model.IssuedToken
+	for _, t := range r.issued {
+		if t.Email == email && now.Before(t.ExpiresAt) {
+			cp := *t
+			res = append(res, &cp)
+		}
+	}
+	return res, nil
+}
+
+func (r *RevocationRepository) Revoke(_ context.Context, token *model.RevokedToken) error {
+	r.Lock()
+	defer r.Unlock()
+	r.purgeExpired(time.Now())
+	cp := *token
+	r.revoked[token.JTI] = &cp
+	return nil
+}
+
+func (r *RevocationRepository) IsRevoked(_ context.Context, jti string) (bool, error) {
+	r.RLock()
+	defer r.RUnlock()
+	t, ok := r.revoked[jti]
+	return ok && time.Now().Before(t.ExpiresAt), nil
+}
+
+func (r *RevocationRepository) ListRevoked(_ context.Context) ([]*model.RevokedToken, error) {
+	r.RLock()
+	defer r.RUnlock()
+
+	now := time.Now()
+	res := []*model.RevokedToken{}
+	for _, t := range r.revoked {
+		if now.Before(t.ExpiresAt) {
+			cp := *t
+			res = append(res, &cp)
+		}
+	}
+	return res, nil
+}
+
+// purgeExpired debe llamarse con el lock de escritura tomado.
+func (r *RevocationRepository) purgeExpired(now time.Time) {
+	for jti, t := range r.issued {
+		if !now.Before(t.ExpiresAt) {
+			delete(r.issued, jti)
+		}
+	}
+	for jti, t := range r.revoked {
+		if !now.Before(t.ExpiresAt) {
+			delete(r.revoked, jti)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package memory
+
+import (
+	"context"
+	"sync"
+
+	"proyecto/auth-server/internal/repository"
+	"proyecto/auth-server/pkg/model"
+)
+
+// SagaRepository guarda en memoria el estado de las sagas de registro.
+// Solo sirve para desarrollo: tras un reinicio no hay nada que retomar.
+type SagaRepository struct {
+	sync.RWMutex
+	data map[string]*model.RegistrationSaga
+}
+
+func NewSagaRepository() *SagaRepository {
+	return &SagaRepository{data: map[string]*model.RegistrationSaga{}}
+}
+
+func (r *SagaRepository) PutSaga(_ context.Context, saga *model.RegistrationSaga) error {
+	r.Lock()
+	defer r.Unlock()
+	cp := *saga
+	r.data[saga.ID] = &cp
+	return nil
+}
+
+func (r *SagaRepository) GetSaga(_ context.Context, id string) (*model.RegistrationSaga, error) {
+	r.RLock()
+	defer r.RUnlock()
+	s, ok := r.data[id]
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+	cp := *s
+	return &cp, nil
+}
+
+func (r *SagaRepository) ListPendingSagas(_ context.Context) ([]*model.RegistrationSaga, error) {
+	r.RLock()
+	defer r.RUnlock()
+	var res []*model.RegistrationSaga
+	for _, s := range r.data {
+		if s.State.Pending() {
+			cp := *s
+			res = append(res, &cp)
+		}
+	}
+	return res, nil
+}
@@ -0,0 +1,81 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"proyecto/auth-server/internal/repository"
+	"proyecto/auth-server/pkg/model"
+)
+
+const loginAttemptsSelect = `SELECT email, failures, last_failure, locked_until, expires_at FROM login_attempts`
+
+func (r *Repository) GetLoginAttempts(ctx context.Context, email string) (*model.LoginAttempts, error) {
+	a, err := scanLoginAttempts(r.db.QueryRowContext(ctx,
+		loginAttemptsSelect+` WHERE email = $1 AND expires_at > $2`, email, time.Now()))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, repository.ErrNotFound
+	}
+	return a, err
+}
+
+// RecordLoginFailure suma un fallo en un solo UPSERT, para que intentos
+// simultáneos no se pisen la cuenta. Si la entrada venció o el último fallo
+// es más viejo que window se empieza a contar de nuevo.
+func (r *Repository) RecordLoginFailure(ctx context.Context, email string, at time.Time, window time.Duration) (*model.LoginAttempts, error) {
+	var a *model.LoginAttempts
+	err := r.inTx(ctx, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM login_attempts WHERE expires_at <= $1`, at); err != nil {
+			return err
+		}
+		var err error
+		a, err = scanLoginAttempts(tx.QueryRowContext(ctx, `
+			INSERT INTO login_attempts AS la (email, failures, last_failure, expires_at)
+			VALUES ($1, 1, $2, $3)
+			ON CONFLICT (email) DO UPDATE SET
+				failures     = CASE WHEN la.last_failure < $4 THEN 1 ELSE la.failures + 1 END,
+				last_failure = EXCLUDED.last_failure,
+				expires_at   = GREATEST(EXCLUDED.expires_at, la.locked_until)
+			RETURNING email, failures, last_failure, locked_until, expires_at`,
+			email, at, at.Add(window), at.Add(-window),
+		))
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// LockLogin no acepta intentos de email hasta until.
+func (r *Repository) LockLogin(ctx context.Context, email string, until time.Time) error {
+	res, err := r.db.ExecContext(ctx, `
+		UPDATE login_attempts SET locked_until = $2, expires_at = GREATEST(expires_at, $2)
+		WHERE email = $1`, email, until)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return repository.ErrNotFound
+	}
+	return nil
+}
+
+func (r *Repository) ResetLoginAttempts(ctx context.Context, email string) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM login_attempts WHERE email = $1`, email)
+	return err
+}
+
+func scanLoginAttempts(row *sql.Row) (*model.LoginAttempts, error) {
+	var a model.LoginAttempts
+	var lockedUntil sql.NullTime
+	if err := row.Scan(&a.Email, &a.Failures, &a.LastFailure, &lockedUntil, &a.ExpiresAt); err != nil {
+		return nil, err
+	}
+	a.LockedUntil = lockedUntil.Time
+	return &a, nil
+}
@@ -0,0 +1,70 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"proyecto/auth-server/internal/repository"
+	"proyecto/auth-server/pkg/model"
+)
+
+func (r *Repository) GetRefreshToken(ctx context.Context, tokenHash string) (*model.RefreshToken, error) {
+	var t model.RefreshToken
+	err := r.db.QueryRowContext(ctx, `
+		SELECT token_hash, family_id, email, used, revoked, created_at, expires_at
+		FROM refresh_tokens WHERE token_hash = $1`, tokenHash,
+	).Scan(&t.TokenHash, &t.FamilyID, &t.Email, &t.Used, &t.Revoked, &t.CreatedAt, &t.ExpiresAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, repository.ErrNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// PutRefreshToken guarda el token y de paso borra los vencidos.
+func (r *Repository) PutRefreshToken(ctx context.Context, token *model.RefreshToken) error {
+	return r.inTx(ctx, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM refresh_tokens WHERE expires_at <= $1`, token.CreatedAt); err != nil {
+			return err
+		}
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO refresh_tokens (token_hash, family_id, email, used, revoked, created_at, expires_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)
+			ON CONFLICT (token_hash) DO UPDATE SET
+				used    = EXCLUDED.used,
+				revoked = EXCLUDED.revoked`,
+			token.TokenHash, token.FamilyID, token.Email, token.Used, token.Revoked, token.CreatedAt, token.ExpiresAt,
+		)
+		return err
+	})
+}
+
+// MarkRefreshTokenUsed marca el token como usado con un UPDATE condicional:
+// de dos canjes simultáneos solo uno cambia la fila.
+func (r *Repository) MarkRefreshTokenUsed(ctx context.Context, tokenHash string) error {
+	res, err := r.db.ExecContext(ctx, `UPDATE refresh_tokens SET used = TRUE WHERE token_hash = $1 AND NOT used`, tokenHash)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 1 {
+		return nil
+	}
+	if _, err := r.GetRefreshToken(ctx, tokenHash); err != nil {
+		return err
+	}
+	return repository.ErrAlreadyUsed
+}
+
+func (r *Repository) RevokeFamily(ctx context.Context, familyID string) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE refresh_tokens SET revoked = TRUE WHERE family_id = $1`, familyID)
+	return err
+}
+
+func (r *Repository) RevokeByEmail(ctx context.Context, email string) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE refresh_tokens SET revoked = TRUE WHERE email = $1`, email)
+	return err
+}
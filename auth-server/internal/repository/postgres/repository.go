@@ -0,0 +1,323 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"proyecto/auth-server/internal/repository"
+	"proyecto/auth-server/pkg/model"
+	"proyecto/pkg/idempotency"
+	"proyecto/pkg/storage/postgres"
+)
+
+var migrations = []postgres.Migration{
+	{Version: 1, Name: "create auth_users and auth_audit", SQL: `
+		CREATE TABLE auth_users (
+			email         TEXT PRIMARY KEY,
+			password_hash TEXT NOT NULL,
+			provider      TEXT NOT NULL DEFAULT '',
+			role          TEXT NOT NULL,
+			created_at    TIMESTAMPTZ NOT NULL
+		);
+		CREATE TABLE auth_audit (
+			id          BIGSERIAL PRIMARY KEY,
+			email       TEXT NOT NULL,
+			action      TEXT NOT NULL,
+			occurred_at TIMESTAMPTZ NOT NULL DEFAULT now()
+		);
+		CREATE INDEX auth_audit_email_idx ON auth_audit (email);
+	`},
+	{Version: 2, Name: "create registration_sagas", SQL: `
+		CREATE TABLE registration_sagas (
+			id         TEXT PRIMARY KEY,
+			email      TEXT NOT NULL,
+			state      TEXT NOT NULL,
+			profile    JSONB NOT NULL,
+			last_error TEXT NOT NULL DEFAULT '',
+			created_at TIMESTAMPTZ NOT NULL,
+			updated_at TIMESTAMPTZ NOT NULL
+		);
+		CREATE INDEX registration_sagas_state_idx ON registration_sagas (state);
+	`},
+	// Los usuarios que ya existían quedan como verificados; los nuevos no.
+	{Version: 3, Name: "add email verification to auth_users", SQL: `
+		ALTER TABLE auth_users
+			ADD COLUMN email_verified       BOOLEAN NOT NULL DEFAULT TRUE,
+			ADD COLUMN verification_sent_at TIMESTAMPTZ;
+		ALTER TABLE auth_users ALTER COLUMN email_verified SET DEFAULT FALSE;
+	`},
+	{Version: 4, Name: "create sessions and login attempts", SQL: `
+		CREATE TABLE refresh_tokens (
+			token_hash TEXT PRIMARY KEY,
+			family_id  TEXT NOT NULL,
+			email      TEXT NOT NULL,
+			used       BOOLEAN NOT NULL DEFAULT FALSE,
+			revoked    BOOLEAN NOT NULL DEFAULT FALSE,
+			created_at TIMESTAMPTZ NOT NULL,
+			expires_at TIMESTAMPTZ NOT NULL
+		);
+		CREATE INDEX refresh_tokens_family_idx ON refresh_tokens (family_id);
+		CREATE INDEX refresh_tokens_email_idx ON refresh_tokens (email);
+		CREATE INDEX refresh_tokens_expires_idx ON refresh_tokens (expires_at);
+		CREATE TABLE issued_tokens (
+			jti        TEXT PRIMARY KEY,
+			email      TEXT NOT NULL,
+			expires_at TIMESTAMPTZ NOT NULL
+		);
+		CREATE INDEX issued_tokens_email_idx ON issued_tokens (email);
+		CREATE INDEX issued_tokens_expires_idx ON issued_tokens (expires_at);
+		CREATE TABLE revoked_tokens (
+			jti        TEXT PRIMARY KEY,
+			email      TEXT NOT NULL,
+			revoked_at TIMESTAMPTZ NOT NULL,
+			expires_at TIMESTAMPTZ NOT NULL
+		);
+		CREATE INDEX revoked_tokens_expires_idx ON revoked_tokens (expires_at);
+		CREATE TABLE login_attempts (
+			email        TEXT PRIMARY KEY,
+			failures     INTEGER NOT NULL,
+			last_failure TIMESTAMPTZ NOT NULL,
+			locked_until TIMESTAMPTZ,
+			expires_at   TIMESTAMPTZ NOT NULL
+		);
+		CREATE INDEX login_attempts_expires_idx ON login_attempts (expires_at);
+	`},
+	{Version: 5, Name: "create idempotency_keys", SQL: idempotency.PostgresSchema},
+	{Version: 6, Name: "add saga_id to auth_users", SQL: `
+		ALTER TABLE auth_users ADD COLUMN saga_id TEXT NOT NULL DEFAULT '';
+	`},
+}
+
+// Repository guarda los usuarios de auth-server (y las sagas de registro, las
+// sesiones y los logins fallidos) en PostgreSQL. Cada escritura de usuario deja
+// su fila en auth_audit dentro de la misma transacción.
+type Repository struct {
+	db *sql.DB
+}
+
+// Open conecta a dsn y aplica las migraciones pendientes.
+func Open(ctx context.Context, dsn string) (*Repository, error) {
+	db, err := postgres.Open(ctx, dsn, migrations)
+	if err != nil {
+		return nil, err
+	}
+	return &Repository{db: db}, nil
+}
+
+func (r *Repository) Close() error {
+	return r.db.Close()
+}
+
+// IdempotencyStore guarda las claves de idempotencia en la misma base.
+func (r *Repository) IdempotencyStore() *idempotency.PostgresStore {
+	return idempotency.NewPostgresStore(r.db)
+}
+
+// Ping comprueba la conexión con la base (check de salud).
+func (r *Repository) Ping(ctx context.Context) error {
+	return r.db.PingContext(ctx)
+}
+
+func (r *Repository) GetHashByEmail(ctx context.Context, email string) (*model.AuthUser, error) {
+	var u model.AuthUser
+	var sentAt sql.NullTime
+	err := r.db.QueryRowContext(ctx,
+		`SELECT email, password_hash, provider, role, created_at, email_verified, verification_sent_at, saga_id
+		FROM auth_users WHERE email = $1`, email,
+	).Scan(&u.Email, &u.PasswordHash, &u.Provider, &u.Role, &u.CreatedAt, &u.EmailVerified, &sentAt, &u.SagaID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, repository.ErrNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	u.VerificationSentAt = sentAt.Time
+	return &u, nil
+}
+
+func (r *Repository) Put(ctx context.Context, user *model.AuthUser) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// xmax = 0 solo en filas recién insertadas: distingue alta de actualización.
+	var inserted bool
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO auth_users (email, password_hash, provider, role, created_at, email_verified, verification_sent_at, saga_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (email) DO UPDATE SET
+			password_hash        = EXCLUDED.password_hash,
+			provider             = EXCLUDED.provider,
+			role                 = EXCLUDED.role,
+			email_verified       = EXCLUDED.email_verified,
+			verification_sent_at = EXCLUDED.verification_sent_at
+		RETURNING (xmax = 0)`,
+		user.Email, user.PasswordHash, user.Provider, user.Role, user.CreatedAt, user.EmailVerified, nullTime(user.VerificationSentAt), user.SagaID,
+	).Scan(&inserted)
+	if err != nil {
+		return err
+	}
+
+	action := "updated"
+	if inserted {
+		action = "created"
+	}
+	if err := audit(ctx, tx, user.Email, action); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Create da de alta un usuario nuevo (la PK de email garantiza que no exista) y su fila de auditoría.
+func (r *Repository) Create(ctx context.Context, user *model.AuthUser) error {
+	return r.inTx(ctx, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO auth_users (email, password_hash, provider, role, created_at, email_verified, verification_sent_at, saga_id)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+			user.Email, user.PasswordHash, user.Provider, user.Role, user.CreatedAt, user.EmailVerified, nullTime(user.VerificationSentAt), user.SagaID,
+		)
+		if postgres.IsUniqueViolation(err) {
+			return repository.ErrAlreadyExists
+		} else if err != nil {
+			return err
+		}
+		return audit(ctx, tx, user.Email, "created")
+	})
+}
+
+func (r *Repository) UpdatePasswordHash(ctx context.Context, email, hash string) error {
+	return r.updateUser(ctx, email, "password_hash", hash)
+}
+
+func (r *Repository) SetRole(ctx context.Context, email, role string) error {
+	return r.updateUser(ctx, email, "role", role)
+}
+
+func (r *Repository) MarkVerified(ctx context.Context, email string) error {
+	return r.updateUser(ctx, email, "email_verified", true)
+}
+
+func (r *Repository) SetVerificationSentAt(ctx context.Context, email string, at time.Time) error {
+	return r.updateUser(ctx, email, "verification_sent_at", nullTime(at))
+}
+
+// updateUser cambia solo column (un nombre fijo, nunca un dato del usuario) y
+// deja su fila de auditoría.
+func (r *Repository) updateUser(ctx context.Context, email, column string, value any) error {
+	return r.inTx(ctx, func(tx *sql.Tx) error {
+		res, err := tx.ExecContext(ctx, `UPDATE auth_users SET `+column+` = $2 WHERE email = $1`, email, value)
+		if err != nil {
+			return err
+		}
+		if n, err := res.RowsAffected(); err != nil {
+			return err
+		} else if n == 0 {
+			return repository.ErrNotFound
+		}
+		return audit(ctx, tx, email, "updated "+column)
+	})
+}
+
+func (r *Repository) Delete(ctx context.Context, email string) error {
+	return r.inTx(ctx, func(tx *sql.Tx) error {
+		res, err := tx.ExecContext(ctx, `DELETE FROM auth_users WHERE email = $1`, email)
+		if err != nil {
+			return err
+		}
+		if n, err := res.RowsAffected(); err != nil {
+			return err
+		} else if n == 0 {
+			return repository.ErrNotFound
+		}
+		return audit(ctx, tx, email, "deleted")
+	})
+}
+
+func (r *Repository) PutSaga(ctx context.Context, saga *model.RegistrationSaga) error {
+	profile, err := json.Marshal(saga.Profile)
+	if err != nil {
+		return err
+	}
+	_, err = r.db.ExecContext(ctx, `
+		INSERT INTO registration_sagas (id, email, state, profile, last_error, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (id) DO UPDATE SET
+			state      = EXCLUDED.state,
+			profile    = EXCLUDED.profile,
+			last_error = EXCLUDED.last_error,
+			updated_at = EXCLUDED.updated_at`,
+		saga.ID, saga.Email, string(saga.State), profile, saga.LastError, saga.CreatedAt, saga.UpdatedAt,
+	)
+	return err
+}
+
+func (r *Repository) GetSaga(ctx context.Context, id string) (*model.RegistrationSaga, error) {
+	rows, err := r.db.QueryContext(ctx, sagaSelect+` WHERE id = $1`, id)
+	if err != nil {
+		return nil, err
+	}
+	sagas, err := scanSagas(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(sagas) == 0 {
+		return nil, repository.ErrNotFound
+	}
+	return sagas[0], nil
+}
+
+func (r *Repository) ListPendingSagas(ctx context.Context) ([]*model.RegistrationSaga, error) {
+	rows, err := r.db.QueryContext(ctx, sagaSelect+` WHERE state = ANY($1)`,
+		[]string{string(model.SagaStarted), string(model.SagaAuthCreated), string(model.SagaCompensating)})
+	if err != nil {
+		return nil, err
+	}
+	return scanSagas(rows)
+}
+
+const sagaSelect = `SELECT id, email, state, profile, last_error, created_at, updated_at FROM registration_sagas`
+
+func scanSagas(rows *sql.Rows) ([]*model.RegistrationSaga, error) {
+	defer rows.Close()
+	var res []*model.RegistrationSaga
+	for rows.Next() {
+		var s model.RegistrationSaga
+		var state string
+		var profile []byte
+		if err := rows.Scan(&s.ID, &s.Email, &state, &profile, &s.LastError, &s.CreatedAt, &s.UpdatedAt); err != nil {
+			return nil, err
+		}
+		s.State = model.SagaState(state)
+		if err := json.Unmarshal(profile, &s.Profile); err != nil {
+			return nil, err
+		}
+		res = append(res, &s)
+	}
+	return res, rows.Err()
+}
+
+func (r *Repository) inTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func audit(ctx context.Context, tx *sql.Tx, email, action string) error {
+	_, err := tx.ExecContext(ctx, `INSERT INTO auth_audit (email, action) VALUES ($1, $2)`, email, action)
+	return err
+}
+
+// nullTime guarda la fecha cero como NULL.
+func nullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t, Valid: !t.IsZero()}
+}
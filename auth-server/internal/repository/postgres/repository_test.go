@@ -0,0 +1,314 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"proyecto/auth-server/internal/repository"
+	"proyecto/auth-server/pkg/model"
+	"proyecto/pkg/storage/postgres/postgrestest"
+)
+
+func openTest(t *testing.T) (*Repository, string) {
+	t.Helper()
+	dsn := postgrestest.DSN(t)
+	r, err := Open(context.Background(), dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { r.Close() })
+	return r, dsn
+}
+
+func TestUsers(t *testing.T) {
+	r, _ := openTest(t)
+	ctx := context.Background()
+	user := &model.AuthUser{Email: "ana@example.com", PasswordHash: "h1", Role: "user", CreatedAt: time.Now()}
+
+	if _, err := r.GetHashByEmail(ctx, user.Email); !errors.Is(err, repository.ErrNotFound) {
+		t.Fatalf("Get antes de crear = %v, quería ErrNotFound", err)
+	}
+	if err := r.Create(ctx, user); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Create(ctx, user); !errors.Is(err, repository.ErrAlreadyExists) {
+		t.Errorf("Create repetido = %v, quería ErrAlreadyExists", err)
+	}
+
+	updated := *user
+	updated.PasswordHash = "h2"
+	if err := r.Put(ctx, &updated); err != nil {
+		t.Fatal(err)
+	}
+	got, err := r.GetHashByEmail(ctx, user.Email)
+	if err != nil || got.PasswordHash != "h2" {
+		t.Errorf("Get tras Put = %+v, %v", got, err)
+	}
+
+	if err := r.Delete(ctx, user.Email); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Delete(ctx, user.Email); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("Delete repetido = %v, quería ErrNotFound", err)
+	}
+}
+
+func TestUserFieldUpdates(t *testing.T) {
+	r, _ := openTest(t)
+	ctx := context.Background()
+	if err := r.Create(ctx, &model.AuthUser{Email: "ana@example.com", PasswordHash: "h1", Role: "user", CreatedAt: time.Now()}); err != nil {
+		t.Fatal(err)
+	}
+
+	sentAt := time.Now().Truncate(time.Second)
+	for _, update := range []func() error{
+		func() error { return r.UpdatePasswordHash(ctx, "ana@example.com", "h2") },
+		func() error { return r.SetRole(ctx, "ana@example.com", "admin") },
+		func() error { return r.MarkVerified(ctx, "ana@example.com") },
+		func() error { return r.SetVerificationSentAt(ctx, "ana@example.com", sentAt) },
+	} {
+		if err := update(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	got, err := r.GetHashByEmail(ctx, "ana@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Cada cambio conserva los anteriores.
+	if got.PasswordHash != "h2" || got.Role != "admin" || !got.EmailVerified || !got.VerificationSentAt.Equal(sentAt) {
+		t.Errorf("usuario = %+v", got)
+	}
+
+	if err := r.SetRole(ctx, "nadie@example.com", "admin"); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("SetRole de un usuario inexistente = %v, quería ErrNotFound", err)
+	}
+}
+
+func TestUserVerificationFields(t *testing.T) {
+	r, _ := openTest(t)
+	ctx := context.Background()
+	user := &model.AuthUser{Email: "ana@example.com", Role: "user", CreatedAt: time.Now()}
+	if err := r.Create(ctx, user); err != nil {
+		t.Fatal(err)
+	}
+	got, err := r.GetHashByEmail(ctx, user.Email)
+	if err != nil || got.EmailVerified || !got.VerificationSentAt.IsZero() {
+		t.Fatalf("Get recién creado = %+v, %v; quería sin verificar y sin envío", got, err)
+	}
+
+	sentAt := time.Now().Truncate(time.Second)
+	updated := *got
+	updated.EmailVerified, updated.VerificationSentAt = true, sentAt
+	if err := r.Put(ctx, &updated); err != nil {
+		t.Fatal(err)
+	}
+	got, err = r.GetHashByEmail(ctx, user.Email)
+	if err != nil || !got.EmailVerified || !got.VerificationSentAt.Equal(sentAt) {
+		t.Errorf("Get tras Put = %+v, %v; quería verificado con envío %v", got, err, sentAt)
+	}
+}
+
+func TestReopenKeepsSchema(t *testing.T) {
+	r, dsn := openTest(t)
+	ctx := context.Background()
+	if err := r.Create(ctx, &model.AuthUser{Email: "ana@example.com", Role: "user"}); err != nil {
+		t.Fatal(err)
+	}
+	r.Close()
+
+	r, err := Open(ctx, dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if _, err := r.GetHashByEmail(ctx, "ana@example.com"); err != nil {
+		t.Errorf("Get tras reabrir = %v", err)
+	}
+}
+
+func TestListPendingSagas(t *testing.T) {
+	r, _ := openTest(t)
+	ctx := context.Background()
+	for id, state := range map[string]model.SagaState{
+		"started":      model.SagaStarted,
+		"auth_created": model.SagaAuthCreated,
+		"compensating": model.SagaCompensating,
+		"completed":    model.SagaCompleted,
+		"compensated":  model.SagaCompensated,
+		"failed":       model.SagaFailed,
+	} {
+		if err := r.PutSaga(ctx, &model.RegistrationSaga{ID: id, Email: id + "@example.com", State: state}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	pending, err := r.ListPendingSagas(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]bool{}
+	for _, s := range pending {
+		got[s.ID] = true
+	}
+	if len(got) != 3 || !got["started"] || !got["auth_created"] || !got["compensating"] {
+		t.Errorf("sagas pendientes = %v", got)
+	}
+
+	saga, err := r.GetSaga(ctx, "completed")
+	if err != nil || saga.State != model.SagaCompleted {
+		t.Errorf("GetSaga = %+v, %v", saga, err)
+	}
+	if _, err := r.GetSaga(ctx, "otra"); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("GetSaga de una que no existe = %v", err)
+	}
+}
+
+func TestRefreshTokens(t *testing.T) {
+	r, _ := openTest(t)
+	ctx := context.Background()
+	now := time.Now()
+	for _, tok := range []*model.RefreshToken{
+		{TokenHash: "a1", FamilyID: "a", Email: "ana@example.com", CreatedAt: now, ExpiresAt: now.Add(time.Hour)},
+		{TokenHash: "a2", FamilyID: "a", Email: "ana@example.com", CreatedAt: now, ExpiresAt: now.Add(time.Hour)},
+		{TokenHash: "b1", FamilyID: "b", Email: "bob@example.com", CreatedAt: now, ExpiresAt: now.Add(time.Hour)},
+	} {
+		if err := r.PutRefreshToken(ctx, tok); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// De varios canjes simultáneos gana uno solo.
+	var wg sync.WaitGroup
+	results := make(chan error, 10)
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results <- r.MarkRefreshTokenUsed(ctx, "a1")
+		}()
+	}
+	wg.Wait()
+	close(results)
+	won := 0
+	for err := range results {
+		switch {
+		case err == nil:
+			won++
+		case !errors.Is(err, repository.ErrAlreadyUsed):
+			t.Errorf("MarkRefreshTokenUsed = %v", err)
+		}
+	}
+	if won != 1 {
+		t.Errorf("canjes ganadores = %d, quería 1", won)
+	}
+	if err := r.MarkRefreshTokenUsed(ctx, "nada"); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("MarkRefreshTokenUsed de uno que no existe = %v", err)
+	}
+
+	if err := r.RevokeFamily(ctx, "a"); err != nil {
+		t.Fatal(err)
+	}
+	for hash, want := range map[string]bool{"a1": true, "a2": true, "b1": false} {
+		tok, err := r.GetRefreshToken(ctx, hash)
+		if err != nil || tok.Revoked != want {
+			t.Errorf("%s revocado = %v (%v), quería %v", hash, tok.Revoked, err, want)
+		}
+	}
+	if err := r.RevokeByEmail(ctx, "bob@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	if tok, _ := r.GetRefreshToken(ctx, "b1"); !tok.Revoked {
+		t.Error("RevokeByEmail no revocó b1")
+	}
+
+	// Los vencidos se borran en la siguiente escritura.
+	later := now.Add(2 * time.Hour)
+	if err := r.PutRefreshToken(ctx, &model.RefreshToken{TokenHash: "c1", FamilyID: "c", CreatedAt: later, ExpiresAt: later.Add(time.Hour)}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.GetRefreshToken(ctx, "a1"); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("token vencido = %v, quería ErrNotFound", err)
+	}
+}
+
+func TestRevocations(t *testing.T) {
+	r, _ := openTest(t)
+	ctx := context.Background()
+	now := time.Now()
+
+	for _, tok := range []*model.IssuedToken{
+		{JTI: "j1", Email: "ana@example.com", ExpiresAt: now.Add(time.Hour)},
+		{JTI: "j2", Email: "ana@example.com", ExpiresAt: now.Add(-time.Minute)},
+		{JTI: "j3", Email: "bob@example.com", ExpiresAt: now.Add(time.Hour)},
+	} {
+		if err := r.TrackIssued(ctx, tok); err != nil {
+			t.Fatal(err)
+		}
+	}
+	issued, err := r.ListIssuedByEmail(ctx, "ana@example.com")
+	if err != nil || len(issued) != 1 || issued[0].JTI != "j1" {
+		t.Errorf("ListIssuedByEmail = %v, %v", issued, err)
+	}
+
+	if err := r.Revoke(ctx, &model.RevokedToken{JTI: "j1", RevokedAt: now, ExpiresAt: now.Add(time.Hour)}); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Revoke(ctx, &model.RevokedToken{JTI: "viejo", RevokedAt: now, ExpiresAt: now.Add(-time.Second)}); err != nil {
+		t.Fatal(err)
+	}
+	for jti, want := range map[string]bool{"j1": true, "viejo": false, "j3": false} {
+		if got, err := r.IsRevoked(ctx, jti); err != nil || got != want {
+			t.Errorf("IsRevoked(%s) = %v, %v; quería %v", jti, got, err, want)
+		}
+	}
+	list, err := r.ListRevoked(ctx)
+	if err != nil || len(list) != 1 || list[0].JTI != "j1" {
+		t.Errorf("ListRevoked = %v, %v", list, err)
+	}
+}
+
+func TestLoginAttempts(t *testing.T) {
+	r, _ := openTest(t)
+	ctx := context.Background()
+	now := time.Now().Truncate(time.Microsecond) // lo que guarda TIMESTAMPTZ
+	const email = "ana@example.com"
+
+	if _, err := r.GetLoginAttempts(ctx, email); !errors.Is(err, repository.ErrNotFound) {
+		t.Fatalf("GetLoginAttempts sin fallos = %v", err)
+	}
+	if err := r.LockLogin(ctx, email, now); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("LockLogin sin fallos = %v", err)
+	}
+
+	for i := 1; i <= 3; i++ {
+		a, err := r.RecordLoginFailure(ctx, email, now.Add(time.Duration(i)*time.Second), time.Minute)
+		if err != nil || a.Failures != i {
+			t.Fatalf("fallo %d = %+v, %v", i, a, err)
+		}
+	}
+	until := now.Add(time.Hour)
+	if err := r.LockLogin(ctx, email, until); err != nil {
+		t.Fatal(err)
+	}
+	a, err := r.GetLoginAttempts(ctx, email)
+	if err != nil || !a.LockedUntil.Equal(until) || a.ExpiresAt.Before(until) {
+		t.Errorf("tras LockLogin = %+v, %v", a, err)
+	}
+
+	// Pasada la ventana se empieza a contar de nuevo.
+	a, err = r.RecordLoginFailure(ctx, email, now.Add(10*time.Minute), time.Minute)
+	if err != nil || a.Failures != 1 {
+		t.Errorf("fallo fuera de la ventana = %+v, %v", a, err)
+	}
+
+	if err := r.ResetLoginAttempts(ctx, email); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.GetLoginAttempts(ctx, email); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("GetLoginAttempts tras Reset = %v", err)
+	}
+}
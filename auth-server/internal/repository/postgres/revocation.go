@@ -0,0 +1,83 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"proyecto/auth-server/pkg/model"
+)
+
+// Las entradas vencidas se borran al escribir, ya que un token vencido se rechaza igual.
+
+func (r *Repository) TrackIssued(ctx context.Context, token *model.IssuedToken) error {
+	return r.inTx(ctx, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM issued_tokens WHERE expires_at <= $1`, time.Now()); err != nil {
+			return err
+		}
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO issued_tokens (jti, email, expires_at) VALUES ($1, $2, $3)
+			ON CONFLICT (jti) DO NOTHING`,
+			token.JTI, token.Email, token.ExpiresAt,
+		)
+		return err
+	})
+}
+
+func (r *Repository) ListIssuedByEmail(ctx context.Context, email string) ([]*model.IssuedToken, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT jti, email, expires_at FROM issued_tokens WHERE email = $1 AND expires_at > $2`, email, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var res []*model.IssuedToken
+	for rows.Next() {
+		var t model.IssuedToken
+		if err := rows.Scan(&t.JTI, &t.Email, &t.ExpiresAt); err != nil {
+			return nil, err
+		}
+		res = append(res, &t)
+	}
+	return res, rows.Err()
+}
+
+func (r *Repository) Revoke(ctx context.Context, token *model.RevokedToken) error {
+	return r.inTx(ctx, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM revoked_tokens WHERE expires_at <= $1`, time.Now()); err != nil {
+			return err
+		}
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO revoked_tokens (jti, email, revoked_at, expires_at) VALUES ($1, $2, $3, $4)
+			ON CONFLICT (jti) DO NOTHING`,
+			token.JTI, token.Email, token.RevokedAt, token.ExpiresAt,
+		)
+		return err
+	})
+}
+
+func (r *Repository) IsRevoked(ctx context.Context, jti string) (bool, error) {
+	var revoked bool
+	err := r.db.QueryRowContext(ctx,
+		`SELECT EXISTS (SELECT 1 FROM revoked_tokens WHERE jti = $1 AND expires_at > $2)`, jti, time.Now(),
+	).Scan(&revoked)
+	return revoked, err
+}
+
+func (r *Repository) ListRevoked(ctx context.Context) ([]*model.RevokedToken, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT jti, email, revoked_at, expires_at FROM revoked_tokens WHERE expires_at > $1`, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	res := []*model.RevokedToken{}
+	for rows.Next() {
+		var t model.RevokedToken
+		if err := rows.Scan(&t.JTI, &t.Email, &t.RevokedAt, &t.ExpiresAt); err != nil {
+			return nil, err
+		}
+		res = append(res, &t)
+	}
+	return res, rows.Err()
+}
@@ -7,13 +7,91 @@ import (
 // AuthUser representa a un usuario autenticado.
 type AuthUser struct {
 	// Identidad y credenciales
-	Email        string    `json:"email"`          // Identificador único del usuario
-	PasswordHash string    `json:"password_hash"`  // Hash de la contraseña (solo "local")
-	Provider     string    `json:"provider"`       // "local", "google", "github", etc.
+	Email        string `json:"email"`         // Identificador único del usuario
+	PasswordHash string `json:"password_hash"` // Hash de la contraseña (solo "local")
+	Provider     string `json:"provider"`      // "local", "google", "github", etc.
+	// Verificación del email
+	EmailVerified      bool      `json:"email_verified"`                // El usuario confirmó que el email es suyo
+	VerificationSentAt time.Time `json:"verification_sent_at,omitzero"` // Último envío del email de verificación
 	// Autorización
-	Role string `json:"role"` // "user", "admin", etc.
+	Role string `json:"role"` // Uno de los roles de pkg/auth: "user", "support", "admin"
 	// Metadatos
-	CreatedAt time.Time `json:"created_at"` // Cuándo se registró
+	CreatedAt time.Time `json:"created_at"`        // Cuándo se registró
+	SagaID    string    `json:"saga_id,omitempty"` // Saga de registro que lo creó (vacío en usuarios anteriores)
 }
 
+// RefreshToken representa un token de refresco opaco emitido en el login.
+// Nunca se guarda el token en claro, solo su hash.
+type RefreshToken struct {
+	TokenHash string    `json:"token_hash"` // SHA-256 del token entregado al cliente
+	FamilyID  string    `json:"family_id"`  // Familia de rotación (todos los tokens derivados del mismo login)
+	Email     string    `json:"email"`      // Dueño del token
+	Used      bool      `json:"used"`       // Ya se canjeó por un token nuevo
+	Revoked   bool      `json:"revoked"`    // Se revocó la familia completa
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// IssuedToken registra un access token emitido, para poder revocar todas las sesiones de un usuario.
+type IssuedToken struct {
+	JTI       string    `json:"jti"`
+	Email     string    `json:"email"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// RevokedToken es una entrada de la lista de revocación; deja de importar cuando el token expira.
+type RevokedToken struct {
+	JTI       string    `json:"jti"`
+	Email     string    `json:"email"`
+	RevokedAt time.Time `json:"revoked_at"`
+	ExpiresAt time.Time `json:"expires_at"` // igual al exp del token
+}
+
+// LoginAttempts lleva los logins fallidos seguidos de un email, para frenar
+// ataques de fuerza bruta. Se guarda aunque el email no exista, para no revelarlo.
+type LoginAttempts struct {
+	Email       string    `json:"email"`
+	Failures    int       `json:"failures"` // fallos seguidos dentro de la ventana
+	LastFailure time.Time `json:"last_failure"`
+	LockedUntil time.Time `json:"locked_until"` // no se aceptan intentos hasta entonces
+	ExpiresAt   time.Time `json:"expires_at"`   // cuándo se puede olvidar la entrada
+}
 
+// MetadataUser es el perfil que auth-server manda a metadata-user al registrar.
+type MetadataUser struct {
+	Email       string `json:"email"`
+	FullName    string `json:"full_name"`
+	AvatarURL   string `json:"avatar_url"`
+	PhoneNumber string `json:"phone_number"`
+	BirthDate   string `json:"birth_date"`
+	LastUpdated string `json:"last_updated"`
+}
+
+// SagaState es el paso en el que quedó una saga de registro.
+type SagaState string
+
+const (
+	SagaStarted      SagaState = "started"      // todavía no se creó el usuario de auth
+	SagaAuthCreated  SagaState = "auth_created" // usuario creado, faltan los metadatos
+	SagaCompensating SagaState = "compensating" // metadatos fallaron, falta borrar el usuario
+	SagaCompleted    SagaState = "completed"
+	SagaCompensated  SagaState = "compensated"
+	SagaFailed       SagaState = "failed" // no se llegó a crear nada
+)
+
+// Pending indica si la saga quedó a medias y debe retomarse al arrancar.
+func (s SagaState) Pending() bool {
+	return s == SagaStarted || s == SagaAuthCreated || s == SagaCompensating
+}
+
+// RegistrationSaga guarda el progreso de un registro (usuario de auth + metadatos)
+// para poder retomarlo o deshacerlo si el proceso se cae a mitad.
+type RegistrationSaga struct {
+	ID        string       `json:"id"`
+	Email     string       `json:"email"`
+	State     SagaState    `json:"state"`
+	Profile   MetadataUser `json:"profile"`
+	LastError string       `json:"last_error,omitempty"`
+	CreatedAt time.Time    `json:"created_at"`
+	UpdatedAt time.Time    `json:"updated_at"`
+}
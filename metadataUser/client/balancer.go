@@ -0,0 +1,69 @@
+package client
+
+import (
+	"math/rand"
+	"sync"
+	"sync/atomic"
+)
+
+// Balancer elige a qué instancia mandar cada llamada. done se llama al
+// terminar la llamada, para los balanceadores que cuentan peticiones en curso.
+type Balancer interface {
+	Pick(addrs []string) (addr string, done func())
+}
+
+// RoundRobin reparte las llamadas en orden entre las instancias.
+type RoundRobin struct {
+	next atomic.Uint64
+}
+
+func NewRoundRobin() *RoundRobin {
+	return &RoundRobin{}
+}
+
+func (b *RoundRobin) Pick(addrs []string) (string, func()) {
+	n := b.next.Add(1) - 1
+	return addrs[n%uint64(len(addrs))], func() {}
+}
+
+// LeastInflight manda cada llamada a la instancia con menos peticiones en
+// curso desde este cliente; los empates se resuelven al azar.
+type LeastInflight struct {
+	mu       sync.Mutex
+	inflight map[string]int
+}
+
+func NewLeastInflight() *LeastInflight {
+	return &LeastInflight{inflight: map[string]int{}}
+}
+
+func (b *LeastInflight) Pick(addrs []string) (string, func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	best, ties := "", 0
+	for _, addr := range addrs {
+		n := b.inflight[addr]
+		switch {
+		case best == "" || n < b.inflight[best]:
+			best, ties = addr, 1
+		case n == b.inflight[best]:
+			ties++
+			if rand.Intn(ties) == 0 {
+				best = addr
+			}
+		}
+	}
+
+	b.inflight[best]++
+	var once sync.Once
+	return best, func() {
+		once.Do(func() {
+			b.mu.Lock()
+			defer b.mu.Unlock()
+			if b.inflight[best]--; b.inflight[best] <= 0 {
+				delete(b.inflight, best)
+			}
+		})
+	}
+}
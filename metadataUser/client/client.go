@@ -0,0 +1,496 @@
+// Package client es el cliente interno de metadata-user para los demás
+// servicios: descubre las instancias con el registry, reparte las llamadas
+// con un Balancer, reintenta las llamadas idempotentes y deja de usar por un
+// tiempo las instancias que fallan. Un circuit breaker por instancia y otro
+// por servicio hacen que, si metadata-user no responde, las llamadas fallen
+// enseguida en vez de esperar cada una el timeout.
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	model "proyecto/metadataUser/pkg"
+	"proyecto/pkg/breaker"
+	registry "proyecto/pkg/registry"
+)
+
+// ServiceName es el nombre con el que metadata-user se registra.
+const ServiceName = "metadata-user"
+
+var (
+	// ErrNotFound indica que no hay metadatos para ese email.
+	ErrNotFound = errors.New("metadata not found")
+	// ErrAlreadyExists indica que Create encontró metadatos ya creados.
+	ErrAlreadyExists = errors.New("metadata already exists")
+	// ErrVersionMismatch indica que el registro cambió desde la versión enviada.
+	ErrVersionMismatch = errors.New("metadata version mismatch")
+	// ErrUnauthorized indica que el token no es válido o no da acceso a ese email.
+	ErrUnauthorized = errors.New("unauthorized")
+	// ErrUnavailable indica que ninguna instancia respondió tras los reintentos.
+	ErrUnavailable = errors.New("metadata-user unavailable")
+)
+
+// StatusError es una respuesta inesperada de metadata-user.
+type StatusError struct {
+	Status int
+	Body   string
+	// RetryAfter es la espera que pidió el servidor con Retry-After (0 si no pidió ninguna).
+	RetryAfter time.Duration
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("metadata-user responded %d: %s", e.Status, e.Body)
+}
+
+type Client struct {
+	registry    registry.Registry
+	service     string
+	query       []registry.QueryOption
+	balancer    Balancer
+	http        *http.Client
+	maxAttempts int
+	backoff     time.Duration
+	maxBackoff  time.Duration
+	ejector     *ejector
+	breakers    breaker.Config
+	serviceCB   *breaker.Breaker
+	instanceCB  *breaker.Group
+}
+
+type Option func(*Client)
+
+// WithBalancer cambia el balanceo (por defecto RoundRobin).
+func WithBalancer(b Balancer) Option {
+	return func(c *Client) { c.balancer = b }
+}
+
+// WithHTTPClient cambia el http.Client (por defecto uno con timeout de 5s por intento).
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) { c.http = hc }
+}
+
+// WithTimeout cambia el timeout de cada intento (por defecto 5s). Trabaja
+// sobre una copia del http.Client, así no cambia el que se pasó con WithHTTPClient.
+func WithTimeout(d time.Duration) Option {
+	return func(c *Client) {
+		hc := *c.http
+		hc.Timeout = d
+		c.http = &hc
+	}
+}
+
+// WithRetries fija cuántos intentos se hacen como máximo y la espera base
+// entre ellos, que se duplica en cada reintento (con jitter).
+func WithRetries(maxAttempts int, backoff time.Duration) Option {
+	return func(c *Client) {
+		c.maxAttempts = max(maxAttempts, 1)
+		c.backoff = backoff
+		c.maxBackoff = 20 * backoff
+	}
+}
+
+// WithEjection saca del balanceo una instancia tras threshold fallos
+// seguidos durante cooldown (0 lo desactiva).
+func WithEjection(threshold int, cooldown time.Duration) Option {
+	return func(c *Client) { c.ejector = newEjector(threshold, cooldown) }
+}
+
+// WithBreakers cambia la configuración de los circuit breakers (por instancia y por servicio).
+func WithBreakers(cfg breaker.Config) Option {
+	return func(c *Client) { c.breakers = cfg }
+}
+
+// WithTags limita las llamadas a las instancias con esos tags, p.ej.
+// registry.VersionTag("v2") para apuntar a una versión durante un despliegue.
+func WithTags(tags ...string) Option {
+	return func(c *Client) {
+		for _, t := range tags {
+			c.query = append(c.query, registry.WithTag(t))
+		}
+	}
+}
+
+// WithServiceName cambia el nombre con el que se busca el servicio en el registry.
+func WithServiceName(name string) Option {
+	return func(c *Client) { c.service = name }
+}
+
+func New(reg registry.Registry, opts ...Option) *Client {
+	c := &Client{
+		registry:    reg,
+		service:     ServiceName,
+		balancer:    NewRoundRobin(),
+		http:        &http.Client{Timeout: 5 * time.Second},
+		maxAttempts: 3,
+		backoff:     100 * time.Millisecond,
+		maxBackoff:  2 * time.Second,
+		ejector:     newEjector(3, 30*time.Second),
+		breakers:    breaker.DefaultConfig,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	c.ejector.service = c.service
+	c.serviceCB = breaker.New(c.service, c.breakers)
+	c.instanceCB = breaker.NewGroup(c.breakers)
+	return c
+}
+
+// Create crea los metadatos. Cada llamada manda su propia Idempotency-Key,
+// así reintentarla no puede crear el registro dos veces.
+func (c *Client) Create(ctx context.Context, token string, m *model.MetadataUser) (*model.MetadataUser, error) {
+	return c.CreateWithKey(ctx, token, uuid.NewString(), m)
+}
+
+// CreateWithKey es Create con una Idempotency-Key elegida por quien llama,
+// para que una operación que se repite entre llamadas (p.ej. tras un
+// reinicio) reciba la respuesta del primer intento.
+func (c *Client) CreateWithKey(ctx context.Context, token, key string, m *model.MetadataUser) (*model.MetadataUser, error) {
+	out := &model.MetadataUser{}
+	err := c.do(ctx, call{
+		method:         http.MethodPost,
+		path:           "/MetadataUser",
+		token:          token,
+		form:           metadataForm(m),
+		idempotencyKey: key,
+		expect:         http.StatusCreated,
+		out:            out,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *Client) Get(ctx context.Context, token, email string) (*model.MetadataUser, error) {
+	out := &model.MetadataUser{}
+	err := c.do(ctx, call{
+		method: http.MethodGet,
+		path:   "/MetadataUser/Get",
+		token:  token,
+		query:  url.Values{"email": {email}},
+		expect: http.StatusOK,
+		out:    out,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Update reemplaza el registro completo. Si m.Version no es 0 solo se
+// escribe si el registro sigue en esa versión (ErrVersionMismatch si no); con
+// 0 se manda If-Match: * y se pisa cualquier versión.
+func (c *Client) Update(ctx context.Context, token string, m *model.MetadataUser) (*model.MetadataUser, error) {
+	out := &model.MetadataUser{}
+	err := c.do(ctx, call{
+		method:  http.MethodPut,
+		path:    "/MetadataUser",
+		token:   token,
+		form:    metadataForm(m),
+		version: m.Version,
+		expect:  http.StatusOK,
+		out:     out,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Delete borra los metadatos; con version distinto de 0 solo si siguen en esa versión.
+func (c *Client) Delete(ctx context.Context, token, email string, version int64) error {
+	return c.do(ctx, call{
+		method:  http.MethodDelete,
+		path:    "/MetadataUser",
+		token:   token,
+		query:   url.Values{"email": {email}},
+		version: version,
+		expect:  http.StatusNoContent,
+	})
+}
+
+type call struct {
+	method         string
+	path           string
+	token          string
+	query          url.Values
+	form           url.Values
+	version        int64
+	idempotencyKey string
+	expect         int
+	out            any
+}
+
+// do hace la llamada pasando por el breaker del servicio: solo cuentan como
+// fallo las llamadas que no consiguieron respuesta de ninguna instancia.
+func (c *Client) do(ctx context.Context, cl call) error {
+	done, err := c.serviceCB.Allow()
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrUnavailable, err)
+	}
+	err = c.doWithRetries(ctx, cl)
+	done(!errors.Is(err, ErrUnavailable))
+	return err
+}
+
+// doWithRetries hace la llamada con reintentos. Cada reintento va, si se
+// puede, a una instancia distinta de las ya probadas.
+func (c *Client) doWithRetries(ctx context.Context, cl call) error {
+	var (
+		lastErr error
+		tried   []string
+	)
+	for attempt := 0; attempt < c.maxAttempts; attempt++ {
+		if attempt > 0 {
+			wait := retryAfter(lastErr)
+			if wait > c.maxBackoff {
+				// Pidió esperar más de lo que se espera entre intentos.
+				return c.giveUp(lastErr)
+			}
+			if err := c.sleep(ctx, attempt, wait); err != nil {
+				return errors.Join(ErrUnavailable, lastErr)
+			}
+		}
+
+		addrs, err := c.registry.ServiceAddress(ctx, c.service, c.query...)
+		if err != nil {
+			lastErr = fmt.Errorf("resolving %s: %w", c.service, err)
+			continue
+		}
+		c.forget(addrs)
+		candidates := c.closedInstances(c.ejector.healthy(addrs))
+		if len(candidates) == 0 {
+			return fmt.Errorf("%w: %w", ErrUnavailable, breaker.ErrOpen)
+		}
+		if untried := slices.DeleteFunc(slices.Clone(candidates), func(a string) bool {
+			return slices.Contains(tried, a)
+		}); len(untried) > 0 {
+			candidates = untried
+		}
+
+		addr, done := c.balancer.Pick(candidates)
+		tried = append(tried, addr)
+		cbDone, err := c.instanceCB.Get(c.instanceName(addr)).Allow()
+		if err != nil {
+			done()
+			lastErr = fmt.Errorf("%s: %w", addr, err)
+			continue
+		}
+		retry, healthy, err := c.attempt(ctx, addr, cl)
+		done()
+		cbDone(healthy)
+		if healthy {
+			c.ejector.success(addr)
+		} else {
+			c.ejector.failure(addr)
+		}
+		if err == nil || !retry {
+			return err
+		}
+		lastErr = err
+	}
+	return c.giveUp(lastErr)
+}
+
+// giveUp arma el error de la llamada tras el último intento. Si metadata-user
+// respondió 429 no está caído, solo pide bajar el ritmo: no es ErrUnavailable,
+// así no cuenta como fallo para el breaker del servicio.
+func (c *Client) giveUp(lastErr error) error {
+	var se *StatusError
+	if errors.As(lastErr, &se) && se.Status == http.StatusTooManyRequests {
+		return lastErr
+	}
+	return errors.Join(ErrUnavailable, lastErr)
+}
+
+// retryAfter devuelve la espera que pidió el servidor en la respuesta de err.
+func retryAfter(err error) time.Duration {
+	var se *StatusError
+	if errors.As(err, &se) {
+		return se.RetryAfter
+	}
+	return 0
+}
+
+// closedInstances quita las instancias con el circuito abierto.
+func (c *Client) closedInstances(addrs []string) []string {
+	return slices.DeleteFunc(slices.Clone(addrs), func(a string) bool {
+		return c.instanceCB.Get(c.instanceName(a)).State() == breaker.Open
+	})
+}
+
+// forget descarta el estado (breaker y expulsión) de las instancias que ya
+// no están en addrs, para que no se acumule el de las que se fueron.
+func (c *Client) forget(addrs []string) {
+	names := make([]string, len(addrs))
+	for i, a := range addrs {
+		names[i] = c.instanceName(a)
+	}
+	c.instanceCB.Retain(names)
+	c.ejector.retain(addrs)
+}
+
+func (c *Client) instanceName(addr string) string {
+	return c.service + "@" + addr
+}
+
+// attempt hace una llamada a addr. retry indica si el error permite
+// reintentar y healthy si la instancia respondió como un servidor sano.
+func (c *Client) attempt(ctx context.Context, addr string, cl call) (retry, healthy bool, err error) {
+	target := "http://" + addr + cl.path
+	if len(cl.query) > 0 {
+		target += "?" + cl.query.Encode()
+	}
+	var body io.Reader
+	if cl.form != nil {
+		body = strings.NewReader(cl.form.Encode())
+	}
+
+	req, err := http.NewRequestWithContext(ctx, cl.method, target, body)
+	if err != nil {
+		return false, true, err
+	}
+	if cl.form != nil {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	req.Header.Set("Authorization", "Bearer "+cl.token)
+	switch {
+	case cl.version != 0:
+		req.Header.Set("If-Match", `"`+strconv.FormatInt(cl.version, 10)+`"`)
+	case cl.method == http.MethodPut:
+		// El servidor exige If-Match en PUT; sin versión se acepta cualquiera.
+		req.Header.Set("If-Match", "*")
+	}
+	if cl.idempotencyKey != "" {
+		req.Header.Set("Idempotency-Key", cl.idempotencyKey)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			// Lo canceló quien llama; no es culpa de la instancia.
+			return false, true, ctx.Err()
+		}
+		return c.retryable(cl, err), false, fmt.Errorf("calling %s: %w", target, err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == cl.expect:
+		if cl.out == nil {
+			return false, true, nil
+		}
+		if err := json.NewDecoder(resp.Body).Decode(cl.out); err != nil {
+			return false, true, fmt.Errorf("decoding response from %s: %w", target, err)
+		}
+		return false, true, nil
+	case resp.StatusCode >= 500:
+		return c.idempotent(cl), false, statusError(resp)
+	case resp.StatusCode == http.StatusTooManyRequests:
+		// La instancia está sana, solo limitó la petición: se reintenta tras
+		// Retry-After pero no cuenta para la expulsión ni para el breaker.
+		return c.idempotent(cl), true, statusError(resp)
+	}
+
+	// El servicio respondió: la instancia está sana aunque la petición no.
+	switch resp.StatusCode {
+	case http.StatusNotFound:
+		return false, true, ErrNotFound
+	case http.StatusPreconditionFailed:
+		return false, true, ErrVersionMismatch
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return false, true, ErrUnauthorized
+	case http.StatusConflict:
+		// Con Retry-After es otra petición con la misma clave aún en curso.
+		if resp.Header.Get("Retry-After") != "" {
+			return true, true, statusError(resp)
+		}
+		return false, true, ErrAlreadyExists
+	}
+	return false, true, statusError(resp)
+}
+
+// idempotent indica si la llamada se puede repetir sin efectos dobles:
+// GET, PUT y DELETE lo son; POST solo si lleva Idempotency-Key.
+func (c *Client) idempotent(cl call) bool {
+	return cl.method != http.MethodPost || cl.idempotencyKey != ""
+}
+
+// retryable indica si un error de red permite reintentar. Si ni siquiera se
+// pudo conectar la petición no llegó al servidor y siempre se puede repetir.
+func (c *Client) retryable(cl call, err error) bool {
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == "dial" {
+		return true
+	}
+	return c.idempotent(cl)
+}
+
+// sleep espera el backoff del intento (exponencial con jitter completo), y
+// al menos atLeast.
+func (c *Client) sleep(ctx context.Context, attempt int, atLeast time.Duration) error {
+	d := c.backoff << (attempt - 1)
+	if d > c.maxBackoff || d <= 0 {
+		d = c.maxBackoff
+	}
+	if d > 0 {
+		d = time.Duration(rand.Int63n(int64(d)) + 1)
+	}
+	d = max(d, atLeast)
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+func statusError(resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	return &StatusError{
+		Status:     resp.StatusCode,
+		Body:       strings.TrimSpace(string(body)),
+		RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+	}
+}
+
+// parseRetryAfter entiende Retry-After en segundos o como fecha HTTP.
+func parseRetryAfter(v string) time.Duration {
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(v); err == nil {
+		return max(time.Duration(secs)*time.Second, 0)
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return max(time.Until(t), 0)
+	}
+	return 0
+}
+
+func metadataForm(m *model.MetadataUser) url.Values {
+	return url.Values{
+		"email":        {m.Email},
+		"full_name":    {m.FullName},
+		"avatar_url":   {m.AvatarURL},
+		"phone_number": {m.PhoneNumber},
+		"birth_date":   {m.BirthDate},
+	}
+}
@@ -0,0 +1,151 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	model "proyecto/metadataUser/pkg"
+	"proyecto/pkg/breaker"
+	registry "proyecto/pkg/registry"
+)
+
+// staticRegistry resuelve siempre a las mismas direcciones.
+type staticRegistry struct {
+	registry.Registry
+	addrs []string
+}
+
+func (r staticRegistry) ServiceAddress(context.Context, string, ...registry.QueryOption) ([]string, error) {
+	return r.addrs, nil
+}
+
+// newTestServer responde con los status de statuses en orden (el último se repite).
+func newTestServer(t *testing.T, retryAfter string, statuses ...int) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		n := int(calls.Add(1))
+		status := statuses[min(n, len(statuses))-1]
+		if status == http.StatusTooManyRequests {
+			w.Header().Set("Retry-After", retryAfter)
+		}
+		w.WriteHeader(status)
+		if status == http.StatusOK {
+			_, _ = w.Write([]byte(`{"email":"ana@example.com","version":1}`))
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &calls
+}
+
+func newTestClient(srv *httptest.Server, opts ...Option) *Client {
+	addr := strings.TrimPrefix(srv.URL, "http://")
+	opts = append([]Option{
+		WithRetries(3, time.Millisecond),
+		WithBreakers(breaker.Config{MinRequests: 1, FailureRatio: 0.5}),
+		WithEjection(1, time.Minute),
+	}, opts...)
+	return New(staticRegistry{addrs: []string{addr}}, opts...)
+}
+
+func TestTooManyRequestsRetried(t *testing.T) {
+	srv, calls := newTestServer(t, "0", http.StatusTooManyRequests, http.StatusOK)
+	c := newTestClient(srv)
+
+	m, err := c.Get(context.Background(), "token", "ana@example.com")
+	if err != nil || m.Email != "ana@example.com" {
+		t.Fatalf("Get = %+v, %v", m, err)
+	}
+	if n := calls.Load(); n != 2 {
+		t.Errorf("llamadas = %d, quería 2", n)
+	}
+}
+
+func TestTooManyRequestsIsNotAFailure(t *testing.T) {
+	srv, calls := newTestServer(t, "0", http.StatusTooManyRequests)
+	c := newTestClient(srv)
+	addr := strings.TrimPrefix(srv.URL, "http://")
+
+	_, err := c.Get(context.Background(), "token", "ana@example.com")
+	var se *StatusError
+	if !errors.As(err, &se) || se.Status != http.StatusTooManyRequests || errors.Is(err, ErrUnavailable) {
+		t.Fatalf("Get = %v, quería el 429 sin ErrUnavailable", err)
+	}
+	if n := calls.Load(); n != 3 {
+		t.Errorf("llamadas = %d, quería 3", n)
+	}
+	// Ni la instancia ni el servicio quedan marcados como caídos.
+	if got := c.ejector.healthy([]string{addr}); len(got) != 1 || len(c.ejector.hosts) != 0 {
+		t.Errorf("la instancia quedó expulsada: %v", c.ejector.hosts)
+	}
+	if s := c.instanceCB.Get(c.instanceName(addr)).State(); s != breaker.Closed {
+		t.Errorf("breaker de la instancia = %s, quería closed", s)
+	}
+	if s := c.serviceCB.State(); s != breaker.Closed {
+		t.Errorf("breaker del servicio = %s, quería closed", s)
+	}
+}
+
+func TestRetryAfterLongerThanBackoff(t *testing.T) {
+	srv, calls := newTestServer(t, "60", http.StatusTooManyRequests, http.StatusOK)
+	c := newTestClient(srv)
+
+	start := time.Now()
+	_, err := c.Get(context.Background(), "token", "ana@example.com")
+	var se *StatusError
+	if !errors.As(err, &se) || se.RetryAfter != time.Minute {
+		t.Fatalf("Get = %v, quería el 429 con Retry-After de 1m", err)
+	}
+	if n := calls.Load(); n != 1 || time.Since(start) > time.Second {
+		t.Errorf("llamadas = %d en %s; no tenía que esperar ni reintentar", n, time.Since(start))
+	}
+}
+
+func TestServerErrorEjects(t *testing.T) {
+	srv, _ := newTestServer(t, "", http.StatusServiceUnavailable)
+	c := newTestClient(srv, WithRetries(1, time.Millisecond))
+
+	_, err := c.Get(context.Background(), "token", "ana@example.com")
+	if !errors.Is(err, ErrUnavailable) {
+		t.Fatalf("Get = %v, quería ErrUnavailable", err)
+	}
+	if len(c.ejector.hosts) != 1 {
+		t.Errorf("la instancia no quedó expulsada")
+	}
+}
+
+func TestWithTimeoutCopiesClient(t *testing.T) {
+	hc := &http.Client{Timeout: time.Minute}
+	c := New(staticRegistry{}, WithHTTPClient(hc), WithTimeout(time.Second))
+	if hc.Timeout != time.Minute {
+		t.Errorf("WithTimeout cambió el http.Client recibido: %s", hc.Timeout)
+	}
+	if c.http.Timeout != time.Second {
+		t.Errorf("timeout del cliente = %s, quería 1s", c.http.Timeout)
+	}
+}
+
+func TestUpdateSendsIfMatch(t *testing.T) {
+	var got atomic.Value
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		got.Store(req.Header.Get("If-Match"))
+		_, _ = w.Write([]byte(`{"email":"ana@example.com","version":2}`))
+	}))
+	t.Cleanup(srv.Close)
+	c := newTestClient(srv)
+
+	for version, want := range map[int64]string{0: "*", 1: `"1"`} {
+		if _, err := c.Update(context.Background(), "token", &model.MetadataUser{Email: "ana@example.com", Version: version}); err != nil {
+			t.Fatal(err)
+		}
+		if got.Load() != want {
+			t.Errorf("Update con versión %d mandó If-Match %q, quería %q", version, got.Load(), want)
+		}
+	}
+}
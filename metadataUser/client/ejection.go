@@ -0,0 +1,102 @@
+package client
+
+import (
+	"log"
+	"slices"
+	"sync"
+	"time"
+)
+
+// ejector saca temporalmente del balanceo las instancias que fallan varias
+// veces seguidas (detección pasiva: solo mira las llamadas reales). Cada
+// expulsión seguida de la misma instancia dura el doble, hasta maxCooldown.
+type ejector struct {
+	service     string // para los logs
+	mu          sync.Mutex
+	threshold   int
+	cooldown    time.Duration
+	maxCooldown time.Duration
+	hosts       map[string]*hostState
+}
+
+type hostState struct {
+	failures     int
+	ejections    int
+	ejectedUntil time.Time
+}
+
+func newEjector(threshold int, cooldown time.Duration) *ejector {
+	return &ejector{
+		threshold:   threshold,
+		cooldown:    cooldown,
+		maxCooldown: 10 * cooldown,
+		hosts:       map[string]*hostState{},
+	}
+}
+
+// healthy filtra las instancias expulsadas. Si lo están todas devuelve la
+// lista completa: es mejor probar una instancia dudosa que no llamar a nadie.
+func (e *ejector) healthy(addrs []string) []string {
+	if e.threshold <= 0 {
+		return addrs
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	now := time.Now()
+	out := make([]string, 0, len(addrs))
+	for _, addr := range addrs {
+		if h, ok := e.hosts[addr]; ok && now.Before(h.ejectedUntil) {
+			continue
+		}
+		out = append(out, addr)
+	}
+	if len(out) == 0 {
+		return addrs
+	}
+	return out
+}
+
+// retain olvida las instancias que no están en addrs.
+func (e *ejector) retain(addrs []string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for addr := range e.hosts {
+		if !slices.Contains(addrs, addr) {
+			delete(e.hosts, addr)
+		}
+	}
+}
+
+func (e *ejector) success(addr string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	delete(e.hosts, addr)
+}
+
+func (e *ejector) failure(addr string) {
+	if e.threshold <= 0 {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	h, ok := e.hosts[addr]
+	if !ok {
+		h = &hostState{}
+		e.hosts[addr] = h
+	}
+	h.failures++
+	if h.failures < e.threshold {
+		return
+	}
+
+	d := e.cooldown << h.ejections
+	if d > e.maxCooldown || d <= 0 {
+		d = e.maxCooldown
+	}
+	h.failures = 0
+	h.ejections++
+	h.ejectedUntil = time.Now().Add(d)
+	log.Printf("%s: instancia %s expulsada del balanceo por %s", e.service, addr, d)
+}
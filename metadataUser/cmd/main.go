@@ -6,121 +6,142 @@ import (
 	"log"
 	"net/http"
 	"context"
-	"time"
 	"os"
-	"os/signal"
-	"syscall"
 
+	"proyecto/metadataUser/internal/config"
 	"proyecto/metadataUser/internal/controller"
 	httphandler "proyecto/metadataUser/internal/handler"
+	"proyecto/metadataUser/internal/repository/bolt"
 	"proyecto/metadataUser/internal/repository/memory"
-	"proyecto/pkg/discovery/consul"
-	registrypkg"proyecto/pkg/registry"
+	"proyecto/metadataUser/internal/repository/postgres"
+	"proyecto/pkg/auth"
+	configpkg "proyecto/pkg/config"
+	"proyecto/pkg/idempotency"
+	"proyecto/pkg/ratelimit"
+	"proyecto/pkg/health"
+	"proyecto/pkg/service"
 )
 
-const defaultServiceName = "metadata-user"
-
-
 func main() {
-	var port int
-	flag.IntVar(&port, "port", 8081, "Puerto del microservicio de metadata de usuario")
+	// Config: valores por defecto, archivo (-config), variables de entorno y flags, en ese orden.
+	var configFile string
+	var printConfig bool
+	flag.StringVar(&configFile, "config", os.Getenv("CONFIG_FILE"), "Archivo YAML de configuración")
+	flag.Int("port", 8081, "Puerto del microservicio de metadata de usuario")
+	flag.String("storage", "memory", "Almacenamiento de metadatos: memory, bolt o postgres")
+	flag.BoolVar(&printConfig, "print-config", false, "Muestra la configuración efectiva (sin secretos) y termina")
 	flag.Parse()
-	serviceName := os.Getenv("SERVICE_NAME")
-	if serviceName == "" {
-		serviceName = defaultServiceName
-	}
-
-	log.Printf("Starting %s service on port %d", serviceName, port)
 
-
-	// ===== Consul =====
-	// Dónde está Consul (local: localhost:8500 (si no lo corro con docker) o si es docker: consul:8500 o docker->host: host.docker.internal:8500)
-	consulAddr := os.Getenv("CONSUL_HOST")
-	if consulAddr == "" {
-		consulAddr = "localhost:8500"
+	cfg := config.Default()
+	if err := configpkg.Load(configFile, &cfg); err != nil {
+		log.Fatalf("error cargando configuración: %v", err)
 	}
-
-	log.Printf("CONSUL_HOST = %s", consulAddr)
-
-	reg, err := consul.NewRegistry(consulAddr)
+	cfg.ApplyFlags(flag.CommandLine)
+	if err := cfg.Validate(); err != nil {
+		log.Fatalf("configuración inválida:\n%v", err)
+	}
+	dump, err := configpkg.Redacted(cfg)
 	if err != nil {
-		log.Fatalf("error creando registry de consul: %v", err)
+		log.Fatalf("error mostrando configuración: %v", err)
 	}
-
-	ctx := context.Background()
-	instanceID := registrypkg.GenerateInstanceID(serviceName)
-
-	// Dirección que se **publica** en Consul para que otros clientes la usen.
-	// En local: localhost
-	// En Docker (misma red que Consul): el nombre del contenedor (p.ej. auth-server)
-	host := os.Getenv("SERVICE_HOST")
-	if host == "" {
-		// Fallback sensato: en local funciona; en Docker deberías pasar SERVICE_HOST=auth-server
-		host = "localhost"
+	if printConfig {
+		fmt.Print(dump)
+		return
 	}
+	log.Printf("Configuración efectiva:\n%s", dump)
 
-	hostPort := fmt.Sprintf("%s:%d", host, port)
-
-	// Registro en Consul (nota: 0.0.0.0 NO es válido para Consul)
-	if err := reg.Register(ctx, instanceID, serviceName, hostPort); err != nil {
-		log.Fatalf("error registrando servicio en consul: %v", err)
+	// Registry, /healthz, /readyz, latido y apagado ordenado los maneja pkg/service
+	svc, err := service.New(cfg.Service)
+	if err != nil {
+		log.Fatalf("error iniciando servicio: %v", err)
 	}
-	log.Printf("%s registrado en Consul con ID=%s y address=%s", serviceName, instanceID, hostPort)
-
-	// Health TTL (latido): marca el servicio como "passing" periódicamente
-	go func() {
-		t := time.NewTicker(3 * time.Second)
-		defer t.Stop()
-		for range t.C {
-			if err := reg.ReportHealthyState(instanceID, serviceName); err != nil {
-				log.Printf("Failed to report healthy state: %v", err)
-			}
-		}
-	}()
-
-	// Deregistro limpio al salir por señal (Ctrl c /SIGTERM) se borra en consul 
-	stop := make(chan os.Signal, 1)
-	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
-	go func() {
-		<-stop
-		log.Println("Recibida señal, deregistrando servicio en Consul...")
-		_ = reg.Deregister(context.Background(), instanceID, serviceName)
-		os.Exit(0)
-	}()
+	reg := svc.Registry
+	ctx := context.Background()
 
 	//Crear todo 
-	r := memory.New()
+	// Almacenamiento: memory se pierde al reiniciar, bolt guarda en un archivo
+	// (BOLT_PATH), postgres usa DATABASE_URL; ambos migran al arrancar. Las
+	// claves de idempotencia van en el mismo almacenamiento.
+	var r metadataUser.MetadataUserRepository
+	var idempotencyStore idempotency.Store
+	switch cfg.Storage.Backend {
+	case "memory":
+		r = memory.New()
+		idempotencyStore = idempotency.NewMemoryStore()
+	case "bolt":
+		path := cfg.Storage.BoltPath
+		if path == "" {
+			path = svc.Name + ".db"
+		}
+		boltRepo, err := bolt.Open(path)
+		if err != nil {
+			log.Fatalf("error abriendo base bolt: %v", err)
+		}
+		svc.OnShutdown(boltRepo.Close)
+		r = boltRepo
+		idempotencyStore = boltRepo.IdempotencyStore()
+		log.Printf("Metadatos guardados en %s", path)
+	case "postgres":
+		pgRepo, err := postgres.Open(ctx, cfg.Storage.DatabaseURL)
+		if err != nil {
+			log.Fatalf("error conectando a postgres: %v", err)
+		}
+		svc.OnShutdown(pgRepo.Close)
+		r = pgRepo
+		idempotencyStore = pgRepo.IdempotencyStore()
+		log.Printf("Metadatos guardados en PostgreSQL")
+	default:
+		log.Fatalf("backend de almacenamiento desconocido: %q", cfg.Storage.Backend)
+	}
 	c := metadataUser.New(r)
 	h := httphandler.New(c)
 
+	svc.Health.Add("repository", health.Critical, health.PingCheck(r))
+	svc.Health.Add(cfg.Auth.Server, health.Warning, health.ResolvableCheck(reg, cfg.Auth.Server))
+
+	// Verificación de los tokens de auth-server: con el JWKS que publica (o con
+	// JWT_SECRET / JWT_SECRET_FILE si se firma con HS256) y consultando su lista de revocación.
+	var keySource auth.KeySource = auth.NewRemoteJWKS(auth.RegistryURL(reg, cfg.Auth.Server, "/.well-known/jwks.json"), nil)
+	var verifierOpts []auth.Option
+	if cfg.Auth.JWTAlg == "HS256" {
+		secret, err := cfg.Auth.LoadSecret()
+		if err != nil {
+			log.Fatalf("error cargando JWT secret: %v", err)
+		}
+		hmacKey := auth.NewRotatingHMACKey(secret)
+		keySource = hmacKey
+		verifierOpts = append(verifierOpts, auth.WithMethods("HS256"))
+		// Con SIGHUP se vuelve a leer el archivo del secreto (el anterior se sigue aceptando).
+		if cfg.Auth.JWTSecretFile != "" {
+			svc.OnReload(func() error {
+				secret, err := cfg.Auth.LoadSecret()
+				if err != nil {
+					return err
+				}
+				hmacKey.Set(secret)
+				return nil
+			})
+		}
+	}
+	revocations := auth.NewRemoteRevocations(auth.RegistryURL(reg, cfg.Auth.Server, "/Auth-Server/revoked"), nil, cfg.Auth.RevocationsToken, cfg.Auth.RevocationsInterval)
+	verifierOpts = append(verifierOpts, auth.WithRevocationChecker(revocations))
+	requireAuth := auth.Middleware(auth.NewVerifier(keySource, config.ServiceName, verifierOpts...))
+	// Va dentro de requireAuth para que las claves queden separadas por usuario.
+	idempotent := idempotency.Middleware(idempotencyStore, idempotency.DefaultRetention)
+
 	// endpoint
 	
 	// Rutas 
-	mux := http.NewServeMux()
-	mux.Handle("/MetadataUser", http.HandlerFunc(h.CreateMetadatUser)) // Es para Crear los usuarios 
-	mux.Handle("/MetadataUser/Get", http.HandlerFunc(h.GetMetadatUser)) //Es para obtener los usuarios 
-
-	//Servidor HTTP
-	srv := &http.Server{
-		Addr:         fmt.Sprintf(":%d", port),
-		Handler:      mux,
-		ReadTimeout:  10 * time.Second, // tiempo máx. para leer la petición
-		WriteTimeout: 15 * time.Second, // tiempo máx. para escribir la respuesta
-		IdleTimeout:  60 * time.Second, // tiempo máx. de conexión inactiva
-	}
-	// Graceful shutdown adicional (opcional): cierre ordenado del server
-	go func() {
-		<-stop
-		log.Println("Shutting down HTTP server...")
-		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-		defer cancel()
-		_ = srv.Shutdown(ctx)
-	}()
-
-	log.Printf("%s escuchando en %s", serviceName, srv.Addr)
-	if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-		log.Fatalf("server error: %v", err)
+	// Límites por ruta (rate_limits en la config); van dentro de requireAuth para poder contar por usuario.
+	limit := ratelimit.New(ratelimit.NewMemoryStore(), cfg.RateLimits).Wrap
+	mux := svc.Mux
+	mux.Handle("POST /MetadataUser", requireAuth(limit("create", idempotent(http.HandlerFunc(h.CreateMetadatUser)))))    // Es para Crear los usuarios (409 si ya existe)
+	mux.Handle("PUT /MetadataUser", requireAuth(limit("replace", http.HandlerFunc(h.ReplaceMetadatUser))))   // Reemplaza el registro completo
+	mux.Handle("PATCH /MetadataUser", requireAuth(limit("patch", http.HandlerFunc(h.PatchMetadatUser))))   // Modifica solo los campos enviados
+	mux.Handle("DELETE /MetadataUser", requireAuth(limit("delete", http.HandlerFunc(h.DeleteMetadatUser)))) // Borra los metadatos
+	mux.Handle("/MetadataUser/Get", requireAuth(limit("get", http.HandlerFunc(h.GetMetadatUser))))        //Es para obtener los usuarios 
+
+	if err := svc.Run(ctx); err != nil {
+		log.Fatalf("%s: %v", svc.Name, err)
 	}
 }
-
-/* Le faltan algunas cosas, como verificar que si tenga acceso el usuario , y la parte de modificar, y borrar */
\ No newline at end of file
@@ -0,0 +1,127 @@
+// Package config es la configuración de metadata-user: valores por defecto,
+// archivo YAML (-config o CONFIG_FILE), variables de entorno y flags.
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"time"
+
+	"proyecto/pkg/auth"
+	configpkg "proyecto/pkg/config"
+	"proyecto/pkg/ratelimit"
+	"proyecto/pkg/service"
+	"proyecto/pkg/storage"
+)
+
+const ServiceName = "metadata-user"
+
+type Config struct {
+	Service service.Config `yaml:"service"`
+	Storage storage.Config `yaml:"storage"`
+	Auth    Auth           `yaml:"auth"`
+	// RateLimits son los límites por ruta (create, get, ...); "default" se
+	// aplica a las rutas sin uno propio y requests: 0 quita el límite.
+	RateLimits map[string]ratelimit.Limit `yaml:"rate_limits"`
+}
+
+// Auth es cómo se verifican los tokens de auth-server.
+type Auth struct {
+	Server string `yaml:"server" env:"AUTH_SERVICE"` // nombre de auth-server en el registry
+	// JWTAlg HS256 verifica con JWTSecret; cualquier otro con el JWKS de auth-server.
+	JWTAlg    string `yaml:"jwt_alg" env:"JWT_ALG"`
+	JWTSecret string `yaml:"jwt_secret" env:"JWT_SECRET" secret:"true"`
+	// JWTSecretFile es un archivo con el secreto; se vuelve a leer con SIGHUP.
+	JWTSecretFile string `yaml:"jwt_secret_file" env:"JWT_SECRET_FILE"`
+	// RevocationsInterval es cada cuánto se baja la lista de revocación.
+	RevocationsInterval time.Duration `yaml:"revocations_interval" env:"REVOCATIONS_INTERVAL"`
+	// RevocationsToken es la credencial de servicio para bajar la lista de
+	// revocación; tiene que coincidir con revocations_token de auth-server.
+	RevocationsToken string `yaml:"revocations_token" env:"REVOCATIONS_TOKEN" secret:"true"`
+}
+
+func Default() Config {
+	return Config{
+		Service: service.DefaultConfig(ServiceName, 8081),
+		Storage: storage.DefaultConfig(),
+		Auth: Auth{
+			Server:              "auth-server",
+			JWTAlg:              "RS256",
+			RevocationsInterval: 30 * time.Second,
+		},
+		RateLimits: map[string]ratelimit.Limit{
+			"default": {Algorithm: ratelimit.TokenBucket, By: ratelimit.BySubject, Requests: 120, Window: time.Minute, Burst: 60},
+		},
+	}
+}
+
+// ApplyFlags pisa la configuración con los flags que se pasaron en fs
+// (-port y -storage); los que no se pasaron no cambian nada, así el archivo y
+// las variables de entorno mandan sobre sus valores por defecto.
+func (c *Config) ApplyFlags(fs *flag.FlagSet) {
+	fs.Visit(func(f *flag.Flag) {
+		getter, ok := f.Value.(flag.Getter)
+		if !ok {
+			return
+		}
+		switch f.Name {
+		case "port":
+			c.Service.Port, _ = getter.Get().(int)
+		case "storage":
+			c.Storage.Backend, _ = getter.Get().(string)
+		}
+	})
+}
+
+// Validate devuelve todos los errores juntos, para corregirlos de una vez.
+func (c Config) Validate() error {
+	errs := []error{c.Service.Validate(), c.Storage.Validate()}
+	if c.Auth.Server == "" {
+		errs = append(errs, errors.New("auth.server es obligatorio"))
+	}
+	switch c.Auth.JWTAlg {
+	case "RS256", "EdDSA":
+	case "HS256":
+		switch {
+		case c.Auth.JWTSecret == "" && c.Auth.JWTSecretFile == "":
+			errs = append(errs, errors.New("auth.jwt_secret (JWT_SECRET) o auth.jwt_secret_file (JWT_SECRET_FILE) es obligatorio con HS256"))
+		case c.Auth.JWTSecret != "" && c.Auth.JWTSecretFile != "":
+			errs = append(errs, errors.New("auth.jwt_secret y auth.jwt_secret_file no se pueden usar juntos"))
+		case c.Auth.JWTSecret != "":
+			if err := auth.CheckSecret([]byte(c.Auth.JWTSecret)); err != nil {
+				errs = append(errs, fmt.Errorf("auth.jwt_secret: %w", err))
+			}
+		}
+	default:
+		errs = append(errs, fmt.Errorf("auth.jwt_alg desconocido: %q (RS256, EdDSA o HS256)", c.Auth.JWTAlg))
+	}
+	if c.Auth.RevocationsInterval <= 0 {
+		errs = append(errs, errors.New("auth.revocations_interval debe ser mayor que 0"))
+	}
+	if len(c.Auth.RevocationsToken) < auth.MinSecretLength {
+		errs = append(errs, fmt.Errorf("auth.revocations_token (REVOCATIONS_TOKEN) es obligatorio y de al menos %d bytes", auth.MinSecretLength))
+	}
+	for name, l := range c.RateLimits {
+		if err := l.Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("rate_limits.%s: %w", name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// LoadSecret devuelve el secreto HS256 (de al menos auth.MinSecretLength
+// bytes), leyendo JWTSecretFile si se configuró.
+func (a Auth) LoadSecret() ([]byte, error) {
+	secret := a.JWTSecret
+	if a.JWTSecretFile != "" {
+		var err error
+		if secret, err = configpkg.ReadSecretFile(a.JWTSecretFile); err != nil {
+			return nil, fmt.Errorf("auth.jwt_secret_file: %w", err)
+		}
+	}
+	if err := auth.CheckSecret([]byte(secret)); err != nil {
+		return nil, err
+	}
+	return []byte(secret), nil
+}
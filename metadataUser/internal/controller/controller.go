@@ -3,42 +3,93 @@ package metadataUser
 import (
 	"context"
 	"errors"
+	"time"
 
+	"proyecto/metadataUser/internal/repository"
 	model "proyecto/metadataUser/pkg"
 )
 
-//error personal 
-var ErrNotFound = errors.New("Not found")
+// error personal (son los mismos del repositorio para que el handler los distinga)
+var (
+	ErrNotFound        = repository.ErrNotFound
+	ErrAlreadyExists   = repository.ErrAlreadyExists
+	ErrVersionMismatch = repository.ErrVersionMismatch
+)
+
+// AnyVersion se pasa como versión esperada cuando el cliente no mandó If-Match.
+const AnyVersion int64 = 0
 
-//Tipos de request (Solo Get)
-type metadataUserRepository interface {
+// Cuántas veces reintenta Patch si otro escritor gana la carrera entre leer y escribir.
+const maxPatchAttempts = 3
+
+// Operaciones que necesita el controlador sobre el almacenamiento
+type MetadataUserRepository interface {
 	Get(ctx context.Context, id string) (*model.MetadataUser, error)
-	Put(_ context.Context, metadata *model.MetadataUser) (error)
+	Create(ctx context.Context, metadata *model.MetadataUser) error
+	Update(ctx context.Context, metadata *model.MetadataUser, expectedVersion int64) error
+	Delete(ctx context.Context, id string, expectedVersion int64) error
 }
 
 type Controller struct {
-	repo metadataUserRepository
+	repo MetadataUserRepository
 }
 
-//Constructor
-func New(repo metadataUserRepository) *Controller {
+// Constructor
+func New(repo MetadataUserRepository) *Controller {
 	return &Controller{repo}
 }
 
-
 func (c *Controller) Get(ctx context.Context, id string) (*model.MetadataUser, error) {
-	res, err := c.repo.Get(ctx, id)
-	if err != nil {
-		return nil, ErrNotFound
-	}
-	return res, err
+	return c.repo.Get(ctx, id)
 }
 
+// Create guarda un registro nuevo; falla con ErrAlreadyExists si el email ya existe.
+func (c *Controller) Create(ctx context.Context, metadata *model.MetadataUser) (*model.MetadataUser, error) {
+	metadata.LastUpdated = time.Now().Format(time.RFC3339)
+	if err := c.repo.Create(ctx, metadata); err != nil {
+		return nil, err
+	}
+	return metadata, nil
+}
 
-func (c *Controller) Put(ctx context.Context, metadata *model.MetadataUser) (*model.MetadataUser, error) {
-	err := c.repo.Put(ctx, metadata)
-	if err != nil {
+// Replace reemplaza el registro completo si sigue en la versión ifMatch
+// (AnyVersion para no comprobarla); falla con ErrNotFound si no existe.
+func (c *Controller) Replace(ctx context.Context, metadata *model.MetadataUser, ifMatch int64) (*model.MetadataUser, error) {
+	metadata.LastUpdated = time.Now().Format(time.RFC3339)
+	if err := c.repo.Update(ctx, metadata, ifMatch); err != nil {
 		return nil, err
 	}
 	return metadata, nil
-}
\ No newline at end of file
+}
+
+// Patch actualiza solo los campos presentes en patch. La escritura se hace
+// contra la versión leída, así nunca se pisa un cambio concurrente: sin
+// If-Match se reintenta con la versión nueva, con If-Match se devuelve ErrVersionMismatch.
+func (c *Controller) Patch(ctx context.Context, id string, patch model.MetadataUserPatch, ifMatch int64) (*model.MetadataUser, error) {
+	var err error
+	for attempt := 0; attempt < maxPatchAttempts; attempt++ {
+		var current *model.MetadataUser
+		current, err = c.repo.Get(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if ifMatch != AnyVersion && current.Version != ifMatch {
+			return nil, ErrVersionMismatch
+		}
+
+		updated := *current
+		patch.Apply(&updated)
+		var res *model.MetadataUser
+		res, err = c.Replace(ctx, &updated, current.Version)
+		if errors.Is(err, ErrVersionMismatch) && ifMatch == AnyVersion {
+			continue
+		}
+		return res, err
+	}
+	return nil, err
+}
+
+// Delete borra el registro si sigue en la versión ifMatch (AnyVersion para no comprobarla).
+func (c *Controller) Delete(ctx context.Context, id string, ifMatch int64) error {
+	return c.repo.Delete(ctx, id, ifMatch)
+}
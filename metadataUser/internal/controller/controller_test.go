@@ -0,0 +1,76 @@
+package metadataUser
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"proyecto/metadataUser/internal/repository/memory"
+	model "proyecto/metadataUser/pkg"
+)
+
+// racingRepo es el repositorio en memoria con otro escritor que cambia el
+// registro entre la lectura y la escritura de Patch las primeras races veces.
+type racingRepo struct {
+	*memory.Repository
+	races int
+}
+
+func (r *racingRepo) Update(ctx context.Context, m *model.MetadataUser, expectedVersion int64) error {
+	if r.races > 0 {
+		r.races--
+		current, err := r.Repository.Get(ctx, m.Email)
+		if err != nil {
+			return err
+		}
+		current.AvatarURL = "de otro escritor"
+		if err := r.Repository.Update(ctx, current, current.Version); err != nil {
+			return err
+		}
+	}
+	return r.Repository.Update(ctx, m, expectedVersion)
+}
+
+func newRacingController(t *testing.T, races int) (*Controller, *racingRepo) {
+	t.Helper()
+	repo := &racingRepo{Repository: memory.New()}
+	if err := repo.Create(context.Background(), &model.MetadataUser{Email: "ana@example.com"}); err != nil {
+		t.Fatal(err)
+	}
+	repo.races = races
+	return New(repo), repo
+}
+
+func TestPatchRetriesLostRace(t *testing.T) {
+	ctrl, _ := newRacingController(t, 1)
+	name := "Ana"
+
+	m, err := ctrl.Patch(context.Background(), "ana@example.com", model.MetadataUserPatch{FullName: &name}, AnyVersion)
+	if err != nil {
+		t.Fatalf("Patch = %v", err)
+	}
+	// Se reintentó sobre la versión del otro escritor, sin pisar su cambio.
+	if m.FullName != "Ana" || m.AvatarURL != "de otro escritor" || m.Version != 3 {
+		t.Errorf("Patch = %+v, quería los dos cambios en la versión 3", m)
+	}
+}
+
+func TestPatchGivesUp(t *testing.T) {
+	ctrl, _ := newRacingController(t, maxPatchAttempts)
+	name := "Ana"
+	if _, err := ctrl.Patch(context.Background(), "ana@example.com", model.MetadataUserPatch{FullName: &name}, AnyVersion); !errors.Is(err, ErrVersionMismatch) {
+		t.Errorf("Patch tras %d carreras perdidas = %v, quería ErrVersionMismatch", maxPatchAttempts, err)
+	}
+}
+
+func TestPatchIfMatchDoesNotRetry(t *testing.T) {
+	ctrl, repo := newRacingController(t, 1)
+	name := "Ana"
+	if _, err := ctrl.Patch(context.Background(), "ana@example.com", model.MetadataUserPatch{FullName: &name}, 1); !errors.Is(err, ErrVersionMismatch) {
+		t.Errorf("Patch con If-Match y una carrera perdida = %v, quería ErrVersionMismatch", err)
+	}
+	got, _ := repo.Get(context.Background(), "ana@example.com")
+	if got.FullName != "" {
+		t.Errorf("se escribió %q con una versión vieja", got.FullName)
+	}
+}
@@ -0,0 +1,66 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	metadataUser "proyecto/metadataUser/internal/controller"
+)
+
+// etag arma el ETag (fuerte) de una versión del registro.
+func etag(version int64) string {
+	return `"` + strconv.FormatInt(version, 10) + `"`
+}
+
+// ifMatchVersion lee If-Match y devuelve la versión que debe tener el registro
+// para escribir. Sin header o con "*" no se comprueba (AnyVersion). Un valor que
+// no es un ETag nuestro nunca puede coincidir, así que se informa con ok=false.
+func ifMatchVersion(req *http.Request) (version int64, ok bool) {
+	v := strings.TrimSpace(req.Header.Get("If-Match"))
+	if v == "" || v == "*" {
+		return metadataUser.AnyVersion, true
+	}
+	unquoted, found := strings.CutPrefix(v, `"`)
+	if !found {
+		return 0, false
+	}
+	unquoted, found = strings.CutSuffix(unquoted, `"`)
+	if !found {
+		return 0, false
+	}
+	version, err := strconv.ParseInt(unquoted, 10, 64)
+	if err != nil || version <= 0 {
+		return 0, false
+	}
+	return version, true
+}
+
+// requireIfMatch es ifMatchVersion para PUT y PATCH, donde If-Match es
+// obligatorio: sin él responde 428 para que nadie pise cambios sin querer
+// ("*" sigue aceptando cualquier versión). Si devuelve false ya respondió.
+func requireIfMatch(w http.ResponseWriter, req *http.Request) (version int64, ok bool) {
+	if strings.TrimSpace(req.Header.Get("If-Match")) == "" {
+		http.Error(w, "Falta If-Match: lee el registro y manda su ETag (o * para cualquier versión).", http.StatusPreconditionRequired)
+		return 0, false
+	}
+	if version, ok = ifMatchVersion(req); !ok {
+		w.WriteHeader(http.StatusPreconditionFailed)
+	}
+	return version, ok
+}
+
+// notModified indica si If-None-Match coincide con el ETag actual (respuesta 304).
+func notModified(req *http.Request, current string) bool {
+	header := req.Header.Get("If-None-Match")
+	if header == "" {
+		return false
+	}
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == current {
+			return true
+		}
+	}
+	return false
+}
@@ -5,11 +5,11 @@ import (
 	"errors"
 	"log"
 	"net/http"
-	"time"
 
 	"proyecto/metadataUser/internal/controller"
 	"proyecto/metadataUser/internal/repository"
 	model "proyecto/metadataUser/pkg"
+	"proyecto/pkg/auth"
 )
 
 //El puntero al controlador 
@@ -30,6 +30,10 @@ func (h * Handler) GetMetadatUser(w http.ResponseWriter, req *http.Request) {
         w.WriteHeader(http.StatusBadRequest)
 		return
     }
+    //Solo el dueño (o quien tenga metadata:read:any) puede ver sus datos
+    if !auth.RequireEmailAccess(w, req, Email, auth.PermMetadataReadSelf, auth.PermMetadataReadAny) {
+        return
+    }
 
     ctx := req.Context()
     //Obtiene los datos o el error 
@@ -42,42 +46,177 @@ func (h * Handler) GetMetadatUser(w http.ResponseWriter, req *http.Request) {
         w.WriteHeader(http.StatusInternalServerError)
         return
     }
+    // Con If-None-Match igual a la versión actual no hace falta mandar el cuerpo
+    tag := etag(m.Version)
+    w.Header().Set("ETag", tag)
+    if notModified(req, tag) {
+        w.WriteHeader(http.StatusNotModified)
+        return
+    }
     // codifica y manda la espuesta 
     if err := json.NewEncoder(w).Encode(m); err != nil {
-        log.Printf("Response error: v%\n", err)
+        log.Printf("Response error: %v\n", err)
     }
 }
 
-// Es el handler 
+// Es el handler para crear (falla con 409 si el email ya existe)
 func (h * Handler) CreateMetadatUser(w http.ResponseWriter, req *http.Request) {
 	//Obtener el Id 
-	user := model.MetadataUser{
-		Email:       req.FormValue("email"),
-		FullName:    req.FormValue("full_name"),
-		AvatarURL:   req.FormValue("avatar_url"),
-		PhoneNumber: req.FormValue("phone_number"),
-		BirthDate:   req.FormValue("birth_date"),
-		LastUpdated: time.Now().Format(time.RFC3339), // Fecha actual en formato ISO
-	}
+	user := metadataFromForm(req)
 	//Verifica que no venga vacio 
 	if user.Email == "" {
 		http.Error(w, "El campo 'email' es obligatorio.", http.StatusBadRequest)
 		return
 	}
+	//Solo el dueño (o quien tenga metadata:write:any) puede escribir sus datos
+	if !auth.RequireEmailAccess(w, req, user.Email, auth.PermMetadataWriteSelf, auth.PermMetadataWriteAny) {
+		return
+	}
 
 	ctx := req.Context()
 	//Obtiene los datos o el error 
-	m, err := h.ctrl.Put(ctx, &user)
+	m, err := h.ctrl.Create(ctx, &user)
+	if err != nil && errors.Is(err, repository.ErrAlreadyExists) {
+		http.Error(w, "Ya existen metadatos para ese email.", http.StatusConflict)
+		return
+	} else if err != nil {
+		log.Printf("Repository error: %v\n", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("ETag", etag(m.Version))
+	writeJSON(w, http.StatusCreated, m)
+}
+
+// Reemplaza el registro completo (los campos que no vengan quedan vacíos)
+func (h *Handler) ReplaceMetadatUser(w http.ResponseWriter, req *http.Request) {
+	user := metadataFromForm(req)
+	if user.Email == "" {
+		http.Error(w, "El campo 'email' es obligatorio.", http.StatusBadRequest)
+		return
+	}
+	if !auth.RequireEmailAccess(w, req, user.Email, auth.PermMetadataWriteSelf, auth.PermMetadataWriteAny) {
+		return
+	}
+
+	ifMatch, ok := requireIfMatch(w, req)
+	if !ok {
+		return
+	}
+
+	m, err := h.ctrl.Replace(req.Context(), &user, ifMatch)
 	if err != nil && errors.Is(err, repository.ErrNotFound) {
 		w.WriteHeader(http.StatusNotFound)
 		return
+	} else if err != nil && errors.Is(err, repository.ErrVersionMismatch) {
+		http.Error(w, "El registro cambió; vuelve a leerlo (If-Match no coincide).", http.StatusPreconditionFailed)
+		return
 	} else if err != nil {
 		log.Printf("Repository error: %v\n", err)
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
-	// codifica y manda la espuesta 
-	if err := json.NewEncoder(w).Encode(m); err != nil {
-		log.Printf("Response error: v%\n", err)
+	w.Header().Set("ETag", etag(m.Version))
+	writeJSON(w, http.StatusOK, m)
+}
+
+// Actualización parcial: solo se modifican los campos que vienen en la petición
+func (h *Handler) PatchMetadatUser(w http.ResponseWriter, req *http.Request) {
+	if err := req.ParseForm(); err != nil {
+		http.Error(w, "Formulario inválido", http.StatusBadRequest)
+		return
+	}
+	email := req.Form.Get("email")
+	if email == "" {
+		http.Error(w, "El campo 'email' es obligatorio.", http.StatusBadRequest)
+		return
+	}
+	if !auth.RequireEmailAccess(w, req, email, auth.PermMetadataWriteSelf, auth.PermMetadataWriteAny) {
+		return
 	}
-}
\ No newline at end of file
+
+	field := func(name string) *string {
+		if _, ok := req.Form[name]; !ok {
+			return nil
+		}
+		v := req.Form.Get(name)
+		return &v
+	}
+	patch := model.MetadataUserPatch{
+		FullName:    field("full_name"),
+		AvatarURL:   field("avatar_url"),
+		PhoneNumber: field("phone_number"),
+		BirthDate:   field("birth_date"),
+	}
+
+	ifMatch, ok := requireIfMatch(w, req)
+	if !ok {
+		return
+	}
+
+	m, err := h.ctrl.Patch(req.Context(), email, patch, ifMatch)
+	if err != nil && errors.Is(err, repository.ErrNotFound) {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	} else if err != nil && errors.Is(err, repository.ErrVersionMismatch) {
+		http.Error(w, "El registro cambió; vuelve a leerlo (If-Match no coincide).", http.StatusPreconditionFailed)
+		return
+	} else if err != nil {
+		log.Printf("Repository error: %v\n", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("ETag", etag(m.Version))
+	writeJSON(w, http.StatusOK, m)
+}
+
+// Borra los metadatos de un email
+func (h *Handler) DeleteMetadatUser(w http.ResponseWriter, req *http.Request) {
+	email := req.FormValue("email")
+	if email == "" {
+		http.Error(w, "El campo 'email' es obligatorio.", http.StatusBadRequest)
+		return
+	}
+	if !auth.RequireEmailAccess(w, req, email, auth.PermMetadataWriteSelf, auth.PermMetadataWriteAny) {
+		return
+	}
+
+	ifMatch, ok := ifMatchVersion(req)
+	if !ok {
+		w.WriteHeader(http.StatusPreconditionFailed)
+		return
+	}
+
+	err := h.ctrl.Delete(req.Context(), email, ifMatch)
+	if err != nil && errors.Is(err, repository.ErrNotFound) {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	} else if err != nil && errors.Is(err, repository.ErrVersionMismatch) {
+		http.Error(w, "El registro cambió; vuelve a leerlo (If-Match no coincide).", http.StatusPreconditionFailed)
+		return
+	} else if err != nil {
+		log.Printf("Repository error: %v\n", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func metadataFromForm(req *http.Request) model.MetadataUser {
+	return model.MetadataUser{
+		Email:       req.FormValue("email"),
+		FullName:    req.FormValue("full_name"),
+		AvatarURL:   req.FormValue("avatar_url"),
+		PhoneNumber: req.FormValue("phone_number"),
+		BirthDate:   req.FormValue("birth_date"),
+	}
+}
+
+// codifica y manda la respuesta con el status dado
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("Response error: %v\n", err)
+	}
+}
@@ -0,0 +1,153 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	metadataUser "proyecto/metadataUser/internal/controller"
+	"proyecto/metadataUser/internal/repository/memory"
+	model "proyecto/metadataUser/pkg"
+	"proyecto/pkg/auth"
+)
+
+// ana es el token de una usuaria normal: solo puede tocar sus propios datos.
+var ana = &auth.AccessClaims{
+	Email:       "ana@example.com",
+	Permissions: []string{auth.PermMetadataReadSelf, auth.PermMetadataWriteSelf},
+}
+
+func newTestHandler() *Handler {
+	return New(metadataUser.New(memory.New()))
+}
+
+// serve llama a handle como si la petición viniera detrás de auth.Middleware
+// con el token de ana.
+func serve(handle http.HandlerFunc, method string, form url.Values, header http.Header) *httptest.ResponseRecorder {
+	target := "/MetadataUser"
+	var body *strings.Reader
+	if method == http.MethodGet || method == http.MethodDelete {
+		target += "?" + form.Encode()
+		body = strings.NewReader("")
+	} else {
+		body = strings.NewReader(form.Encode())
+	}
+	req := httptest.NewRequest(method, target, body)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	req = req.WithContext(auth.WithClaims(req.Context(), ana))
+	rec := httptest.NewRecorder()
+	handle(rec, req)
+	return rec
+}
+
+func decode(t *testing.T, rec *httptest.ResponseRecorder) *model.MetadataUser {
+	t.Helper()
+	var m model.MetadataUser
+	if err := json.NewDecoder(rec.Body).Decode(&m); err != nil {
+		t.Fatalf("respuesta %d %q: %v", rec.Code, rec.Body.String(), err)
+	}
+	return &m
+}
+
+func TestCreateAndGet(t *testing.T) {
+	h := newTestHandler()
+	form := url.Values{"email": {"ana@example.com"}, "full_name": {"Ana"}}
+
+	rec := serve(h.CreateMetadatUser, http.MethodPost, form, nil)
+	if rec.Code != http.StatusCreated || rec.Header().Get("ETag") != `"1"` {
+		t.Fatalf("crear = %d, ETag %q; quería 201 con ETag \"1\"", rec.Code, rec.Header().Get("ETag"))
+	}
+	if m := decode(t, rec); m.FullName != "Ana" || m.Version != 1 {
+		t.Errorf("creado = %+v", m)
+	}
+	if rec := serve(h.CreateMetadatUser, http.MethodPost, form, nil); rec.Code != http.StatusConflict {
+		t.Errorf("crear de nuevo = %d, quería 409", rec.Code)
+	}
+
+	query := url.Values{"email": {"ana@example.com"}}
+	rec = serve(h.GetMetadatUser, http.MethodGet, query, nil)
+	if rec.Code != http.StatusOK || rec.Header().Get("ETag") != `"1"` || decode(t, rec).FullName != "Ana" {
+		t.Errorf("leer = %d, ETag %q", rec.Code, rec.Header().Get("ETag"))
+	}
+	if rec := serve(h.GetMetadatUser, http.MethodGet, url.Values{"email": {"nadie@example.com"}}, nil); rec.Code != http.StatusForbidden {
+		t.Errorf("leer datos ajenos = %d, quería 403", rec.Code)
+	}
+}
+
+func TestGetNotFound(t *testing.T) {
+	h := newTestHandler()
+	if rec := serve(h.GetMetadatUser, http.MethodGet, url.Values{"email": {"ana@example.com"}}, nil); rec.Code != http.StatusNotFound {
+		t.Errorf("leer sin datos = %d, quería 404", rec.Code)
+	}
+	if rec := serve(h.GetMetadatUser, http.MethodGet, url.Values{}, nil); rec.Code != http.StatusBadRequest {
+		t.Errorf("leer sin email = %d, quería 400", rec.Code)
+	}
+}
+
+func TestGetNotModified(t *testing.T) {
+	h := newTestHandler()
+	serve(h.CreateMetadatUser, http.MethodPost, url.Values{"email": {"ana@example.com"}}, nil)
+	query := url.Values{"email": {"ana@example.com"}}
+
+	for header, want := range map[string]int{
+		`"1"`:         http.StatusNotModified,
+		`W/"1"`:       http.StatusNotModified,
+		`"7", "1"`:    http.StatusNotModified,
+		`*`:           http.StatusNotModified,
+		`"2"`:         http.StatusOK,
+		`"no-es-mío"`: http.StatusOK,
+	} {
+		rec := serve(h.GetMetadatUser, http.MethodGet, query, http.Header{"If-None-Match": {header}})
+		if rec.Code != want {
+			t.Errorf("If-None-Match %s = %d, quería %d", header, rec.Code, want)
+		}
+		if want == http.StatusNotModified && rec.Body.Len() != 0 {
+			t.Errorf("304 con cuerpo %q", rec.Body.String())
+		}
+	}
+}
+
+func TestWritesRequireIfMatch(t *testing.T) {
+	h := newTestHandler()
+	serve(h.CreateMetadatUser, http.MethodPost, url.Values{"email": {"ana@example.com"}}, nil)
+	form := url.Values{"email": {"ana@example.com"}, "full_name": {"Ana María"}}
+
+	for name, handle := range map[string]http.HandlerFunc{
+		http.MethodPut:   h.ReplaceMetadatUser,
+		http.MethodPatch: h.PatchMetadatUser,
+	} {
+		if rec := serve(handle, name, form, nil); rec.Code != http.StatusPreconditionRequired {
+			t.Errorf("%s sin If-Match = %d, quería 428", name, rec.Code)
+		}
+		for _, stale := range []string{`"9"`, `1`, `"x"`} {
+			if rec := serve(handle, name, form, http.Header{"If-Match": {stale}}); rec.Code != http.StatusPreconditionFailed {
+				t.Errorf("%s con If-Match %s = %d, quería 412", name, stale, rec.Code)
+			}
+		}
+	}
+
+	// Con el ETag vigente se escribe y la versión avanza.
+	rec := serve(h.ReplaceMetadatUser, http.MethodPut, form, http.Header{"If-Match": {`"1"`}})
+	if rec.Code != http.StatusOK || rec.Header().Get("ETag") != `"2"` {
+		t.Fatalf("PUT con If-Match \"1\" = %d, ETag %q", rec.Code, rec.Header().Get("ETag"))
+	}
+	// La versión leída antes ya no vale.
+	patch := url.Values{"email": {"ana@example.com"}, "phone_number": {"555"}}
+	if rec := serve(h.PatchMetadatUser, http.MethodPatch, patch, http.Header{"If-Match": {`"1"`}}); rec.Code != http.StatusPreconditionFailed {
+		t.Errorf("PATCH con la versión vieja = %d, quería 412", rec.Code)
+	}
+	// "*" acepta cualquier versión.
+	rec = serve(h.PatchMetadatUser, http.MethodPatch, patch, http.Header{"If-Match": {"*"}})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("PATCH con If-Match * = %d", rec.Code)
+	}
+	if m := decode(t, rec); m.FullName != "Ana María" || m.PhoneNumber != "555" || m.Version != 3 {
+		t.Errorf("tras el PATCH = %+v", m)
+	}
+}
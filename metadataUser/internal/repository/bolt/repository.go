@@ -0,0 +1,121 @@
+package bolt
+
+import (
+	"context"
+
+	bolt "go.etcd.io/bbolt"
+
+	"proyecto/metadataUser/internal/repository"
+	model "proyecto/metadataUser/pkg"
+	"proyecto/pkg/idempotency"
+	"proyecto/pkg/storage/boltdb"
+)
+
+const (
+	metadataBucket    = "metadata_users"
+	idempotencyBucket = "idempotency_keys"
+)
+
+var migrations = []boltdb.Migration{
+	{Version: 1, Name: "create metadata_users", Up: boltdb.CreateBuckets(metadataBucket)},
+	{Version: 2, Name: "create idempotency_keys", Up: boltdb.CreateBuckets(idempotencyBucket)},
+}
+
+// Repository guarda los metadatos de usuario en un archivo BoltDB. Las
+// escrituras de Bolt son serializadas, así que el compare-and-swap de versión
+// dentro de una transacción es atómico.
+type Repository struct {
+	db *bolt.DB
+}
+
+// Open abre la base en path y aplica las migraciones pendientes.
+func Open(path string) (*Repository, error) {
+	db, err := boltdb.Open(path, migrations)
+	if err != nil {
+		return nil, err
+	}
+	return &Repository{db: db}, nil
+}
+
+func (r *Repository) Close() error {
+	return r.db.Close()
+}
+
+// IdempotencyStore guarda las claves de idempotencia en la misma base.
+func (r *Repository) IdempotencyStore() *idempotency.BoltStore {
+	return idempotency.NewBoltStore(r.db, idempotencyBucket)
+}
+
+// Ping comprueba que la base sigue abierta (check de salud).
+func (r *Repository) Ping(_ context.Context) error {
+	return r.db.View(func(*bolt.Tx) error { return nil })
+}
+
+func (r *Repository) Get(_ context.Context, id string) (*model.MetadataUser, error) {
+	var m model.MetadataUser
+	err := r.db.View(func(tx *bolt.Tx) error {
+		found, err := boltdb.GetJSON(tx.Bucket([]byte(metadataBucket)), id, &m)
+		if err != nil {
+			return err
+		}
+		if !found {
+			return repository.ErrNotFound
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+func (r *Repository) Create(_ context.Context, metadata *model.MetadataUser) error {
+	return r.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(metadataBucket))
+		if b.Get([]byte(metadata.Email)) != nil {
+			return repository.ErrAlreadyExists
+		}
+		metadata.Version = 1
+		return boltdb.PutJSON(b, metadata.Email, metadata)
+	})
+}
+
+// Update reemplaza el registro si su versión sigue siendo expectedVersion (0 = cualquiera).
+func (r *Repository) Update(_ context.Context, metadata *model.MetadataUser, expectedVersion int64) error {
+	return r.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(metadataBucket))
+		current, err := get(b, metadata.Email, expectedVersion)
+		if err != nil {
+			return err
+		}
+		metadata.Version = current.Version + 1
+		return boltdb.PutJSON(b, metadata.Email, metadata)
+	})
+}
+
+// Delete borra el registro si su versión sigue siendo expectedVersion (0 = cualquiera).
+func (r *Repository) Delete(_ context.Context, id string, expectedVersion int64) error {
+	return r.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(metadataBucket))
+		if _, err := get(b, id, expectedVersion); err != nil {
+			return err
+		}
+		return b.Delete([]byte(id))
+	})
+}
+
+// get lee el registro dentro de la transacción y comprueba la versión esperada.
+func get(b *bolt.Bucket, id string, expectedVersion int64) (*model.MetadataUser, error) {
+	var current model.MetadataUser
+	found, err := boltdb.GetJSON(b, id, &current)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, repository.ErrNotFound
+	}
+	if expectedVersion != 0 && current.Version != expectedVersion {
+		return nil, repository.ErrVersionMismatch
+	}
+	return &current, nil
+}
@@ -0,0 +1,95 @@
+package bolt
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"proyecto/metadataUser/internal/repository"
+	model "proyecto/metadataUser/pkg"
+)
+
+func openTest(t *testing.T) *Repository {
+	t.Helper()
+	r, err := Open(filepath.Join(t.TempDir(), "metadata.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { r.Close() })
+	return r
+}
+
+func TestCreateGet(t *testing.T) {
+	r := openTest(t)
+	ctx := context.Background()
+
+	if _, err := r.Get(ctx, "ana@example.com"); !errors.Is(err, repository.ErrNotFound) {
+		t.Fatalf("Get antes de crear = %v, quería ErrNotFound", err)
+	}
+	m := &model.MetadataUser{Email: "ana@example.com", FullName: "Ana"}
+	if err := r.Create(ctx, m); err != nil {
+		t.Fatal(err)
+	}
+	if m.Version != 1 {
+		t.Errorf("versión tras Create = %d, quería 1", m.Version)
+	}
+	if err := r.Create(ctx, &model.MetadataUser{Email: "ana@example.com"}); !errors.Is(err, repository.ErrAlreadyExists) {
+		t.Errorf("Create repetido = %v, quería ErrAlreadyExists", err)
+	}
+
+	got, err := r.Get(ctx, "ana@example.com")
+	if err != nil || got.FullName != "Ana" || got.Version != 1 {
+		t.Errorf("Get = %+v, %v", got, err)
+	}
+}
+
+func TestUpdateVersion(t *testing.T) {
+	r := openTest(t)
+	ctx := context.Background()
+	if err := r.Create(ctx, &model.MetadataUser{Email: "ana@example.com", FullName: "Ana"}); err != nil {
+		t.Fatal(err)
+	}
+
+	m := &model.MetadataUser{Email: "ana@example.com", FullName: "Ana María"}
+	if err := r.Update(ctx, m, 1); err != nil {
+		t.Fatal(err)
+	}
+	if m.Version != 2 {
+		t.Errorf("versión tras Update = %d, quería 2", m.Version)
+	}
+	// Otro cliente que leyó la versión 1 no puede pisar el cambio.
+	if err := r.Update(ctx, &model.MetadataUser{Email: "ana@example.com"}, 1); !errors.Is(err, repository.ErrVersionMismatch) {
+		t.Errorf("Update con versión vieja = %v, quería ErrVersionMismatch", err)
+	}
+	// 0 = cualquier versión.
+	if err := r.Update(ctx, &model.MetadataUser{Email: "ana@example.com", FullName: "A"}, 0); err != nil {
+		t.Errorf("Update sin versión = %v", err)
+	}
+	if err := r.Update(ctx, &model.MetadataUser{Email: "nadie@example.com"}, 0); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("Update de uno que no existe = %v, quería ErrNotFound", err)
+	}
+
+	got, _ := r.Get(ctx, "ana@example.com")
+	if got.FullName != "A" || got.Version != 3 {
+		t.Errorf("Get = %+v", got)
+	}
+}
+
+func TestDeleteVersion(t *testing.T) {
+	r := openTest(t)
+	ctx := context.Background()
+	if err := r.Create(ctx, &model.MetadataUser{Email: "ana@example.com"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := r.Delete(ctx, "ana@example.com", 7); !errors.Is(err, repository.ErrVersionMismatch) {
+		t.Errorf("Delete con otra versión = %v, quería ErrVersionMismatch", err)
+	}
+	if err := r.Delete(ctx, "ana@example.com", 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Delete(ctx, "ana@example.com", 0); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("Delete repetido = %v, quería ErrNotFound", err)
+	}
+}
@@ -2,4 +2,10 @@ package repository
 
 import "errors"
 
-var ErrNotFound = errors.New("Not found")
\ No newline at end of file
+var ErrNotFound = errors.New("Not found")
+
+// ErrAlreadyExists se devuelve al crear un registro con un email que ya existe.
+var ErrAlreadyExists = errors.New("Already exists")
+
+// ErrVersionMismatch se devuelve cuando la versión esperada no coincide con la guardada.
+var ErrVersionMismatch = errors.New("Version mismatch")
@@ -23,6 +23,7 @@ func New() *Repository {
 				PhoneNumber: "",
 				BirthDate:   "",
 				LastUpdated: "",
+				Version:     1,
 			},
 		},
 	}
@@ -38,12 +39,52 @@ func (r *Repository) Get(_ context.Context, id string) (*model.MetadataUser, err
 		return nil, repository.ErrNotFound
 	}
 
-	return m, nil
+	// Se devuelve una copia para que nadie modifique el mapa sin el lock
+	cp := *m
+	return &cp, nil
 }
 
-func (r *Repository) Put(_ context.Context, metadata *model.MetadataUser) error {
+func (r *Repository) Create(_ context.Context, metadata *model.MetadataUser) error {
 	r.Lock()
 	defer r.Unlock()
-	r.data[metadata.Email] = metadata
+	if _, ok := r.data[metadata.Email]; ok {
+		return repository.ErrAlreadyExists
+	}
+	metadata.Version = 1
+	cp := *metadata
+	r.data[metadata.Email] = &cp
+	return nil
+}
+
+// Update reemplaza el registro si su versión sigue siendo expectedVersion
+// (compare-and-swap); expectedVersion 0 acepta cualquier versión.
+func (r *Repository) Update(_ context.Context, metadata *model.MetadataUser, expectedVersion int64) error {
+	r.Lock()
+	defer r.Unlock()
+	current, ok := r.data[metadata.Email]
+	if !ok {
+		return repository.ErrNotFound
+	}
+	if expectedVersion != 0 && current.Version != expectedVersion {
+		return repository.ErrVersionMismatch
+	}
+	metadata.Version = current.Version + 1
+	cp := *metadata
+	r.data[metadata.Email] = &cp
 	return nil
-}
\ No newline at end of file
+}
+
+// Delete borra el registro si su versión sigue siendo expectedVersion (0 = cualquiera).
+func (r *Repository) Delete(_ context.Context, id string, expectedVersion int64) error {
+	r.Lock()
+	defer r.Unlock()
+	current, ok := r.data[id]
+	if !ok {
+		return repository.ErrNotFound
+	}
+	if expectedVersion != 0 && current.Version != expectedVersion {
+		return repository.ErrVersionMismatch
+	}
+	delete(r.data, id)
+	return nil
+}
@@ -0,0 +1,132 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"proyecto/metadataUser/internal/repository"
+	model "proyecto/metadataUser/pkg"
+	"proyecto/pkg/idempotency"
+	"proyecto/pkg/storage/postgres"
+)
+
+var migrations = []postgres.Migration{
+	{Version: 1, Name: "create metadata_users", SQL: `
+		CREATE TABLE metadata_users (
+			email        TEXT PRIMARY KEY,
+			full_name    TEXT NOT NULL DEFAULT '',
+			avatar_url   TEXT NOT NULL DEFAULT '',
+			phone_number TEXT NOT NULL DEFAULT '',
+			birth_date   TEXT NOT NULL DEFAULT '',
+			last_updated TEXT NOT NULL DEFAULT '',
+			version      BIGINT NOT NULL DEFAULT 1
+		);
+	`},
+	{Version: 2, Name: "create idempotency_keys", SQL: idempotency.PostgresSchema},
+}
+
+// Repository guarda los metadatos de usuario en PostgreSQL. El control de
+// versión se hace en el propio UPDATE/DELETE (WHERE version = ...), así que es atómico.
+type Repository struct {
+	db *sql.DB
+}
+
+// Open conecta a dsn y aplica las migraciones pendientes.
+func Open(ctx context.Context, dsn string) (*Repository, error) {
+	db, err := postgres.Open(ctx, dsn, migrations)
+	if err != nil {
+		return nil, err
+	}
+	return &Repository{db: db}, nil
+}
+
+func (r *Repository) Close() error {
+	return r.db.Close()
+}
+
+// IdempotencyStore guarda las claves de idempotencia en la misma base.
+func (r *Repository) IdempotencyStore() *idempotency.PostgresStore {
+	return idempotency.NewPostgresStore(r.db)
+}
+
+// Ping comprueba la conexión con la base (check de salud).
+func (r *Repository) Ping(ctx context.Context) error {
+	return r.db.PingContext(ctx)
+}
+
+func (r *Repository) Get(ctx context.Context, id string) (*model.MetadataUser, error) {
+	var m model.MetadataUser
+	err := r.db.QueryRowContext(ctx, `
+		SELECT email, full_name, avatar_url, phone_number, birth_date, last_updated, version
+		FROM metadata_users WHERE email = $1`, id,
+	).Scan(&m.Email, &m.FullName, &m.AvatarURL, &m.PhoneNumber, &m.BirthDate, &m.LastUpdated, &m.Version)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, repository.ErrNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+func (r *Repository) Create(ctx context.Context, metadata *model.MetadataUser) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO metadata_users (email, full_name, avatar_url, phone_number, birth_date, last_updated, version)
+		VALUES ($1, $2, $3, $4, $5, $6, 1)`,
+		metadata.Email, metadata.FullName, metadata.AvatarURL, metadata.PhoneNumber, metadata.BirthDate, metadata.LastUpdated,
+	)
+	if postgres.IsUniqueViolation(err) {
+		return repository.ErrAlreadyExists
+	} else if err != nil {
+		return err
+	}
+	metadata.Version = 1
+	return nil
+}
+
+// Update reemplaza el registro si su versión sigue siendo expectedVersion (0 = cualquiera).
+func (r *Repository) Update(ctx context.Context, metadata *model.MetadataUser, expectedVersion int64) error {
+	var version int64
+	err := r.db.QueryRowContext(ctx, `
+		UPDATE metadata_users SET
+			full_name = $2, avatar_url = $3, phone_number = $4, birth_date = $5, last_updated = $6,
+			version = version + 1
+		WHERE email = $1 AND ($7::BIGINT = 0 OR version = $7::BIGINT)
+		RETURNING version`,
+		metadata.Email, metadata.FullName, metadata.AvatarURL, metadata.PhoneNumber, metadata.BirthDate, metadata.LastUpdated, expectedVersion,
+	).Scan(&version)
+	if errors.Is(err, sql.ErrNoRows) {
+		return r.missOrConflict(ctx, metadata.Email)
+	} else if err != nil {
+		return err
+	}
+	metadata.Version = version
+	return nil
+}
+
+// Delete borra el registro si su versión sigue siendo expectedVersion (0 = cualquiera).
+func (r *Repository) Delete(ctx context.Context, id string, expectedVersion int64) error {
+	res, err := r.db.ExecContext(ctx,
+		`DELETE FROM metadata_users WHERE email = $1 AND ($2::BIGINT = 0 OR version = $2::BIGINT)`, id, expectedVersion)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return r.missOrConflict(ctx, id)
+	}
+	return nil
+}
+
+// missOrConflict explica por qué un UPDATE/DELETE condicional no tocó filas.
+func (r *Repository) missOrConflict(ctx context.Context, id string) error {
+	var exists bool
+	if err := r.db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM metadata_users WHERE email = $1)`, id).Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		return repository.ErrNotFound
+	}
+	return repository.ErrVersionMismatch
+}
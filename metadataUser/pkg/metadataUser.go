@@ -1,11 +1,35 @@
 package model
 
-
 type MetadataUser struct {
-	Email              string    `json:"email"`
-	FullName           string    `json:"full_name"`
-	AvatarURL          string    `json:"avatar_url"`
-	PhoneNumber        string    `json:"phone_number"`
-	BirthDate          string 	 `json:"birth_date"`
-	LastUpdated        string 	 `json:"last_updated"`
-}
\ No newline at end of file
+	Email       string `json:"email"`
+	FullName    string `json:"full_name"`
+	AvatarURL   string `json:"avatar_url"`
+	PhoneNumber string `json:"phone_number"`
+	BirthDate   string `json:"birth_date"`
+	LastUpdated string `json:"last_updated"`
+	Version     int64  `json:"version"` // Se incrementa en cada escritura (control de concurrencia optimista)
+}
+
+// MetadataUserPatch es una actualización parcial: solo se tocan los campos que no son nil.
+type MetadataUserPatch struct {
+	FullName    *string `json:"full_name,omitempty"`
+	AvatarURL   *string `json:"avatar_url,omitempty"`
+	PhoneNumber *string `json:"phone_number,omitempty"`
+	BirthDate   *string `json:"birth_date,omitempty"`
+}
+
+// Apply copia en m los campos presentes en el patch.
+func (p MetadataUserPatch) Apply(m *MetadataUser) {
+	if p.FullName != nil {
+		m.FullName = *p.FullName
+	}
+	if p.AvatarURL != nil {
+		m.AvatarURL = *p.AvatarURL
+	}
+	if p.PhoneNumber != nil {
+		m.PhoneNumber = *p.PhoneNumber
+	}
+	if p.BirthDate != nil {
+		m.BirthDate = *p.BirthDate
+	}
+}
@@ -0,0 +1,44 @@
+package auth
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Issuer es el "iss" que pone auth-server en todos sus access tokens.
+const Issuer = "auth-service"
+
+// AccessClaims define los claims personalizados de un JWT de acceso.
+type AccessClaims struct {
+	Sub   string `json:"sub"`
+	Email string `json:"email"`
+	// EmailVerified es false mientras el usuario no confirme su email.
+	EmailVerified bool     `json:"email_verified"`
+	Role          string   `json:"role"`
+	Permissions   []string `json:"perms,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// Has indica si el token trae el permiso perm.
+func (c *AccessClaims) Has(perm string) bool {
+	return slices.Contains(c.Permissions, perm)
+}
+
+// CanAccessEmail indica si el dueño del token puede operar sobre los datos de
+// email: con selfPerm si son los propios, o con anyPerm sobre cualquiera. Los
+// dos emails se comparan normalizados.
+func (c *AccessClaims) CanAccessEmail(email, selfPerm, anyPerm string) bool {
+	if c.Has(anyPerm) {
+		return true
+	}
+	own := NormalizeEmail(c.Email)
+	return own != "" && own == NormalizeEmail(email) && c.Has(selfPerm)
+}
+
+// NormalizeEmail es la forma en la que se guardan y se buscan los emails: sin
+// espacios alrededor y en minúsculas.
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
@@ -0,0 +1,30 @@
+package auth
+
+import "testing"
+
+func TestCanAccessEmail(t *testing.T) {
+	own := &AccessClaims{Email: "Ana@Example.com", Permissions: []string{PermMetadataReadSelf}}
+	admin := &AccessClaims{Email: "root@example.com", Permissions: []string{PermMetadataReadAny}}
+	for _, tc := range []struct {
+		claims *AccessClaims
+		email  string
+		want   bool
+	}{
+		{own, "ana@example.com", true},
+		{own, " ANA@example.com ", true},
+		{own, "bea@example.com", false},
+		{&AccessClaims{Email: "ana@example.com"}, "ana@example.com", false}, // sin el permiso
+		{&AccessClaims{Permissions: []string{PermMetadataReadSelf}}, " ", false},
+		{admin, "bea@example.com", true},
+	} {
+		if got := tc.claims.CanAccessEmail(tc.email, PermMetadataReadSelf, PermMetadataReadAny); got != tc.want {
+			t.Errorf("CanAccessEmail(%q) con el token de %q = %v, quería %v", tc.email, tc.claims.Email, got, tc.want)
+		}
+	}
+}
+
+func TestNormalizeEmail(t *testing.T) {
+	if got := NormalizeEmail("  Ana@Example.COM\n"); got != "ana@example.com" {
+		t.Errorf("NormalizeEmail = %q, quería ana@example.com", got)
+	}
+}
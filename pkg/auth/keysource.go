@@ -0,0 +1,337 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"proyecto/pkg/jwks"
+	registry "proyecto/pkg/registry"
+)
+
+var (
+	ErrUnknownKey = errors.New("unknown signing key")
+	// ErrUnavailable indica que no se pudo bajar de auth-server lo necesario
+	// para verificar el token; no dice nada de si el token es válido.
+	ErrUnavailable = errors.New("auth-server unavailable")
+)
+
+// URLResolver devuelve la URL a consultar; se resuelve en cada fetch para
+// seguir a auth-server aunque cambie de instancia.
+type URLResolver func(ctx context.Context) (string, error)
+
+// RegistryURL resuelve path contra una instancia sana de serviceName en el registry.
+func RegistryURL(reg registry.Registry, serviceName, path string) URLResolver {
+	return func(ctx context.Context) (string, error) {
+		addrs, err := reg.ServiceAddress(ctx, serviceName)
+		if err != nil {
+			return "", err
+		}
+		return "http://" + addrs[rand.Intn(len(addrs))] + path, nil
+	}
+}
+
+// StaticURL resuelve siempre a la misma URL.
+func StaticURL(url string) URLResolver {
+	return func(context.Context) (string, error) { return url, nil }
+}
+
+// HMACKey es un KeySource para tokens HS256 firmados con un secreto compartido.
+type HMACKey []byte
+
+func (k HMACKey) VerifyKey(_ context.Context, _ string, alg string) (any, error) {
+	if alg != "HS256" {
+		return nil, ErrUnknownKey
+	}
+	return []byte(k), nil
+}
+
+// MinSecretLength es el largo mínimo de un secreto HS256: 32 bytes, el tamaño
+// del hash (RFC 7518, sección 3.2).
+const MinSecretLength = 32
+
+var ErrWeakSecret = errors.New("HS256 secret too short")
+
+// CheckSecret rechaza un secreto HS256 vacío o más corto que MinSecretLength.
+func CheckSecret(secret []byte) error {
+	if len(secret) < MinSecretLength {
+		return fmt.Errorf("%w: %d bytes, need at least %d", ErrWeakSecret, len(secret), MinSecretLength)
+	}
+	return nil
+}
+
+// RotatingHMACKey es un HMACKey que se puede cambiar en caliente (p.ej. al
+// recargar el archivo del secreto); el secreto anterior se sigue aceptando
+// para los tokens que ya se firmaron con él.
+type RotatingHMACKey struct {
+	mu       sync.RWMutex
+	current  []byte
+	previous []byte
+}
+
+func NewRotatingHMACKey(secret []byte) *RotatingHMACKey {
+	return &RotatingHMACKey{current: secret}
+}
+
+// Set pone secret como secreto actual; el que había queda como anterior.
+func (k *RotatingHMACKey) Set(secret []byte) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if bytes.Equal(secret, k.current) {
+		return
+	}
+	k.previous, k.current = k.current, secret
+}
+
+func (k *RotatingHMACKey) VerifyKey(_ context.Context, _ string, alg string) (any, error) {
+	if alg != "HS256" {
+		return nil, ErrUnknownKey
+	}
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	if k.previous == nil {
+		return k.current, nil
+	}
+	return jwt.VerificationKeySet{Keys: []jwt.VerificationKey{k.current, k.previous}}, nil
+}
+
+// RemoteJWKS descarga y cachea el JWKS de auth-server. Un kid desconocido
+// provoca una nueva descarga (para seguir las rotaciones), como mucho una vez
+// cada minRefresh.
+type RemoteJWKS struct {
+	resolve    URLResolver
+	client     *http.Client
+	ttl        time.Duration
+	minRefresh time.Duration
+
+	mu    sync.Mutex
+	set   jwks.Set
+	state fetchState
+}
+
+func NewRemoteJWKS(resolve URLResolver, client *http.Client) *RemoteJWKS {
+	if client == nil {
+		client = &http.Client{Timeout: 5 * time.Second}
+	}
+	return &RemoteJWKS{
+		resolve:    resolve,
+		client:     client,
+		ttl:        5 * time.Minute,
+		minRefresh: 10 * time.Second,
+	}
+}
+
+// VerifyKey busca kid en el JWKS en caché. Un kid desconocido espera una
+// descarga nueva; un JWKS viejo se refresca en segundo plano y mientras se
+// sigue verificando con el que hay, aunque auth-server no responda.
+func (r *RemoteJWKS) VerifyKey(ctx context.Context, kid, alg string) (any, error) {
+	r.mu.Lock()
+	jwk, ok := r.set.Lookup(kid)
+	var done <-chan struct{}
+	switch {
+	case !ok && r.state.canTry(r.minRefresh):
+		done = r.state.start(&r.mu, r.fetch)
+	case !ok:
+		done = r.state.inflight
+	case time.Since(r.state.okAt) > r.ttl && r.state.canTry(r.minRefresh):
+		r.state.start(&r.mu, r.fetch)
+	}
+	r.mu.Unlock()
+
+	if !ok {
+		if done != nil {
+			if err := wait(ctx, done); err != nil {
+				return nil, err
+			}
+		}
+		r.mu.Lock()
+		jwk, ok = r.set.Lookup(kid)
+		lastErr := r.state.err
+		r.mu.Unlock()
+		if !ok && lastErr != nil {
+			// No se sabe si el kid existe: auth-server no respondió.
+			return nil, fmt.Errorf("%w: fetching JWKS: %v", ErrUnavailable, lastErr)
+		}
+	}
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+	if jwk.Alg != alg {
+		return nil, fmt.Errorf("%w: alg %s does not match key %s", ErrUnknownKey, alg, kid)
+	}
+	return jwk.PublicKey()
+}
+
+func (r *RemoteJWKS) fetch(ctx context.Context) (func(), error) {
+	var set jwks.Set
+	if err := getJSON(ctx, r.client, r.resolve, "", &set); err != nil {
+		return nil, err
+	}
+	return func() { r.set = set }, nil
+}
+
+// RemoteRevocations mantiene en caché la lista de jti revocados que publica
+// auth-server y la refresca como mucho cada interval. token es la credencial
+// de servicio con la que auth-server deja bajar la lista.
+type RemoteRevocations struct {
+	resolve  URLResolver
+	client   *http.Client
+	token    string
+	interval time.Duration
+
+	mu      sync.Mutex
+	revoked map[string]time.Time
+	state   fetchState
+}
+
+func NewRemoteRevocations(resolve URLResolver, client *http.Client, token string, interval time.Duration) *RemoteRevocations {
+	if client == nil {
+		client = &http.Client{Timeout: 5 * time.Second}
+	}
+	return &RemoteRevocations{resolve: resolve, client: client, token: token, interval: interval, revoked: map[string]time.Time{}}
+}
+
+// IsRevoked responde con la lista en caché; si venció, la refresca en
+// segundo plano. Solo espera la descarga si todavía no hay ninguna lista.
+func (r *RemoteRevocations) IsRevoked(ctx context.Context, jti string) (bool, error) {
+	r.mu.Lock()
+	var done <-chan struct{}
+	if r.state.canTry(r.interval) {
+		done = r.state.start(&r.mu, r.fetch)
+	} else {
+		done = r.state.inflight
+	}
+	loaded := !r.state.okAt.IsZero()
+	r.mu.Unlock()
+
+	if !loaded && done != nil {
+		if err := wait(ctx, done); err != nil {
+			return false, err
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	// Sin lista nunca descargada no podemos decidir; con una vieja seguimos usándola.
+	if r.state.okAt.IsZero() {
+		return false, fmt.Errorf("%w: fetching revocation list: %v", ErrUnavailable, r.state.err)
+	}
+	exp, ok := r.revoked[jti]
+	return ok && time.Now().Before(exp), nil
+}
+
+func (r *RemoteRevocations) fetch(ctx context.Context) (func(), error) {
+	var body struct {
+		Revoked []struct {
+			JTI       string    `json:"jti"`
+			ExpiresAt time.Time `json:"expires_at"`
+		} `json:"revoked"`
+	}
+	if err := getJSON(ctx, r.client, r.resolve, r.token, &body); err != nil {
+		return nil, err
+	}
+	revoked := make(map[string]time.Time, len(body.Revoked))
+	for _, t := range body.Revoked {
+		revoked[t.JTI] = t.ExpiresAt
+	}
+	return func() { r.revoked = revoked }, nil
+}
+
+// Tras una descarga fallida se espera retryBackoff antes de reintentar,
+// duplicándolo en cada fallo hasta maxRetryBackoff.
+const (
+	retryBackoff    = time.Second
+	maxRetryBackoff = time.Minute
+)
+
+// fetchState lleva las descargas de una caché remota: una sola a la vez,
+// fuera del lock de la caché y con backoff si auth-server no responde. Sus
+// campos se usan con el lock del dueño tomado.
+type fetchState struct {
+	okAt     time.Time // última descarga buena
+	triedAt  time.Time // último intento, bueno o no
+	failures int       // fallos seguidos
+	err      error     // error del último intento
+	inflight chan struct{}
+}
+
+// canTry indica si toca otra descarga: pasó gap desde el último intento (o
+// el backoff, si falló) y no hay una en curso.
+func (s *fetchState) canTry(gap time.Duration) bool {
+	if s.inflight != nil {
+		return false
+	}
+	if s.failures > 0 {
+		gap = min(retryBackoff<<min(s.failures-1, 16), maxRetryBackoff)
+	}
+	return s.triedAt.IsZero() || time.Since(s.triedAt) >= gap
+}
+
+// start lanza fetch en segundo plano y devuelve un canal que se cierra al
+// terminar. fetch corre sin el lock; la función que devuelve aplica el
+// resultado y se llama con mu tomado.
+func (s *fetchState) start(mu *sync.Mutex, fetch func(context.Context) (func(), error)) <-chan struct{} {
+	done := make(chan struct{})
+	s.inflight = done
+	s.triedAt = time.Now()
+	go func() {
+		// No depende del request que la disparó; la corta el timeout del cliente.
+		apply, err := fetch(context.Background())
+		mu.Lock()
+		defer mu.Unlock()
+		if err != nil {
+			s.failures++
+			s.err = err
+		} else {
+			apply()
+			s.okAt = time.Now()
+			s.failures = 0
+			s.err = nil
+		}
+		s.inflight = nil
+		close(done)
+	}()
+	return done
+}
+
+// wait espera a que termine una descarga o se cancele ctx.
+func wait(ctx context.Context, done <-chan struct{}) error {
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// getJSON hace un GET a la URL de resolve; con token manda Authorization: Bearer.
+func getJSON(ctx context.Context, client *http.Client, resolve URLResolver, token string, out any) error {
+	url, err := resolve(ctx)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %d from %s", resp.StatusCode, url)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
@@ -0,0 +1,179 @@
+package auth
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"proyecto/pkg/jwks"
+)
+
+// fakeAuthServer publica un JWKS con una llave Ed25519 y una lista de
+// revocación; con up=false responde 503 a todo.
+type fakeAuthServer struct {
+	*httptest.Server
+	priv    ed25519.PrivateKey
+	up      atomic.Bool
+	calls   atomic.Int32
+	revoked []string
+}
+
+func newFakeAuthServer(t *testing.T) *fakeAuthServer {
+	t.Helper()
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &fakeAuthServer{priv: priv}
+	s.up.Store(true)
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		s.calls.Add(1)
+		if !s.up.Load() {
+			http.Error(w, "down", http.StatusServiceUnavailable)
+			return
+		}
+		switch req.URL.Path {
+		case "/jwks":
+			_ = json.NewEncoder(w).Encode(jwks.Set{Keys: []jwks.JWK{{
+				Kty: "OKP", Kid: "k1", Use: "sig", Alg: "EdDSA", Crv: "Ed25519",
+				X: base64.RawURLEncoding.EncodeToString(pub),
+			}}})
+		case "/revoked":
+			if req.Header.Get("Authorization") != "Bearer service-token" {
+				http.Error(w, "no", http.StatusUnauthorized)
+				return
+			}
+			type entry struct {
+				JTI       string    `json:"jti"`
+				ExpiresAt time.Time `json:"expires_at"`
+			}
+			var list []entry
+			for _, jti := range s.revoked {
+				list = append(list, entry{JTI: jti, ExpiresAt: time.Now().Add(time.Hour)})
+			}
+			_ = json.NewEncoder(w).Encode(map[string]any{"revoked": list})
+		default:
+			http.NotFound(w, req)
+		}
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *fakeAuthServer) token(t *testing.T, kid, jti string) string {
+	t.Helper()
+	claims := AccessClaims{
+		Sub: "ana@example.com", Email: "ana@example.com", Role: RoleUser,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        jti,
+			Issuer:    Issuer,
+			Audience:  jwt.ClaimStrings{"metadata-user"},
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+		},
+	}
+	tok := jwt.NewWithClaims(jwt.SigningMethodEdDSA, claims)
+	tok.Header["kid"] = kid
+	raw, err := tok.SignedString(s.priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return raw
+}
+
+func TestRemoteJWKSUnavailable(t *testing.T) {
+	srv := newFakeAuthServer(t)
+	srv.up.Store(false)
+	v := NewVerifier(NewRemoteJWKS(StaticURL(srv.URL+"/jwks"), nil), "metadata-user")
+
+	_, err := v.Verify(context.Background(), srv.token(t, "k1", "j1"))
+	if !errors.Is(err, ErrUnavailable) || errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("Verify con auth-server caído = %v, quería ErrUnavailable", err)
+	}
+
+	// Durante el backoff no se vuelve a consultar auth-server.
+	for range 20 {
+		_, _ = v.Verify(context.Background(), srv.token(t, "k1", "j1"))
+	}
+	if n := srv.calls.Load(); n != 1 {
+		t.Errorf("descargas del JWKS = %d, quería 1", n)
+	}
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Authorization", "Bearer "+srv.token(t, "k1", "j1"))
+	Middleware(v)(http.NotFoundHandler()).ServeHTTP(rec, req)
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("middleware respondió %d, quería 503", rec.Code)
+	}
+}
+
+func TestRemoteJWKSKeepsCacheWhileDown(t *testing.T) {
+	srv := newFakeAuthServer(t)
+	keys := NewRemoteJWKS(StaticURL(srv.URL+"/jwks"), nil)
+	keys.ttl, keys.minRefresh = 0, 0
+	v := NewVerifier(keys, "metadata-user")
+
+	if _, err := v.Verify(context.Background(), srv.token(t, "k1", "j1")); err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	srv.up.Store(false)
+	// El JWKS venció, pero el refresco va en segundo plano y el kid sigue en caché.
+	if _, err := v.Verify(context.Background(), srv.token(t, "k1", "j2")); err != nil {
+		t.Fatalf("Verify con JWKS viejo: %v", err)
+	}
+
+	keys.mu.Lock()
+	inflight := keys.state.inflight
+	keys.mu.Unlock()
+	if inflight != nil {
+		<-inflight // termina el refresco en curso
+	}
+
+	srv.up.Store(true)
+	keys.mu.Lock()
+	keys.state.triedAt = time.Now().Add(-time.Hour) // se salta el backoff
+	keys.mu.Unlock()
+	_, err := v.Verify(context.Background(), srv.token(t, "otro", "j3"))
+	if !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("kid desconocido = %v, quería ErrInvalidToken", err)
+	}
+}
+
+func TestRemoteRevocations(t *testing.T) {
+	srv := newFakeAuthServer(t)
+	srv.revoked = []string{"revocado"}
+	srv.up.Store(false)
+	r := NewRemoteRevocations(StaticURL(srv.URL+"/revoked"), nil, "service-token", time.Minute)
+
+	// Sin ninguna lista no se puede decidir.
+	if _, err := r.IsRevoked(context.Background(), "revocado"); !errors.Is(err, ErrUnavailable) {
+		t.Fatalf("IsRevoked sin lista = %v, quería ErrUnavailable", err)
+	}
+	for range 20 {
+		_, _ = r.IsRevoked(context.Background(), "revocado")
+	}
+	if n := srv.calls.Load(); n != 1 {
+		t.Errorf("descargas durante el backoff = %d, quería 1", n)
+	}
+
+	srv.up.Store(true)
+	r.mu.Lock()
+	r.state.triedAt = time.Now().Add(-time.Hour) // se salta el backoff
+	r.mu.Unlock()
+	for jti, want := range map[string]bool{"revocado": true, "vigente": false} {
+		got, err := r.IsRevoked(context.Background(), jti)
+		if err != nil || got != want {
+			t.Errorf("IsRevoked(%q) = %v, %v; quería %v", jti, got, err, want)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+)
+
+type contextKey struct{}
+
+// WithClaims guarda los claims del token en el contexto.
+func WithClaims(ctx context.Context, claims *AccessClaims) context.Context {
+	return context.WithValue(ctx, contextKey{}, claims)
+}
+
+// ClaimsFromContext devuelve los claims que dejó el middleware.
+func ClaimsFromContext(ctx context.Context) (*AccessClaims, bool) {
+	claims, ok := ctx.Value(contextKey{}).(*AccessClaims)
+	return claims, ok
+}
+
+// Middleware exige un bearer token válido y deja sus claims en el contexto.
+func Middleware(v *Verifier) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			raw, ok := strings.CutPrefix(req.Header.Get("Authorization"), "Bearer ")
+			if !ok || raw == "" {
+				unauthorized(w, "Falta el token de acceso")
+				return
+			}
+
+			claims, err := v.Verify(req.Context(), raw)
+			if errors.Is(err, ErrInvalidToken) || errors.Is(err, ErrRevokedToken) {
+				unauthorized(w, "Token de acceso inválido")
+				return
+			} else if err != nil {
+				log.Printf("Error verificando token: %v", err)
+				http.Error(w, "No se pudo verificar el token", http.StatusServiceUnavailable)
+				return
+			}
+
+			next.ServeHTTP(w, req.WithContext(WithClaims(req.Context(), claims)))
+		})
+	}
+}
+
+// RequireEmailAccess responde 403 (y devuelve false) si el llamante no puede
+// operar sobre los datos de email (ver AccessClaims.CanAccessEmail). Debe usarse detrás de Middleware.
+func RequireEmailAccess(w http.ResponseWriter, req *http.Request, email, selfPerm, anyPerm string) bool {
+	claims, ok := ClaimsFromContext(req.Context())
+	if !ok {
+		unauthorized(w, "Falta el token de acceso")
+		return false
+	}
+	if !claims.CanAccessEmail(email, selfPerm, anyPerm) {
+		http.Error(w, "Acceso denegado", http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+func unauthorized(w http.ResponseWriter, msg string) {
+	w.Header().Set("WWW-Authenticate", `Bearer realm="proyecto"`)
+	http.Error(w, msg, http.StatusUnauthorized)
+}
@@ -0,0 +1,61 @@
+package auth
+
+import "slices"
+
+// Roles conocidos. Al registrarse uno mismo solo se obtiene DefaultRole.
+const (
+	RoleUser    = "user"
+	RoleSupport = "support"
+	RoleAdmin   = "admin"
+
+	DefaultRole = RoleUser
+)
+
+// Permisos con nombre que viajan en el claim "perms" del access token.
+const (
+	PermMetadataReadSelf  = "metadata:read:self"
+	PermMetadataWriteSelf = "metadata:write:self"
+	PermMetadataReadAny   = "metadata:read:any"
+	PermMetadataWriteAny  = "metadata:write:any"
+	PermSessionsRevokeAny = "sessions:revoke:any"
+	PermUsersRoleWrite    = "users:role:write"
+	PermKeysRotate        = "keys:rotate"
+	PermRevocationsRead   = "revocations:read"
+	PermMetricsRead       = "metrics:read"
+)
+
+var rolePermissions = map[string][]string{
+	RoleUser: {
+		PermMetadataReadSelf,
+		PermMetadataWriteSelf,
+	},
+	RoleSupport: {
+		PermMetadataReadSelf,
+		PermMetadataWriteSelf,
+		PermMetadataReadAny,
+		PermSessionsRevokeAny,
+		PermRevocationsRead,
+	},
+	RoleAdmin: {
+		PermMetadataReadSelf,
+		PermMetadataWriteSelf,
+		PermMetadataReadAny,
+		PermMetadataWriteAny,
+		PermSessionsRevokeAny,
+		PermRevocationsRead,
+		PermUsersRoleWrite,
+		PermKeysRotate,
+		PermMetricsRead,
+	},
+}
+
+// ValidRole indica si role es uno de los roles definidos.
+func ValidRole(role string) bool {
+	_, ok := rolePermissions[role]
+	return ok
+}
+
+// PermissionsFor devuelve los permisos de un rol (nil si el rol no existe).
+func PermissionsFor(role string) []string {
+	return slices.Clone(rolePermissions[role])
+}
@@ -0,0 +1,92 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var (
+	// ErrInvalidToken cubre firma inválida, token expirado, iss/aud incorrectos o claims mal formados.
+	ErrInvalidToken = errors.New("invalid access token")
+	// ErrRevokedToken indica que el jti del token fue revocado en auth-server.
+	ErrRevokedToken = errors.New("access token revoked")
+)
+
+// KeySource resuelve la llave de verificación para el kid y alg del header del token.
+type KeySource interface {
+	VerifyKey(ctx context.Context, kid, alg string) (any, error)
+}
+
+// RevocationChecker responde si un jti fue revocado.
+type RevocationChecker interface {
+	IsRevoked(ctx context.Context, jti string) (bool, error)
+}
+
+// Verifier valida los access tokens emitidos por auth-server.
+type Verifier struct {
+	keys     KeySource
+	audience string
+	methods  []string
+	revoked  RevocationChecker
+}
+
+// Option configura un Verifier.
+type Option func(*Verifier)
+
+// WithRevocationChecker hace que se rechacen los tokens cuyo jti esté revocado.
+func WithRevocationChecker(r RevocationChecker) Option {
+	return func(v *Verifier) { v.revoked = r }
+}
+
+// WithMethods limita los algoritmos aceptados (por defecto RS256 y EdDSA).
+func WithMethods(methods ...string) Option {
+	return func(v *Verifier) { v.methods = methods }
+}
+
+// NewVerifier crea un verificador que exige iss=Issuer y que audience esté en "aud".
+func NewVerifier(keys KeySource, audience string, opts ...Option) *Verifier {
+	v := &Verifier{
+		keys:     keys,
+		audience: audience,
+		methods:  []string{"RS256", "EdDSA"},
+	}
+	for _, opt := range opts {
+		opt(v)
+	}
+	return v
+}
+
+// Verify valida firma, exp, iss y aud del token y devuelve sus claims.
+func (v *Verifier) Verify(ctx context.Context, raw string) (*AccessClaims, error) {
+	claims := &AccessClaims{}
+	keyfunc := func(t *jwt.Token) (any, error) {
+		kid, _ := t.Header["kid"].(string)
+		return v.keys.VerifyKey(ctx, kid, t.Method.Alg())
+	}
+	_, err := jwt.ParseWithClaims(raw, claims, keyfunc,
+		jwt.WithValidMethods(v.methods),
+		jwt.WithIssuer(Issuer),
+		jwt.WithAudience(v.audience),
+		jwt.WithExpirationRequired(),
+	)
+	if errors.Is(err, ErrUnavailable) {
+		// No es culpa del token: el middleware responde 503, no 401.
+		return nil, err
+	} else if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	if v.revoked != nil && claims.ID != "" {
+		revoked, err := v.revoked.IsRevoked(ctx, claims.ID)
+		if err != nil {
+			return nil, err
+		}
+		if revoked {
+			return nil, ErrRevokedToken
+		}
+	}
+	return claims, nil
+}
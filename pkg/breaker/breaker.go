@@ -0,0 +1,264 @@
+// Package breaker implementa un circuit breaker para las llamadas entre
+// servicios: cuando falla una fracción de las llamadas recientes deja de
+// llamar (abierto) durante un tiempo, luego deja pasar unas pocas llamadas de
+// prueba (semiabierto) y vuelve a cerrarse si salen bien.
+package breaker
+
+import (
+	"errors"
+	"expvar"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrOpen indica que el circuito está abierto y la llamada no se hizo.
+	ErrOpen = errors.New("circuit breaker is open")
+	// ErrTooManyProbes indica que el circuito está semiabierto y ya hay
+	// tantas llamadas de prueba en curso como permite la configuración.
+	ErrTooManyProbes = errors.New("circuit breaker is half-open")
+)
+
+type State int
+
+const (
+	Closed State = iota
+	Open
+	HalfOpen
+)
+
+func (s State) String() string {
+	switch s {
+	case Closed:
+		return "closed"
+	case Open:
+		return "open"
+	case HalfOpen:
+		return "half-open"
+	}
+	return "unknown"
+}
+
+// Métricas en /debug/vars: estado actual de cada breaker y cuántas llamadas rechazó.
+var (
+	states     = expvar.NewMap("circuit_breaker_state")
+	rejections = expvar.NewMap("circuit_breaker_rejected")
+	trips      = expvar.NewMap("circuit_breaker_opened")
+)
+
+type Config struct {
+	FailureRatio   float64       // fracción de llamadas fallidas de la ventana que abre el circuito
+	MinRequests    int           // llamadas que tiene que haber en la ventana para evaluar la fracción
+	Window         time.Duration // cada cuánto se empiezan a contar de nuevo las llamadas (cerrado)
+	OpenTimeout    time.Duration // cuánto se queda abierto antes de probar
+	HalfOpenProbes int           // llamadas de prueba (y éxitos necesarios para cerrar)
+}
+
+// DefaultConfig abre cuando falla la mitad de las llamadas de una ventana de
+// 30s (con al menos 5) y prueba de nuevo a los 10s.
+var DefaultConfig = Config{FailureRatio: 0.5, MinRequests: 5, Window: 30 * time.Second, OpenTimeout: 10 * time.Second, HalfOpenProbes: 1}
+
+// Validate rechaza los valores fuera de rango; los que están en 0 toman el de DefaultConfig.
+func (c Config) Validate() error {
+	var errs []error
+	if c.FailureRatio < 0 || c.FailureRatio > 1 {
+		errs = append(errs, fmt.Errorf("failure_ratio debe estar entre 0 y 1: %v", c.FailureRatio))
+	}
+	if c.MinRequests < 0 {
+		errs = append(errs, errors.New("min_requests no puede ser negativo"))
+	}
+	if c.Window < 0 {
+		errs = append(errs, errors.New("window no puede ser negativo"))
+	}
+	if c.OpenTimeout < 0 {
+		errs = append(errs, errors.New("open_timeout no puede ser negativo"))
+	}
+	if c.HalfOpenProbes < 0 {
+		errs = append(errs, errors.New("half_open_probes no puede ser negativo"))
+	}
+	return errors.Join(errs...)
+}
+
+type Breaker struct {
+	name string
+	cfg  Config
+
+	mu          sync.Mutex
+	state       State
+	requests    int // llamadas terminadas en la ventana actual
+	failures    int // de ellas, las que fallaron
+	windowStart time.Time
+	probes      int // llamadas de prueba en curso
+	successes   int // pruebas que salieron bien
+	openedAt    time.Time
+	status      *expvar.String
+	now         func() time.Time
+}
+
+func New(name string, cfg Config) *Breaker {
+	if cfg.FailureRatio <= 0 || cfg.FailureRatio > 1 {
+		cfg.FailureRatio = DefaultConfig.FailureRatio
+	}
+	if cfg.MinRequests <= 0 {
+		cfg.MinRequests = DefaultConfig.MinRequests
+	}
+	if cfg.Window <= 0 {
+		cfg.Window = DefaultConfig.Window
+	}
+	if cfg.OpenTimeout <= 0 {
+		cfg.OpenTimeout = DefaultConfig.OpenTimeout
+	}
+	if cfg.HalfOpenProbes <= 0 {
+		cfg.HalfOpenProbes = DefaultConfig.HalfOpenProbes
+	}
+	b := &Breaker{name: name, cfg: cfg, status: new(expvar.String), now: time.Now}
+	b.windowStart = b.now()
+	b.status.Set(Closed.String())
+	states.Set(name, b.status)
+	return b
+}
+
+func (b *Breaker) Name() string {
+	return b.name
+}
+
+// State devuelve el estado actual (un circuito abierto cuyo tiempo ya pasó se informa semiabierto).
+func (b *Breaker) State() State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == Open && b.now().Sub(b.openedAt) >= b.cfg.OpenTimeout {
+		return HalfOpen
+	}
+	return b.state
+}
+
+// Allow pide permiso para hacer una llamada. Si se concede hay que llamar a
+// done con el resultado; si no, devuelve ErrOpen o ErrTooManyProbes.
+func (b *Breaker) Allow() (done func(success bool), err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == Open && b.now().Sub(b.openedAt) >= b.cfg.OpenTimeout {
+		b.setState(HalfOpen)
+	}
+
+	switch b.state {
+	case Open:
+		rejections.Add(b.name, 1)
+		return nil, ErrOpen
+	case HalfOpen:
+		if b.probes >= b.cfg.HalfOpenProbes {
+			rejections.Add(b.name, 1)
+			return nil, ErrTooManyProbes
+		}
+		b.probes++
+		gen := b.openedAt
+		var once sync.Once
+		return func(success bool) {
+			once.Do(func() { b.probeDone(gen, success) })
+		}, nil
+	}
+
+	var once sync.Once
+	return func(success bool) {
+		once.Do(func() { b.callDone(success) })
+	}, nil
+}
+
+func (b *Breaker) callDone(success bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state != Closed {
+		// Llegó tarde, después de que otra llamada abriera el circuito.
+		return
+	}
+	if now := b.now(); now.Sub(b.windowStart) >= b.cfg.Window {
+		b.requests, b.failures, b.windowStart = 0, 0, now
+	}
+	b.requests++
+	if success {
+		return
+	}
+	b.failures++
+	if b.requests >= b.cfg.MinRequests && float64(b.failures)/float64(b.requests) >= b.cfg.FailureRatio {
+		b.trip()
+	}
+}
+
+// probeDone registra el resultado de una prueba. gen evita que una prueba de
+// una apertura anterior cuente para la actual.
+func (b *Breaker) probeDone(gen time.Time, success bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state != HalfOpen || !b.openedAt.Equal(gen) {
+		return
+	}
+	b.probes--
+	if !success {
+		b.trip()
+		return
+	}
+	b.successes++
+	if b.successes >= b.cfg.HalfOpenProbes {
+		b.setState(Closed)
+	}
+}
+
+func (b *Breaker) trip() {
+	b.openedAt = b.now()
+	trips.Add(b.name, 1)
+	b.setState(Open)
+}
+
+func (b *Breaker) setState(s State) {
+	if b.state != s {
+		log.Printf("Circuit breaker %s: %s -> %s", b.name, b.state, s)
+	}
+	b.state = s
+	b.requests, b.failures, b.probes, b.successes = 0, 0, 0, 0
+	b.windowStart = b.now()
+	b.status.Set(s.String())
+}
+
+// Group crea un breaker por nombre (p.ej. uno por instancia) la primera vez que se pide.
+type Group struct {
+	cfg      Config
+	mu       sync.Mutex
+	breakers map[string]*Breaker
+}
+
+func NewGroup(cfg Config) *Group {
+	return &Group{cfg: cfg, breakers: map[string]*Breaker{}}
+}
+
+func (g *Group) Get(name string) *Breaker {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	b, ok := g.breakers[name]
+	if !ok {
+		b = New(name, g.cfg)
+		g.breakers[name] = b
+	}
+	return b
+}
+
+// Retain descarta los breakers cuyo nombre no esté en names (p.ej. los de
+// instancias que ya no están en el registry), también de las métricas.
+func (g *Group) Retain(names []string) {
+	keep := make(map[string]bool, len(names))
+	for _, n := range names {
+		keep[n] = true
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for name := range g.breakers {
+		if !keep[name] {
+			delete(g.breakers, name)
+			states.Delete(name)
+			rejections.Delete(name)
+			trips.Delete(name)
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package breaker
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// testClock es un reloj que solo avanza cuando el test lo pide.
+type testClock struct{ t time.Time }
+
+func (c *testClock) now() time.Time          { return c.t }
+func (c *testClock) advance(d time.Duration) { c.t = c.t.Add(d) }
+
+func newTestBreaker(t *testing.T, cfg Config) (*Breaker, *testClock) {
+	t.Helper()
+	clock := &testClock{t: time.Unix(1_700_000_000, 0)}
+	b := New(t.Name(), cfg)
+	b.now = clock.now
+	b.windowStart = clock.now()
+	return b, clock
+}
+
+// call hace una llamada con el resultado success y devuelve el error de Allow.
+func call(b *Breaker, success bool) error {
+	done, err := b.Allow()
+	if err != nil {
+		return err
+	}
+	done(success)
+	return nil
+}
+
+var testConfig = Config{FailureRatio: 0.5, MinRequests: 4, Window: time.Minute, OpenTimeout: 10 * time.Second, HalfOpenProbes: 1}
+
+func TestClosedToOpen(t *testing.T) {
+	b, _ := newTestBreaker(t, testConfig)
+
+	// Con menos de MinRequests no se abre aunque fallen todas.
+	for range 3 {
+		if err := call(b, false); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if b.State() != Closed {
+		t.Fatalf("estado con 3 fallos = %s, quería closed", b.State())
+	}
+	// Con 4 ya se evalúa la fracción.
+	_ = call(b, false)
+	if b.State() != Open {
+		t.Fatalf("estado con 4 fallos = %s, quería open", b.State())
+	}
+	if err := call(b, true); !errors.Is(err, ErrOpen) {
+		t.Errorf("Allow abierto = %v, quería ErrOpen", err)
+	}
+}
+
+func TestBelowRatioStaysClosed(t *testing.T) {
+	b, _ := newTestBreaker(t, testConfig)
+	for i := range 20 {
+		_ = call(b, i%4 != 0) // falla una de cada cuatro
+	}
+	if b.State() != Closed {
+		t.Errorf("estado = %s, quería closed", b.State())
+	}
+}
+
+func TestWindowResetsCounts(t *testing.T) {
+	b, clock := newTestBreaker(t, testConfig)
+	for range 3 {
+		_ = call(b, false)
+	}
+	// Los fallos de la ventana anterior ya no cuentan.
+	clock.advance(testConfig.Window)
+	for range 3 {
+		_ = call(b, true)
+	}
+	_ = call(b, false)
+	if b.State() != Closed {
+		t.Errorf("estado = %s, quería closed", b.State())
+	}
+}
+
+func TestHalfOpen(t *testing.T) {
+	b, clock := newTestBreaker(t, testConfig)
+	for range 4 {
+		_ = call(b, false)
+	}
+	clock.advance(testConfig.OpenTimeout - time.Second)
+	if err := call(b, true); !errors.Is(err, ErrOpen) {
+		t.Fatalf("Allow antes del timeout = %v, quería ErrOpen", err)
+	}
+
+	clock.advance(time.Second)
+	if b.State() != HalfOpen {
+		t.Fatalf("estado tras el timeout = %s, quería half-open", b.State())
+	}
+	probe, err := b.Allow()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Solo una prueba a la vez.
+	if _, err := b.Allow(); !errors.Is(err, ErrTooManyProbes) {
+		t.Errorf("segunda prueba = %v, quería ErrTooManyProbes", err)
+	}
+
+	// Si la prueba falla vuelve a abrirse, con otro timeout completo.
+	probe(false)
+	if b.State() != Open {
+		t.Fatalf("estado tras una prueba fallida = %s, quería open", b.State())
+	}
+	clock.advance(testConfig.OpenTimeout)
+	if err := call(b, true); err != nil {
+		t.Fatal(err)
+	}
+	if b.State() != Closed {
+		t.Errorf("estado tras una prueba exitosa = %s, quería closed", b.State())
+	}
+}
+
+func TestLateResultIgnored(t *testing.T) {
+	b, _ := newTestBreaker(t, testConfig)
+	late, err := b.Allow()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range 4 {
+		_ = call(b, false)
+	}
+	// Una llamada que empezó con el circuito cerrado no lo cierra al terminar.
+	late(true)
+	if b.State() != Open {
+		t.Errorf("estado = %s, quería open", b.State())
+	}
+}
+
+func TestGroupRetain(t *testing.T) {
+	g := NewGroup(testConfig)
+	a := g.Get("svc@a")
+	g.Get("svc@b")
+	if g.Get("svc@a") != a {
+		t.Fatal("Get devolvió otro breaker para el mismo nombre")
+	}
+
+	g.Retain([]string{"svc@a"})
+	if len(g.breakers) != 1 || g.Get("svc@a") != a {
+		t.Errorf("breakers tras Retain = %v", g.breakers)
+	}
+	if states.Get("svc@b") != nil {
+		t.Error("la métrica de svc@b sigue publicada")
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	if err := DefaultConfig.Validate(); err != nil {
+		t.Errorf("DefaultConfig: %v", err)
+	}
+	if err := (Config{FailureRatio: 1.5}).Validate(); err == nil {
+		t.Error("failure_ratio 1.5 aceptado")
+	}
+	if err := (Config{OpenTimeout: -time.Second}).Validate(); err == nil {
+		t.Error("open_timeout negativo aceptado")
+	}
+}
@@ -0,0 +1,201 @@
+// Package config carga la configuración tipada de los servicios: primero los
+// valores por defecto del struct, luego un archivo YAML y luego las variables
+// de entorno (cada main aplica al final los flags que se pasaron).
+//
+// Los campos se describen con tags:
+//
+//	Port   int    `yaml:"port" env:"SERVICE_PORT"`
+//	Secret string `yaml:"secret" env:"JWT_SECRET" secret:"true"`
+//
+// Los campos con secret:"true" se ocultan en Redacted.
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const redacted = "[REDACTED]"
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// Load completa dst (puntero a struct, ya con sus valores por defecto) con el
+// archivo path (si no está vacío) y luego con las variables de entorno. Una
+// clave desconocida en el archivo es un error, para no ignorar errores de tipeo.
+func Load(path string, dst any) error {
+	v := reflect.ValueOf(dst)
+	if v.Kind() != reflect.Pointer || v.Elem().Kind() != reflect.Struct {
+		return errors.New("config: dst must be a pointer to a struct")
+	}
+
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		if err := dec.Decode(dst); err != nil && !errors.Is(err, io.EOF) {
+			return fmt.Errorf("config %s: %w", path, err)
+		}
+	}
+	return applyEnv(v.Elem())
+}
+
+func applyEnv(v reflect.Value) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field, fv := t.Field(i), v.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		if fv.Kind() == reflect.Struct && fv.Type() != durationType {
+			if err := applyEnv(fv); err != nil {
+				return err
+			}
+			continue
+		}
+		name := field.Tag.Get("env")
+		if name == "" {
+			continue
+		}
+		raw, ok := os.LookupEnv(name)
+		if !ok || raw == "" {
+			continue
+		}
+		if err := setValue(fv, raw); err != nil {
+			return fmt.Errorf("config: %s=%q: %w", name, raw, err)
+		}
+	}
+	return nil
+}
+
+func setValue(v reflect.Value, raw string) error {
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(raw)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int64, reflect.Int32:
+		n, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	case reflect.Slice:
+		if v.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("unsupported slice type %s", v.Type())
+		}
+		var items []string
+		for _, s := range strings.Split(raw, ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				items = append(items, s)
+			}
+		}
+		v.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
+
+// Redacted devuelve cfg como YAML con los campos secret:"true" ocultos,
+// para poder mostrar la configuración efectiva en los logs.
+func Redacted(cfg any) (string, error) {
+	out, err := yaml.Marshal(toMap(reflect.ValueOf(cfg)))
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
+
+func toMap(v reflect.Value) any {
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if v.Type() == durationType {
+		return time.Duration(v.Int()).String()
+	}
+	if v.Kind() == reflect.Map && v.Type().Key().Kind() == reflect.String {
+		m := yaml.Node{Kind: yaml.MappingNode}
+		keys := v.MapKeys()
+		slices.SortFunc(keys, func(a, b reflect.Value) int { return strings.Compare(a.String(), b.String()) })
+		for _, k := range keys {
+			var key, val yaml.Node
+			key.SetString(k.String())
+			if err := val.Encode(toMap(v.MapIndex(k))); err != nil {
+				return nil
+			}
+			m.Content = append(m.Content, &key, &val)
+		}
+		return &m
+	}
+	if v.Kind() != reflect.Struct {
+		return v.Interface()
+	}
+
+	m := yaml.Node{Kind: yaml.MappingNode}
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := strings.Split(field.Tag.Get("yaml"), ",")[0]
+		if !field.IsExported() || name == "-" {
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(field.Name)
+		}
+		var value any = toMap(v.Field(i))
+		if field.Tag.Get("secret") == "true" && !v.Field(i).IsZero() {
+			value = redacted
+		}
+		var key, val yaml.Node
+		key.SetString(name)
+		if err := val.Encode(value); err != nil {
+			val.SetString(fmt.Sprint(value))
+		}
+		m.Content = append(m.Content, &key, &val)
+	}
+	return &m
+}
+
+// ReadSecretFile lee un secreto de un archivo (al estilo de los secrets de
+// Docker, montados en /run/secrets), sin el salto de línea final.
+func ReadSecretFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
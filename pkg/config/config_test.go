@@ -0,0 +1,105 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testConfig struct {
+	Name     string        `yaml:"name" env:"TEST_NAME"`
+	Port     int           `yaml:"port" env:"TEST_PORT"`
+	Debug    bool          `yaml:"debug" env:"TEST_DEBUG"`
+	Timeout  time.Duration `yaml:"timeout" env:"TEST_TIMEOUT"`
+	Tags     []string      `yaml:"tags" env:"TEST_TAGS"`
+	Password string        `yaml:"password" env:"TEST_PASSWORD" secret:"true"`
+	Token    string        `yaml:"token" secret:"true"`
+	Nested   struct {
+		Ratio float64 `yaml:"ratio" env:"TEST_RATIO"`
+	} `yaml:"nested"`
+}
+
+func writeFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	path := writeFile(t, "name: archivo\nport: 9000\ntimeout: 5s\n")
+	t.Setenv("TEST_PORT", "9100")
+	t.Setenv("TEST_DEBUG", "true")
+	t.Setenv("TEST_TAGS", "a, b,,c")
+	t.Setenv("TEST_RATIO", "0.5")
+	t.Setenv("TEST_NAME", "") // vacía no pisa
+
+	cfg := testConfig{Name: "defecto", Port: 1, Timeout: time.Second}
+	if err := Load(path, &cfg); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Name != "archivo" {
+		t.Errorf("Name = %q, quería el del archivo", cfg.Name)
+	}
+	if cfg.Port != 9100 {
+		t.Errorf("Port = %d, quería el del entorno (pisa al archivo)", cfg.Port)
+	}
+	if cfg.Timeout != 5*time.Second || !cfg.Debug || cfg.Nested.Ratio != 0.5 {
+		t.Errorf("Timeout, Debug, Ratio = %s, %v, %v", cfg.Timeout, cfg.Debug, cfg.Nested.Ratio)
+	}
+	if strings.Join(cfg.Tags, "|") != "a|b|c" {
+		t.Errorf("Tags = %q", cfg.Tags)
+	}
+
+	// Sin archivo quedan los valores por defecto.
+	cfg = testConfig{Name: "defecto"}
+	if err := Load("", &cfg); err != nil || cfg.Name != "defecto" {
+		t.Errorf("sin archivo: Name = %q, %v", cfg.Name, err)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	var cfg testConfig
+	if err := Load(writeFile(t, "nmae: typo\n"), &cfg); err == nil {
+		t.Error("clave desconocida en el archivo = nil, quería un error")
+	}
+	if err := Load(filepath.Join(t.TempDir(), "no-existe.yaml"), &cfg); err == nil {
+		t.Error("archivo inexistente = nil, quería un error")
+	}
+	if err := Load("", cfg); err == nil {
+		t.Error("dst sin puntero = nil, quería un error")
+	}
+	t.Setenv("TEST_TIMEOUT", "cinco")
+	if err := Load("", &cfg); err == nil || !strings.Contains(err.Error(), "TEST_TIMEOUT") {
+		t.Errorf("duración inválida = %v, quería un error que nombre TEST_TIMEOUT", err)
+	}
+}
+
+func TestRedacted(t *testing.T) {
+	cfg := testConfig{Name: "svc", Password: "hunter2", Timeout: time.Minute}
+	out, err := Redacted(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(out, "hunter2") || !strings.Contains(out, redacted) {
+		t.Errorf("Redacted no oculta el secreto:\n%s", out)
+	}
+	// Un secreto vacío se muestra vacío: así se ve que falta.
+	if !strings.Contains(out, `token: ""`) {
+		t.Errorf("Redacted oculta un secreto vacío:\n%s", out)
+	}
+	if !strings.Contains(out, "name: svc") || !strings.Contains(out, "timeout: 1m0s") {
+		t.Errorf("Redacted no muestra los valores normales:\n%s", out)
+	}
+}
+
+func TestReadSecretFile(t *testing.T) {
+	got, err := ReadSecretFile(writeFile(t, "s3cr3t\r\n"))
+	if err != nil || got != "s3cr3t" {
+		t.Errorf("ReadSecretFile = %q, %v; quería el secreto sin el salto de línea", got, err)
+	}
+}
@@ -0,0 +1,167 @@
+package consul
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	consul "github.com/hashicorp/consul/api"
+
+	discovery "proyecto/pkg/registry"
+)
+
+const (
+	// Cuánto espera Consul en cada consulta bloqueante si no hay cambios.
+	blockingWait = 5 * time.Minute
+	minRetry     = time.Second
+	maxRetry     = 30 * time.Second
+)
+
+// CachedRegistry es un Registry que guarda en memoria las instancias que atienden de
+// cada servicio y las mantiene al día con consultas bloqueantes de Consul
+// (WaitIndex), en vez de consultar Consul en cada ServiceAddress. Si Consul
+// deja de responder se siguen sirviendo las últimas instancias conocidas
+// durante maxStale (0 = sin límite).
+type CachedRegistry struct {
+	*Registry
+	maxStale time.Duration
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu       sync.Mutex
+	services map[string]*serviceWatch // por servicio + tags pedidos (watchKey)
+	now      func() time.Time
+}
+
+type serviceWatch struct {
+	service string
+	tags    []string
+
+	ready     chan struct{} // se cierra tras la primera consulta (salga bien o mal)
+	readyOnce sync.Once
+
+	mu        sync.Mutex
+	addrs     []string
+	index     uint64
+	updatedAt time.Time // última consulta correcta
+	err       error     // error de la última consulta, nil si salió bien
+}
+
+func NewCachedRegistry(r *Registry, maxStale time.Duration) *CachedRegistry {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &CachedRegistry{
+		Registry: r,
+		maxStale: maxStale,
+		ctx:      ctx,
+		cancel:   cancel,
+		services: map[string]*serviceWatch{},
+		now:      time.Now,
+	}
+}
+
+// Close para todas las consultas en curso.
+func (c *CachedRegistry) Close() {
+	c.cancel()
+}
+
+// ServiceAddress devuelve las instancias sanas desde la caché. La primera
+// llamada para un servicio (y filtro de tags) arranca su vigilancia y espera
+// la primera respuesta.
+func (c *CachedRegistry) ServiceAddress(ctx context.Context, serviceName string, opts ...discovery.QueryOption) ([]string, error) {
+	w := c.watch(serviceName, discovery.NewQuery(opts...))
+	select {
+	case <-w.ready:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.err != nil && (w.updatedAt.IsZero() || (c.maxStale > 0 && c.now().Sub(w.updatedAt) > c.maxStale)) {
+		return nil, fmt.Errorf("resolving %s: %w", serviceName, w.err)
+	}
+	if len(w.addrs) == 0 {
+		return nil, discovery.ErrNotFound
+	}
+	return slices.Clone(w.addrs), nil
+}
+
+func (c *CachedRegistry) watch(serviceName string, q discovery.Query) *serviceWatch {
+	key, tags := watchKey(serviceName, q)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	w, ok := c.services[key]
+	if !ok {
+		w = &serviceWatch{service: serviceName, tags: tags, ready: make(chan struct{})}
+		c.services[key] = w
+		go c.run(w)
+	}
+	return w
+}
+
+// watchKey identifica la vigilancia de un servicio con un filtro de tags; el
+// orden de los tags no importa.
+func watchKey(serviceName string, q discovery.Query) (key string, tags []string) {
+	tags = slices.Clone(q.Tags)
+	slices.Sort(tags)
+	return serviceName + "|" + strings.Join(tags, ","), tags
+}
+
+// run repite la consulta bloqueante: Consul solo responde cuando el índice
+// del servicio pasa de w.index o cuando vence blockingWait.
+func (c *CachedRegistry) run(w *serviceWatch) {
+	serviceName := w.service
+	retry := minRetry
+	for {
+		opts := (&consul.QueryOptions{WaitIndex: w.index, WaitTime: blockingWait}).WithContext(c.ctx)
+		entries, meta, err := c.client.Health().ServiceMultipleTags(serviceName, w.tags, false, opts)
+		if c.ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			log.Printf("Consul: error consultando %s, se usan las últimas instancias conocidas: %v", serviceName, err)
+			w.fail(err)
+			select {
+			case <-time.After(retry):
+			case <-c.ctx.Done():
+				return
+			}
+			retry = min(retry*2, maxRetry)
+			continue
+		}
+		retry = minRetry
+
+		addrs := serving(entries)
+		slices.Sort(addrs)
+
+		// Si el índice retrocede (p.ej. Consul se reinició) hay que empezar de cero.
+		index := meta.LastIndex
+		if index < w.index {
+			index = 0
+		}
+		w.update(addrs, index, c.now())
+	}
+}
+
+func (w *serviceWatch) update(addrs []string, index uint64, now time.Time) {
+	w.mu.Lock()
+	w.addrs = addrs
+	w.index = index
+	w.updatedAt = now
+	w.err = nil
+	w.mu.Unlock()
+	w.readyOnce.Do(func() { close(w.ready) })
+}
+
+func (w *serviceWatch) fail(err error) {
+	w.mu.Lock()
+	w.err = err
+	w.mu.Unlock()
+	w.readyOnce.Do(func() { close(w.ready) })
+}
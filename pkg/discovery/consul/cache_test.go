@@ -0,0 +1,152 @@
+package consul
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	consul "github.com/hashicorp/consul/api"
+
+	discovery "proyecto/pkg/registry"
+)
+
+// testClock es un reloj que solo avanza cuando el test lo pide.
+type testClock struct{ t time.Time }
+
+func (c *testClock) now() time.Time          { return c.t }
+func (c *testClock) advance(d time.Duration) { c.t = c.t.Add(d) }
+
+// newTestCache crea una caché sin Consul: las vigilancias se cargan a mano
+// con preload, así que nunca arranca una consulta.
+func newTestCache(t *testing.T, maxStale time.Duration) (*CachedRegistry, *testClock) {
+	clock := &testClock{t: time.Unix(1_700_000_000, 0)}
+	c := NewCachedRegistry(&Registry{}, maxStale)
+	c.now = clock.now
+	t.Cleanup(c.Close)
+	return c, clock
+}
+
+// preload deja en la caché la vigilancia de service como si ya hubiera
+// respondido Consul.
+func preload(c *CachedRegistry, service string) *serviceWatch {
+	key, tags := watchKey(service, discovery.NewQuery())
+	w := &serviceWatch{service: service, tags: tags, ready: make(chan struct{})}
+	c.services[key] = w
+	return w
+}
+
+func TestServiceAddressMaxStale(t *testing.T) {
+	c, clock := newTestCache(t, time.Minute)
+	ctx := context.Background()
+	w := preload(c, "svc")
+	w.update([]string{"10.0.0.1:80"}, 1, clock.now())
+
+	// Consul deja de responder: se sirven las últimas instancias hasta maxStale.
+	clock.advance(30 * time.Second)
+	w.fail(errors.New("consul caído"))
+	if got, err := c.ServiceAddress(ctx, "svc"); err != nil || !slices.Equal(got, []string{"10.0.0.1:80"}) {
+		t.Errorf("dentro de maxStale = %v, %v; quería la última lista", got, err)
+	}
+	clock.advance(30 * time.Second)
+	if _, err := c.ServiceAddress(ctx, "svc"); err != nil {
+		t.Errorf("justo en maxStale = %v, quería la última lista", err)
+	}
+	clock.advance(time.Second)
+	if _, err := c.ServiceAddress(ctx, "svc"); err == nil || errors.Is(err, discovery.ErrNotFound) {
+		t.Errorf("pasado maxStale = %v, quería el error de Consul", err)
+	}
+
+	// En cuanto Consul vuelve a responder se sirve de nuevo.
+	w.update([]string{"10.0.0.2:80"}, 2, clock.now())
+	if got, err := c.ServiceAddress(ctx, "svc"); err != nil || !slices.Equal(got, []string{"10.0.0.2:80"}) {
+		t.Errorf("tras recuperarse = %v, %v", got, err)
+	}
+}
+
+func TestServiceAddressNoMaxStale(t *testing.T) {
+	c, clock := newTestCache(t, 0)
+	w := preload(c, "svc")
+	w.update([]string{"10.0.0.1:80"}, 1, clock.now())
+	w.fail(errors.New("consul caído"))
+	clock.advance(24 * time.Hour)
+	if _, err := c.ServiceAddress(context.Background(), "svc"); err != nil {
+		t.Errorf("con maxStale 0 = %v, quería la última lista sin límite", err)
+	}
+}
+
+func TestServiceAddressWithoutData(t *testing.T) {
+	c, clock := newTestCache(t, time.Minute)
+	ctx := context.Background()
+
+	// Sin ninguna respuesta buena no hay lista que servir.
+	preload(c, "caido").fail(errors.New("consul caído"))
+	if _, err := c.ServiceAddress(ctx, "caido"); err == nil || errors.Is(err, discovery.ErrNotFound) {
+		t.Errorf("sin respuesta previa = %v, quería el error de Consul", err)
+	}
+
+	preload(c, "vacio").update(nil, 1, clock.now())
+	if _, err := c.ServiceAddress(ctx, "vacio"); !errors.Is(err, discovery.ErrNotFound) {
+		t.Errorf("sin instancias sanas = %v, quería ErrNotFound", err)
+	}
+
+	// Mientras la primera consulta no responde se respeta el contexto.
+	preload(c, "lento")
+	ctx, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := c.ServiceAddress(ctx, "lento"); !errors.Is(err, context.Canceled) {
+		t.Errorf("con el contexto cancelado = %v, quería context.Canceled", err)
+	}
+}
+
+func TestWatchKeyIgnoresTagOrder(t *testing.T) {
+	a, _ := watchKey("svc", discovery.NewQuery(discovery.WithTag("b"), discovery.WithTag("a")))
+	b, _ := watchKey("svc", discovery.NewQuery(discovery.WithTag("a"), discovery.WithTag("b")))
+	if a != b {
+		t.Errorf("watchKey depende del orden de los tags: %q y %q", a, b)
+	}
+}
+
+// TestCachedRegistryConsul prueba la consulta bloqueante contra un Consul de
+// mentira que responde una vez y después espera cambios.
+func TestCachedRegistryConsul(t *testing.T) {
+	entries := []*consul.ServiceEntry{
+		{Service: &consul.AgentService{Address: "10.0.0.1", Port: 80}, Checks: consul.HealthChecks{{Status: consul.HealthPassing}}},
+		{Service: &consul.AgentService{Address: "10.0.0.2", Port: 80}, Checks: consul.HealthChecks{{Status: consul.HealthWarning}}},
+		{Service: &consul.AgentService{Address: "10.0.0.3", Port: 80}, Checks: consul.HealthChecks{{Status: consul.HealthCritical}}},
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if !strings.HasPrefix(req.URL.Path, "/v1/health/service/svc") {
+			http.NotFound(w, req)
+			return
+		}
+		if req.URL.Query().Get("index") == "7" {
+			// Sin cambios: Consul tiene la consulta abierta hasta el WaitTime.
+			<-req.Context().Done()
+			return
+		}
+		w.Header().Set("X-Consul-Index", "7")
+		_ = json.NewEncoder(w).Encode(entries)
+	}))
+	defer srv.Close()
+
+	r, err := NewRegistry(strings.TrimPrefix(srv.URL, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := NewCachedRegistry(r, time.Minute)
+	defer c.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	got, err := c.ServiceAddress(ctx, "svc")
+	// Las instancias en warning siguen atendiendo; las critical no.
+	if err != nil || !slices.Equal(got, []string{"10.0.0.1:80", "10.0.0.2:80"}) {
+		t.Errorf("ServiceAddress = %v, %v", got, err)
+	}
+}
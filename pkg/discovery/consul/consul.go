@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+	"time"
 
 	discovery "proyecto/pkg/registry"
 	consul "github.com/hashicorp/consul/api"
@@ -25,7 +26,7 @@ func NewRegistry(addr string) (*Registry, error) {
 	return &Registry{client: client}, nil
 }
 
-func (r *Registry) Register(ctx context.Context, instanceID string, serviceName string, hostPort string) error {
+func (r *Registry) Register(ctx context.Context, instanceID string, serviceName string, hostPort string, opts ...discovery.RegisterOption) error {
 	parts := strings.Split(hostPort, ":")
 	if len(parts) != 2 {
 		return errors.New("Hostport must be in a form of <host>:<port>, example: localhost:8081")
@@ -34,28 +35,47 @@ func (r *Registry) Register(ctx context.Context, instanceID string, serviceName
 	if err != nil {
 		return err
 	}
+	reg := discovery.NewRegistration(opts...)
+	// El latido TTL (ReportHealthyState) siempre; el check HTTP solo si se pidió.
+	checks := consul.AgentServiceChecks{{CheckID: instanceID, TTL: "5s"}}
+	if reg.HTTPCheck != "" {
+		interval := reg.CheckInterval
+		if interval <= 0 {
+			interval = 10 * time.Second
+		}
+		checks = append(checks, &consul.AgentServiceCheck{
+			CheckID:  instanceID + ":http",
+			Name:     "HTTP " + reg.HTTPCheck,
+			HTTP:     "http://" + hostPort + reg.HTTPCheck,
+			Interval: interval.String(),
+			Timeout:  "2s",
+		})
+	}
 	return r.client.Agent().ServiceRegister(&consul.AgentServiceRegistration{
 		Address: parts[0],
 		ID:      instanceID,
 		Name:    serviceName,
 		Port:    port,
-		Check:   &consul.AgentServiceCheck{CheckID: instanceID, TTL: "5s"},
+		Tags:    reg.Tags,
+		Meta:    reg.Meta,
+		Checks:  checks,
 	})
 }
 func (r *Registry) Deregister(ctx context.Context, instanceID string, _ string) error {
 	return r.client.Agent().ServiceDeregister(instanceID)
 }
 
-func (r *Registry) ServiceAddress(ctx context.Context, serviceName string) ([]string, error) {
-	entries, _, err := r.client.Health().Service(serviceName, "", true, nil)
+// ServiceAddress devuelve las instancias que no están en critical (las que
+// están en warning siguen atendiendo) y tienen todos los tags pedidos.
+func (r *Registry) ServiceAddress(ctx context.Context, serviceName string, opts ...discovery.QueryOption) ([]string, error) {
+	q := discovery.NewQuery(opts...)
+	entries, _, err := r.client.Health().ServiceMultipleTags(serviceName, q.Tags, false, nil)
 	if err != nil {
 		return nil, err
-	} else if len(entries) == 0 {
-		return nil, discovery.ErrNotFound
 	}
-	var res []string
-	for _, e := range entries {
-		res = append(res, fmt.Sprintf("%s:%d", e.Service.Address, e.Service.Port))
+	res := serving(entries)
+	if len(res) == 0 {
+		return nil, discovery.ErrNotFound
 	}
 	return res, nil
 }
@@ -63,3 +83,20 @@ func (r *Registry) ServiceAddress(ctx context.Context, serviceName string) ([]st
 func (r *Registry) ReportHealthyState(instanceID string, _ string) error {
 	return r.client.Agent().PassTTL(instanceID, "")
 }
+
+// ReportState actualiza el check TTL de la instancia con el estado y el detalle.
+func (r *Registry) ReportState(instanceID string, _ string, state discovery.HealthState, output string) error {
+	return r.client.Agent().UpdateTTL(instanceID, output, string(state))
+}
+
+// serving devuelve la dirección de las instancias cuyo peor check no es critical.
+func serving(entries []*consul.ServiceEntry) []string {
+	var res []string
+	for _, e := range entries {
+		if e.Checks.AggregatedStatus() == consul.HealthCritical {
+			continue
+		}
+		res = append(res, fmt.Sprintf("%s:%d", e.Service.Address, e.Service.Port))
+	}
+	return res
+}
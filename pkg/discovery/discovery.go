@@ -0,0 +1,65 @@
+// Package discovery elige la implementación de registry.Registry según la configuración.
+package discovery
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"proyecto/pkg/discovery/consul"
+	"proyecto/pkg/discovery/memory"
+	"proyecto/pkg/discovery/static"
+	registry "proyecto/pkg/registry"
+)
+
+type Config struct {
+	Backend    string        `yaml:"backend" env:"REGISTRY_BACKEND"`     // consul (por defecto), memory o static
+	ConsulAddr string        `yaml:"consul_addr" env:"CONSUL_HOST"`      // dirección del agente de Consul
+	File       string        `yaml:"file" env:"REGISTRY_FILE"`           // archivo del registry static
+	MaxStale   time.Duration `yaml:"max_stale" env:"REGISTRY_MAX_STALE"` // cuánto se usan las direcciones cacheadas si Consul no responde
+}
+
+func DefaultConfig() Config {
+	return Config{Backend: "consul", ConsulAddr: "localhost:8500", MaxStale: 5 * time.Minute}
+}
+
+func (c Config) Validate() error {
+	switch c.Backend {
+	case "consul":
+		if c.ConsulAddr == "" {
+			return errors.New("registry.consul_addr (CONSUL_HOST) es obligatorio con el registry consul")
+		}
+	case "memory":
+	case "static":
+		if c.File == "" {
+			return errors.New("registry.file (REGISTRY_FILE) es obligatorio con el registry static")
+		}
+	default:
+		return fmt.Errorf("registry.backend desconocido: %q (consul, memory o static)", c.Backend)
+	}
+	return nil
+}
+
+// New crea el registry. close libera lo que use (p.ej. las consultas a Consul).
+func New(cfg Config) (reg registry.Registry, close func(), err error) {
+	switch cfg.Backend {
+	case "consul":
+		consulReg, err := consul.NewRegistry(cfg.ConsulAddr)
+		if err != nil {
+			return nil, nil, err
+		}
+		// Las direcciones de los otros servicios se sirven desde caché, que
+		// Consul mantiene al día; si Consul se cae se siguen usando hasta MaxStale.
+		cached := consul.NewCachedRegistry(consulReg, cfg.MaxStale)
+		return cached, cached.Close, nil
+	case "memory":
+		return memory.NewRegistry(memory.DefaultTTL), func() {}, nil
+	case "static":
+		staticReg, err := static.NewRegistry(cfg.File)
+		if err != nil {
+			return nil, nil, err
+		}
+		return staticReg, func() {}, nil
+	}
+	return nil, nil, fmt.Errorf("registry desconocido: %q", cfg.Backend)
+}
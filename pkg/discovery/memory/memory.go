@@ -0,0 +1,102 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	discovery "proyecto/pkg/registry"
+)
+
+// DefaultTTL es el mismo TTL que usa el check de Consul.
+const DefaultTTL = 5 * time.Second
+
+// ErrNotRegistered lo devuelve ReportHealthyState para una instancia que no se registró.
+var ErrNotRegistered = errors.New("instance not registered")
+
+// Registry es un registry en memoria con la misma semántica que el check TTL
+// de Consul: una instancia recién registrada no está sana hasta su primer
+// ReportHealthyState, y deja de estarlo si pasa más de ttl sin reportar.
+// Solo ve las instancias del propio proceso: sirve para desarrollo y tests.
+type Registry struct {
+	ttl  time.Duration
+	mu   sync.RWMutex
+	data map[string]map[string]*instance
+	now  func() time.Time
+}
+
+type instance struct {
+	hostPort   string
+	reg        discovery.Registration
+	lastActive time.Time
+	state      discovery.HealthState
+	output     string
+}
+
+func NewRegistry(ttl time.Duration) *Registry {
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+	return &Registry{ttl: ttl, data: map[string]map[string]*instance{}, now: time.Now}
+}
+
+// Register guarda la instancia con sus tags y metadatos. El check HTTP se
+// ignora: aquí la salud solo depende del latido.
+func (r *Registry) Register(_ context.Context, instanceID string, serviceName string, hostPort string, opts ...discovery.RegisterOption) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.data[serviceName]; !ok {
+		r.data[serviceName] = map[string]*instance{}
+	}
+	r.data[serviceName][instanceID] = &instance{hostPort: hostPort, reg: discovery.NewRegistration(opts...)}
+	return nil
+}
+
+func (r *Registry) Deregister(_ context.Context, instanceID string, serviceName string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.data[serviceName], instanceID)
+	if len(r.data[serviceName]) == 0 {
+		delete(r.data, serviceName)
+	}
+	return nil
+}
+
+func (r *Registry) ReportHealthyState(instanceID string, serviceName string) error {
+	return r.ReportState(instanceID, serviceName, discovery.HealthPassing, "")
+}
+
+// ReportState renueva el TTL con el estado dado; una instancia en critical
+// deja de devolverse hasta que vuelva a reportar passing o warning.
+func (r *Registry) ReportState(instanceID string, serviceName string, state discovery.HealthState, output string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	inst, ok := r.data[serviceName][instanceID]
+	if !ok {
+		return ErrNotRegistered
+	}
+	inst.lastActive = r.now()
+	inst.state = state
+	inst.output = output
+	return nil
+}
+
+// ServiceAddress devuelve las instancias que reportaron dentro del TTL, no
+// están en critical y tienen los tags pedidos.
+func (r *Registry) ServiceAddress(_ context.Context, serviceName string, opts ...discovery.QueryOption) ([]string, error) {
+	q := discovery.NewQuery(opts...)
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var res []string
+	for _, inst := range r.data[serviceName] {
+		if inst.lastActive.IsZero() || r.now().Sub(inst.lastActive) > r.ttl || inst.state == discovery.HealthCritical || !q.Matches(inst.reg.Tags) {
+			continue
+		}
+		res = append(res, inst.hostPort)
+	}
+	if len(res) == 0 {
+		return nil, discovery.ErrNotFound
+	}
+	return res, nil
+}
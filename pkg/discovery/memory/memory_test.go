@@ -0,0 +1,117 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	discovery "proyecto/pkg/registry"
+)
+
+// testClock es un reloj que solo avanza cuando el test lo pide.
+type testClock struct{ t time.Time }
+
+func (c *testClock) now() time.Time          { return c.t }
+func (c *testClock) advance(d time.Duration) { c.t = c.t.Add(d) }
+
+func newTestRegistry(ttl time.Duration) (*Registry, *testClock) {
+	clock := &testClock{t: time.Unix(1_700_000_000, 0)}
+	r := NewRegistry(ttl)
+	r.now = clock.now
+	return r, clock
+}
+
+// addresses es ServiceAddress ordenado, con nil si no hay ninguna.
+func addresses(t *testing.T, r *Registry, service string, opts ...discovery.QueryOption) []string {
+	t.Helper()
+	addrs, err := r.ServiceAddress(context.Background(), service, opts...)
+	if errors.Is(err, discovery.ErrNotFound) {
+		return nil
+	} else if err != nil {
+		t.Fatal(err)
+	}
+	slices.Sort(addrs)
+	return addrs
+}
+
+func TestRegisterDeregister(t *testing.T) {
+	r, _ := newTestRegistry(time.Second)
+	ctx := context.Background()
+	r.Register(ctx, "a", "svc", "host-a:1")
+	r.Register(ctx, "b", "svc", "host-b:1")
+
+	// Hasta el primer latido no está sana, como con el check TTL de Consul.
+	if got := addresses(t, r, "svc"); got != nil {
+		t.Errorf("antes del primer latido = %v, quería ninguna", got)
+	}
+	r.ReportHealthyState("a", "svc")
+	r.ReportHealthyState("b", "svc")
+	if got := addresses(t, r, "svc"); !slices.Equal(got, []string{"host-a:1", "host-b:1"}) {
+		t.Errorf("registradas = %v", got)
+	}
+
+	r.Deregister(ctx, "a", "svc")
+	if got := addresses(t, r, "svc"); !slices.Equal(got, []string{"host-b:1"}) {
+		t.Errorf("tras Deregister = %v, quería solo host-b:1", got)
+	}
+	if err := r.ReportHealthyState("a", "svc"); !errors.Is(err, ErrNotRegistered) {
+		t.Errorf("latido tras Deregister = %v, quería ErrNotRegistered", err)
+	}
+	r.Deregister(ctx, "b", "svc")
+	if _, ok := r.data["svc"]; ok {
+		t.Error("el servicio sin instancias sigue en el mapa")
+	}
+}
+
+func TestTTLExpiry(t *testing.T) {
+	r, clock := newTestRegistry(5 * time.Second)
+	r.Register(context.Background(), "a", "svc", "host-a:1")
+	r.ReportHealthyState("a", "svc")
+
+	clock.advance(5 * time.Second)
+	if got := addresses(t, r, "svc"); got == nil {
+		t.Error("vencida justo al cumplir el TTL")
+	}
+	clock.advance(time.Millisecond)
+	if got := addresses(t, r, "svc"); got != nil {
+		t.Errorf("pasado el TTL sin latido = %v, quería ninguna", got)
+	}
+	r.ReportHealthyState("a", "svc")
+	if got := addresses(t, r, "svc"); got == nil {
+		t.Error("un latido nuevo no la vuelve a poner en servicio")
+	}
+}
+
+func TestHealthStates(t *testing.T) {
+	r, _ := newTestRegistry(time.Second)
+	r.Register(context.Background(), "a", "svc", "host-a:1")
+
+	for state, serving := range map[discovery.HealthState]bool{
+		discovery.HealthPassing:  true,
+		discovery.HealthWarning:  true,
+		discovery.HealthCritical: false,
+	} {
+		r.ReportState("a", "svc", state, "checks")
+		if got := addresses(t, r, "svc"); (got != nil) != serving {
+			t.Errorf("en %s = %v, quería en servicio %v", state, got, serving)
+		}
+	}
+}
+
+func TestTagFilter(t *testing.T) {
+	r, _ := newTestRegistry(time.Second)
+	ctx := context.Background()
+	r.Register(ctx, "v1", "svc", "host-v1:1", discovery.WithVersion("v1"))
+	r.Register(ctx, "v2", "svc", "host-v2:1", discovery.WithVersion("v2"), discovery.WithTags("zone=a"))
+	r.ReportHealthyState("v1", "svc")
+	r.ReportHealthyState("v2", "svc")
+
+	if got := addresses(t, r, "svc", discovery.WithTag(discovery.VersionTag("v2"))); !slices.Equal(got, []string{"host-v2:1"}) {
+		t.Errorf("version=v2 = %v", got)
+	}
+	if got := addresses(t, r, "svc", discovery.WithTag("version=v1"), discovery.WithTag("zone=a")); got != nil {
+		t.Errorf("version=v1 y zone=a = %v, quería ninguna", got)
+	}
+}
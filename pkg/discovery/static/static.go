@@ -0,0 +1,100 @@
+package static
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+
+	"gopkg.in/yaml.v3"
+
+	discovery "proyecto/pkg/registry"
+)
+
+// Registry resuelve los servicios con una lista fija leída de un archivo
+// YAML o JSON. Cada instancia es una dirección o, si lleva tags, un objeto:
+//
+//	services:
+//	  auth-server: ["localhost:8082"]
+//	  metadata-user:
+//	    - localhost:8081
+//	    - address: localhost:8083
+//	      tags: ["version=v2"]
+//
+// Registrarse y reportar salud no hacen nada: las instancias del archivo se
+// consideran siempre sanas.
+type Registry struct {
+	services map[string][]Instance
+}
+
+type Instance struct {
+	Address string   `yaml:"address"`
+	Tags    []string `yaml:"tags"`
+}
+
+// UnmarshalYAML acepta tanto "host:port" como {address, tags}.
+func (i *Instance) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		return node.Decode(&i.Address)
+	}
+	type plain Instance
+	return node.Decode((*plain)(i))
+}
+
+type file struct {
+	Services map[string][]Instance `yaml:"services"`
+}
+
+// NewRegistry lee el archivo (YAML; el JSON también es YAML válido).
+func NewRegistry(path string) (*Registry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var f file
+	if err := yaml.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	for name, instances := range f.Services {
+		for _, inst := range instances {
+			if _, _, err := net.SplitHostPort(inst.Address); err != nil {
+				return nil, fmt.Errorf("%s: service %s: invalid address %q: %w", path, name, inst.Address, err)
+			}
+		}
+	}
+	if len(f.Services) == 0 {
+		return nil, errors.New(path + ": no services defined")
+	}
+	return &Registry{services: f.Services}, nil
+}
+
+func (r *Registry) Register(context.Context, string, string, string, ...discovery.RegisterOption) error {
+	return nil
+}
+
+func (r *Registry) Deregister(context.Context, string, string) error {
+	return nil
+}
+
+func (r *Registry) ReportHealthyState(string, string) error {
+	return nil
+}
+
+func (r *Registry) ReportState(string, string, discovery.HealthState, string) error {
+	return nil
+}
+
+func (r *Registry) ServiceAddress(_ context.Context, serviceName string, opts ...discovery.QueryOption) ([]string, error) {
+	q := discovery.NewQuery(opts...)
+	var res []string
+	for _, inst := range r.services[serviceName] {
+		if q.Matches(inst.Tags) {
+			res = append(res, inst.Address)
+		}
+	}
+	if len(res) == 0 {
+		return nil, discovery.ErrNotFound
+	}
+	return res, nil
+}
@@ -0,0 +1,81 @@
+package static
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	discovery "proyecto/pkg/registry"
+)
+
+func writeFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "registry.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestNewRegistry(t *testing.T) {
+	r, err := NewRegistry(writeFile(t, `
+services:
+  auth-server: ["localhost:8082"]
+  metadata-user:
+    - localhost:8081
+    - address: localhost:8083
+      tags: ["version=v2"]
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	got, err := r.ServiceAddress(ctx, "metadata-user")
+	if err != nil || !slices.Equal(got, []string{"localhost:8081", "localhost:8083"}) {
+		t.Errorf("metadata-user = %v, %v", got, err)
+	}
+	got, err = r.ServiceAddress(ctx, "metadata-user", discovery.WithTag(discovery.VersionTag("v2")))
+	if err != nil || !slices.Equal(got, []string{"localhost:8083"}) {
+		t.Errorf("metadata-user version=v2 = %v, %v", got, err)
+	}
+	if _, err := r.ServiceAddress(ctx, "metadata-user", discovery.WithTag("version=v3")); !errors.Is(err, discovery.ErrNotFound) {
+		t.Errorf("version=v3 = %v, quería ErrNotFound", err)
+	}
+	if _, err := r.ServiceAddress(ctx, "otro"); !errors.Is(err, discovery.ErrNotFound) {
+		t.Errorf("servicio desconocido = %v, quería ErrNotFound", err)
+	}
+
+	// El JSON también es YAML válido.
+	r, err = NewRegistry(writeFile(t, `{"services": {"auth-server": ["127.0.0.1:8082"]}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := r.ServiceAddress(ctx, "auth-server"); !slices.Equal(got, []string{"127.0.0.1:8082"}) {
+		t.Errorf("desde JSON = %v", got)
+	}
+}
+
+func TestNewRegistryErrors(t *testing.T) {
+	for name, content := range map[string]string{
+		"sin puerto":   "services:\n  svc: [localhost]\n",
+		"objeto":       "services:\n  svc:\n    - address: localhost\n",
+		"sin servicio": "services: {}\n",
+		"YAML roto":    "services: [\n",
+	} {
+		if _, err := NewRegistry(writeFile(t, content)); err == nil {
+			t.Errorf("%s: NewRegistry = nil, quería un error", name)
+		}
+	}
+	if _, err := NewRegistry(filepath.Join(t.TempDir(), "no-existe.yaml")); err == nil {
+		t.Error("archivo inexistente: NewRegistry = nil, quería un error")
+	}
+	_, err := NewRegistry(writeFile(t, "services:\n  svc: [localhost]\n"))
+	if err == nil || !strings.Contains(err.Error(), `"localhost"`) {
+		t.Errorf("el error no dice qué dirección es inválida: %v", err)
+	}
+}
@@ -0,0 +1,45 @@
+package health
+
+import (
+	"context"
+	"errors"
+
+	registry "proyecto/pkg/registry"
+)
+
+// Pinger lo implementan los repositorios que pueden comprobar su conexión.
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// PingCheck comprueba v si implementa Pinger; si no (p.ej. un repositorio en
+// memoria) siempre pasa.
+func PingCheck(v any) CheckFunc {
+	return func(ctx context.Context) error {
+		if p, ok := v.(Pinger); ok {
+			return p.Ping(ctx)
+		}
+		return nil
+	}
+}
+
+// RegistryCheck comprueba que el registry responde buscando serviceName. Que
+// no haya instancias sanas (p.ej. la propia aún no pasó su primer check) no
+// es un fallo del registry.
+func RegistryCheck(reg registry.Registry, serviceName string) CheckFunc {
+	return func(ctx context.Context) error {
+		_, err := reg.ServiceAddress(ctx, serviceName)
+		if errors.Is(err, registry.ErrNotFound) {
+			return nil
+		}
+		return err
+	}
+}
+
+// ResolvableCheck comprueba que hay al menos una instancia sana de serviceName.
+func ResolvableCheck(reg registry.Registry, serviceName string, opts ...registry.QueryOption) CheckFunc {
+	return func(ctx context.Context) error {
+		_, err := reg.ServiceAddress(ctx, serviceName, opts...)
+		return err
+	}
+}
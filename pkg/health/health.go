@@ -0,0 +1,163 @@
+// Package health reúne los checks de salud de un servicio: los expone en
+// /healthz y /readyz y los reporta al registry en cada latido.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	registry "proyecto/pkg/registry"
+)
+
+// Severity indica qué estado toma el servicio cuando un check falla.
+type Severity int
+
+const (
+	// Critical: sin esto el servicio no puede atender (p.ej. el repositorio).
+	Critical Severity = iota
+	// Warning: el servicio atiende, pero degradado (p.ej. un servicio del que depende).
+	Warning
+)
+
+// CheckFunc devuelve nil si la dependencia está bien.
+type CheckFunc func(ctx context.Context) error
+
+type check struct {
+	name     string
+	severity Severity
+	fn       CheckFunc
+}
+
+// Result es el resultado de un check.
+type Result struct {
+	Name     string               `json:"name"`
+	Status   registry.HealthState `json:"status"`
+	Output   string               `json:"output,omitempty"`
+	Duration string               `json:"duration"`
+}
+
+// Report es el resultado de todos los checks; Status es el peor de ellos.
+type Report struct {
+	Status registry.HealthState `json:"status"`
+	Checks []Result             `json:"checks"`
+}
+
+// Output resume los checks que fallaron, para el registry.
+func (r Report) Output() string {
+	var failed []string
+	for _, c := range r.Checks {
+		if c.Status != registry.HealthPassing {
+			failed = append(failed, fmt.Sprintf("%s (%s): %s", c.Name, c.Status, c.Output))
+		}
+	}
+	if len(failed) == 0 {
+		return "all checks passing"
+	}
+	return strings.Join(failed, "; ")
+}
+
+// Checker corre los checks registrados, cada uno con su timeout.
+type Checker struct {
+	timeout time.Duration
+	mu      sync.RWMutex
+	checks  []check
+}
+
+func New(timeout time.Duration) *Checker {
+	if timeout <= 0 {
+		timeout = 2 * time.Second
+	}
+	return &Checker{timeout: timeout}
+}
+
+func (c *Checker) Add(name string, severity Severity, fn CheckFunc) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.checks = append(c.checks, check{name: name, severity: severity, fn: fn})
+}
+
+// Run corre todos los checks en paralelo.
+func (c *Checker) Run(ctx context.Context) Report {
+	c.mu.RLock()
+	checks := c.checks
+	c.mu.RUnlock()
+
+	results := make([]Result, len(checks))
+	var wg sync.WaitGroup
+	for i, ch := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(ctx, c.timeout)
+			defer cancel()
+
+			start := time.Now()
+			err := ch.fn(ctx)
+			res := Result{Name: ch.name, Status: registry.HealthPassing, Duration: time.Since(start).String()}
+			if err != nil {
+				res.Output = err.Error()
+				res.Status = registry.HealthCritical
+				if ch.severity == Warning {
+					res.Status = registry.HealthWarning
+				}
+			}
+			results[i] = res
+		}()
+	}
+	wg.Wait()
+
+	report := Report{Status: registry.HealthPassing, Checks: results}
+	for _, r := range results {
+		if r.Status == registry.HealthCritical || (r.Status == registry.HealthWarning && report.Status == registry.HealthPassing) {
+			report.Status = r.Status
+		}
+	}
+	return report
+}
+
+// LivenessHandler (/healthz) solo dice si el proceso atiende peticiones; no
+// mira dependencias, para que un fallo externo no haga reiniciar la instancia.
+func LivenessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]string{"status": string(registry.HealthPassing)})
+	})
+}
+
+// ReadinessHandler (/readyz) corre los checks: 503 si alguno crítico falla,
+// 200 si todo pasa o solo hay warnings.
+func (c *Checker) ReadinessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		report := c.Run(req.Context())
+		status := http.StatusOK
+		if report.Status == registry.HealthCritical {
+			status = http.StatusServiceUnavailable
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		_ = json.NewEncoder(w).Encode(report)
+	})
+}
+
+// Heartbeat corre los checks cada interval y reporta el resultado al registry
+// (passing, warning o critical con el detalle), hasta que se cancele ctx.
+func (c *Checker) Heartbeat(ctx context.Context, reg registry.Registry, instanceID, serviceName string, interval time.Duration, onError func(error)) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		report := c.Run(ctx)
+		if err := reg.ReportState(instanceID, serviceName, report.Status, report.Output()); err != nil && onError != nil {
+			onError(err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
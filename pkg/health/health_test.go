@@ -0,0 +1,95 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	registry "proyecto/pkg/registry"
+)
+
+func pass(context.Context) error { return nil }
+func fail(context.Context) error { return errors.New("caído") }
+
+func TestRunSeverity(t *testing.T) {
+	for name, tc := range map[string]struct {
+		warning, critical CheckFunc
+		want              registry.HealthState
+	}{
+		"todo bien":        {pass, pass, registry.HealthPassing},
+		"falla un warning": {fail, pass, registry.HealthWarning},
+		"falla un crítico": {pass, fail, registry.HealthCritical},
+		"fallan los dos":   {fail, fail, registry.HealthCritical},
+	} {
+		c := New(time.Second)
+		c.Add("dependencia", Warning, tc.warning)
+		c.Add("repositorio", Critical, tc.critical)
+		report := c.Run(context.Background())
+		if report.Status != tc.want {
+			t.Errorf("%s: Status = %s, quería %s", name, report.Status, tc.want)
+		}
+		if len(report.Checks) != 2 || report.Checks[0].Name != "dependencia" || report.Checks[1].Name != "repositorio" {
+			t.Errorf("%s: Checks = %+v, quería los dos en el orden en que se agregaron", name, report.Checks)
+		}
+	}
+}
+
+func TestRunTimeout(t *testing.T) {
+	c := New(50 * time.Millisecond)
+	c.Add("colgado", Critical, func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	c.Add("rápido", Warning, pass)
+
+	start := time.Now()
+	report := c.Run(context.Background())
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Run tardó %s con un timeout de 50ms", elapsed)
+	}
+	if report.Status != registry.HealthCritical || !strings.Contains(report.Checks[0].Output, "deadline") {
+		t.Errorf("check colgado = %+v, quería critical por timeout", report.Checks[0])
+	}
+	if report.Checks[1].Status != registry.HealthPassing {
+		t.Errorf("el check rápido = %s, quería passing", report.Checks[1].Status)
+	}
+	if out := report.Output(); !strings.HasPrefix(out, "colgado (critical)") {
+		t.Errorf("Output = %q", out)
+	}
+}
+
+func TestReadinessHandler(t *testing.T) {
+	for name, tc := range map[string]struct {
+		severity Severity
+		fn       CheckFunc
+		want     int
+	}{
+		"sano":            {Critical, pass, http.StatusOK},
+		"degradado":       {Warning, fail, http.StatusOK},
+		"sin repositorio": {Critical, fail, http.StatusServiceUnavailable},
+	} {
+		c := New(time.Second)
+		c.Add("check", tc.severity, tc.fn)
+		rec := httptest.NewRecorder()
+		c.ReadinessHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+		if rec.Code != tc.want {
+			t.Errorf("%s: /readyz = %d, quería %d", name, rec.Code, tc.want)
+		}
+		var report Report
+		if err := json.NewDecoder(rec.Body).Decode(&report); err != nil || len(report.Checks) != 1 {
+			t.Errorf("%s: cuerpo = %+v, %v", name, report, err)
+		}
+	}
+
+	// /healthz no mira las dependencias.
+	rec := httptest.NewRecorder()
+	LivenessHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("/healthz = %d, quería 200", rec.Code)
+	}
+}
@@ -0,0 +1,94 @@
+package idempotency
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+
+	"proyecto/pkg/storage/boltdb"
+)
+
+// BoltStore es un Store en un bucket de BoltDB, para que las claves sobrevivan
+// a un reinicio. El bucket lo crea la migración del servicio dueño de la base.
+type BoltStore struct {
+	db     *bolt.DB
+	bucket []byte
+	// nextPurge solo se toca dentro de db.Update, que Bolt serializa.
+	nextPurge time.Time
+	now       func() time.Time
+}
+
+func NewBoltStore(db *bolt.DB, bucket string) *BoltStore {
+	return &BoltStore{db: db, bucket: []byte(bucket), now: time.Now}
+}
+
+// Reserve es atómico porque las escrituras de Bolt están serializadas. De paso
+// borra las claves vencidas, como mucho una vez por PurgeInterval.
+func (s *BoltStore) Reserve(_ context.Context, key, fingerprint string, ttl time.Duration) (*Record, bool, error) {
+	var existing *Record
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(s.bucket)
+		now := s.now()
+		if !now.Before(s.nextPurge) {
+			if err := purgeExpired(b, now); err != nil {
+				return err
+			}
+			s.nextPurge = now.Add(PurgeInterval)
+		}
+		var r Record
+		found, err := boltdb.GetJSON(b, key, &r)
+		if err != nil {
+			return err
+		}
+		if found && now.Before(r.ExpiresAt) {
+			existing = &r
+			return nil
+		}
+		return boltdb.PutJSON(b, key, &Record{Fingerprint: fingerprint, ExpiresAt: now.Add(ttl)})
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	return existing, existing == nil, nil
+}
+
+func (s *BoltStore) Complete(_ context.Context, key string, rec *Record) error {
+	cp := *rec
+	cp.Completed = true
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return boltdb.PutJSON(tx.Bucket(s.bucket), key, &cp)
+	})
+}
+
+func (s *BoltStore) Release(_ context.Context, key string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(s.bucket).Delete([]byte(key))
+	})
+}
+
+// purgeExpired borra las claves vencidas; se juntan primero porque borrar
+// mientras se recorre con el cursor puede saltear entradas.
+func purgeExpired(b *bolt.Bucket, now time.Time) error {
+	var expired [][]byte
+	err := b.ForEach(func(k, v []byte) error {
+		var r struct{ ExpiresAt time.Time }
+		if err := json.Unmarshal(v, &r); err != nil {
+			return err
+		}
+		if !now.Before(r.ExpiresAt) {
+			expired = append(expired, k)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, k := range expired {
+		if err := b.Delete(k); err != nil {
+			return err
+		}
+	}
+	return nil
+}
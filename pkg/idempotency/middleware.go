@@ -0,0 +1,164 @@
+package idempotency
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"proyecto/pkg/auth"
+)
+
+// Header es el header con el que el cliente manda la clave.
+const Header = "Idempotency-Key"
+
+// DefaultRetention es cuánto se recuerda la primera respuesta de cada clave.
+const DefaultRetention = 24 * time.Hour
+
+// ReservationLease es cuánto dura la reserva de una clave mientras la primera
+// petición sigue en curso. Es corta para que, si la instancia se cae a mitad
+// de la petición, la clave no quede bloqueada todo DefaultRetention; al
+// guardarse la respuesta pasa a durar la retención completa.
+const ReservationLease = time.Minute
+
+const maxBodyBytes = 1 << 20
+
+// Middleware hace que las peticiones con Idempotency-Key se ejecuten una sola
+// vez: los reintentos con la misma clave y el mismo cuerpo reciben la respuesta
+// guardada; con otro cuerpo, o mientras la primera sigue en curso, reciben 409.
+// Las respuestas 5xx (y los panics) no se guardan, para que el cliente pueda reintentar.
+// Peticiones sin el header pasan sin cambios.
+func Middleware(store Store, retention time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			key := req.Header.Get(Header)
+			if key == "" {
+				next.ServeHTTP(w, req)
+				return
+			}
+			if len(key) > 255 {
+				http.Error(w, "Idempotency-Key demasiado larga", http.StatusBadRequest)
+				return
+			}
+
+			body, err := io.ReadAll(io.LimitReader(req.Body, maxBodyBytes+1))
+			if err != nil {
+				http.Error(w, "No se pudo leer la petición", http.StatusBadRequest)
+				return
+			}
+			if len(body) > maxBodyBytes {
+				http.Error(w, "Petición demasiado grande", http.StatusRequestEntityTooLarge)
+				return
+			}
+			req.Body = io.NopCloser(bytes.NewReader(body))
+
+			ctx := req.Context()
+			storeKey := scopedKey(req, key)
+			fp := fingerprint(req, body)
+
+			existing, reserved, err := store.Reserve(ctx, storeKey, fp, ReservationLease)
+			if err != nil {
+				log.Printf("Error reservando clave de idempotencia: %v", err)
+				http.Error(w, "Error interno", http.StatusInternalServerError)
+				return
+			}
+			if !reserved {
+				switch {
+				case existing.Fingerprint != fp:
+					http.Error(w, "La Idempotency-Key ya se usó con otra petición.", http.StatusConflict)
+				case !existing.Completed:
+					// Retry-After distingue este 409 (reintentable) de un conflicto real.
+					w.Header().Set("Retry-After", "1")
+					http.Error(w, "Hay una petición en curso con esta Idempotency-Key.", http.StatusConflict)
+				default:
+					replay(w, existing)
+				}
+				return
+			}
+
+			// La reserva se libera o se completa aunque el cliente haya cortado.
+			storeCtx := context.WithoutCancel(ctx)
+			release := func() {
+				if err := store.Release(storeCtx, storeKey); err != nil {
+					log.Printf("Error liberando clave de idempotencia: %v", err)
+				}
+			}
+			defer func() {
+				if p := recover(); p != nil {
+					release()
+					panic(p)
+				}
+			}()
+
+			rec := &recorder{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(rec, req)
+
+			if rec.status >= 500 {
+				release()
+				return
+			}
+			err = store.Complete(storeCtx, storeKey, &Record{
+				Fingerprint: fp,
+				Status:      rec.status,
+				Header:      rec.Header().Clone(),
+				Body:        rec.body.Bytes(),
+				ExpiresAt:   time.Now().Add(retention),
+			})
+			if err != nil {
+				log.Printf("Error guardando respuesta idempotente: %v", err)
+			}
+		})
+	}
+}
+
+// scopedKey separa las claves por ruta y por usuario autenticado, para que
+// dos clientes no puedan ver la respuesta del otro eligiendo la misma clave.
+func scopedKey(req *http.Request, key string) string {
+	subject := ""
+	if claims, ok := auth.ClaimsFromContext(req.Context()); ok {
+		subject = claims.Sub
+	}
+	return req.Method + " " + req.URL.Path + " " + subject + " " + key
+}
+
+func fingerprint(req *http.Request, body []byte) string {
+	h := sha256.New()
+	io.WriteString(h, req.Method+" "+req.URL.Path+"?"+req.URL.RawQuery+"\n")
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func replay(w http.ResponseWriter, rec *Record) {
+	for k, v := range rec.Header {
+		w.Header()[k] = v
+	}
+	w.Header().Set("Idempotent-Replayed", "true")
+	w.WriteHeader(rec.Status)
+	_, _ = w.Write(rec.Body)
+}
+
+// recorder copia la respuesta mientras se escribe al cliente.
+type recorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (r *recorder) WriteHeader(status int) {
+	if !r.wroteHeader {
+		r.status = status
+		r.wroteHeader = true
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *recorder) Write(b []byte) (int, error) {
+	r.wroteHeader = true
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
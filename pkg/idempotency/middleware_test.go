@@ -0,0 +1,105 @@
+package idempotency
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func serve(h http.Handler, key, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/recurso", strings.NewReader(body))
+	if key != "" {
+		req.Header.Set(Header, key)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestMiddlewareReplays(t *testing.T) {
+	var calls atomic.Int32
+	h := Middleware(NewMemoryStore(), time.Hour)(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte("creado"))
+	}))
+
+	first := serve(h, "k", "a=1")
+	again := serve(h, "k", "a=1")
+	if calls.Load() != 1 {
+		t.Errorf("el handler corrió %d veces, quería 1", calls.Load())
+	}
+	if again.Code != http.StatusCreated || again.Body.String() != first.Body.String() || again.Header().Get("Idempotent-Replayed") != "true" {
+		t.Errorf("repetición = %d %q", again.Code, again.Body.String())
+	}
+	if rec := serve(h, "k", "a=2"); rec.Code != http.StatusConflict || rec.Header().Get("Retry-After") != "" {
+		t.Errorf("misma clave con otro cuerpo = %d, quería 409 sin Retry-After", rec.Code)
+	}
+	serve(h, "", "a=1")
+	if calls.Load() != 2 {
+		t.Errorf("sin clave el handler tiene que correr siempre")
+	}
+}
+
+func TestMiddlewareReservationLease(t *testing.T) {
+	store := NewMemoryStore()
+	release := make(chan struct{})
+	started := make(chan struct{})
+	h := Middleware(store, time.Hour)(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		close(started)
+		<-release
+	}))
+
+	go serve(h, "k", "")
+	<-started
+	if rec := serve(h, "k", ""); rec.Code != http.StatusConflict || rec.Header().Get("Retry-After") == "" {
+		t.Errorf("petición en curso = %d, quería 409 con Retry-After", rec.Code)
+	}
+	store.mu.Lock()
+	var r Record
+	for _, v := range store.data {
+		r = *v
+	}
+	store.mu.Unlock()
+	if d := time.Until(r.ExpiresAt); d > ReservationLease {
+		t.Errorf("la reserva dura %s, quería como mucho %s", d, ReservationLease)
+	}
+	close(release)
+}
+
+func TestMiddlewareReleasesOnServerError(t *testing.T) {
+	var calls atomic.Int32
+	h := Middleware(NewMemoryStore(), time.Hour)(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		if calls.Add(1) == 1 {
+			http.Error(w, "falló", http.StatusServiceUnavailable)
+		}
+	}))
+	serve(h, "k", "")
+	if rec := serve(h, "k", ""); rec.Code != http.StatusOK || calls.Load() != 2 {
+		t.Errorf("reintento tras un 503 = %d (%d llamadas), quería que corra de nuevo", rec.Code, calls.Load())
+	}
+}
+
+func TestMiddlewareReleasesOnPanic(t *testing.T) {
+	var calls atomic.Int32
+	h := Middleware(NewMemoryStore(), time.Hour)(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		if calls.Add(1) == 1 {
+			panic("algo salió mal")
+		}
+	}))
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("el panic no siguió hacia arriba")
+			}
+		}()
+		serve(h, "k", "")
+	}()
+	if rec := serve(h, "k", ""); rec.Code != http.StatusOK || calls.Load() != 2 {
+		t.Errorf("reintento tras el panic = %d (%d llamadas)", rec.Code, calls.Load())
+	}
+}
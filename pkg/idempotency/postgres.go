@@ -0,0 +1,113 @@
+package idempotency
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// PostgresSchema crea la tabla de PostgresStore; cada servicio la agrega como
+// una migración más de su base.
+const PostgresSchema = `
+	CREATE TABLE idempotency_keys (
+		key         TEXT PRIMARY KEY,
+		fingerprint TEXT NOT NULL,
+		completed   BOOLEAN NOT NULL DEFAULT FALSE,
+		status      INTEGER NOT NULL DEFAULT 0,
+		header      JSONB NOT NULL DEFAULT '{}',
+		body        BYTEA NOT NULL DEFAULT '',
+		expires_at  TIMESTAMPTZ NOT NULL
+	);
+	CREATE INDEX idempotency_keys_expires_idx ON idempotency_keys (expires_at);
+`
+
+// PostgresStore es un Store en la tabla idempotency_keys (PostgresSchema), que
+// comparten todas las instancias del servicio.
+type PostgresStore struct {
+	db *sql.DB
+}
+
+func NewPostgresStore(db *sql.DB) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+// Reserve inserta la reserva con ON CONFLICT DO NOTHING: de dos peticiones
+// simultáneas con la misma clave solo una inserta la fila. De paso borra las
+// claves vencidas.
+func (s *PostgresStore) Reserve(ctx context.Context, key, fingerprint string, ttl time.Duration) (*Record, bool, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, false, err
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	if _, err := tx.ExecContext(ctx, `DELETE FROM idempotency_keys WHERE expires_at <= $1`, now); err != nil {
+		return nil, false, err
+	}
+	var inserted bool
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO idempotency_keys (key, fingerprint, expires_at) VALUES ($1, $2, $3)
+		ON CONFLICT (key) DO NOTHING
+		RETURNING TRUE`, key, fingerprint, now.Add(ttl),
+	).Scan(&inserted)
+	switch {
+	case err == nil:
+		return nil, true, tx.Commit()
+	case !errors.Is(err, sql.ErrNoRows):
+		return nil, false, err
+	}
+
+	var r Record
+	var header []byte
+	err = tx.QueryRowContext(ctx, `
+		SELECT fingerprint, completed, status, header, body, expires_at
+		FROM idempotency_keys WHERE key = $1`, key,
+	).Scan(&r.Fingerprint, &r.Completed, &r.Status, &header, &r.Body, &r.ExpiresAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		// Se liberó entre el INSERT y el SELECT: se informa en curso para que el cliente reintente.
+		return &Record{Fingerprint: fingerprint}, false, nil
+	} else if err != nil {
+		return nil, false, err
+	}
+	if err := json.Unmarshal(header, &r.Header); err != nil {
+		return nil, false, err
+	}
+	return &r, false, tx.Commit()
+}
+
+func (s *PostgresStore) Complete(ctx context.Context, key string, rec *Record) error {
+	header := rec.Header
+	if header == nil {
+		header = http.Header{}
+	}
+	data, err := json.Marshal(header)
+	if err != nil {
+		return err
+	}
+	body := rec.Body
+	if body == nil {
+		body = []byte{}
+	}
+	_, err = s.db.ExecContext(ctx, `
+		INSERT INTO idempotency_keys (key, fingerprint, completed, status, header, body, expires_at)
+		VALUES ($1, $2, TRUE, $3, $4, $5, $6)
+		ON CONFLICT (key) DO UPDATE SET
+			fingerprint = EXCLUDED.fingerprint,
+			completed   = TRUE,
+			status      = EXCLUDED.status,
+			header      = EXCLUDED.header,
+			body        = EXCLUDED.body,
+			expires_at  = EXCLUDED.expires_at`,
+		key, rec.Fingerprint, rec.Status, data, body, rec.ExpiresAt,
+	)
+	return err
+}
+
+func (s *PostgresStore) Release(ctx context.Context, key string) error {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM idempotency_keys WHERE key = $1`, key)
+	return err
+}
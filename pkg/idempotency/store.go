@@ -0,0 +1,86 @@
+package idempotency
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Record es la primera respuesta dada para una clave de idempotencia.
+type Record struct {
+	Fingerprint string      // hash del método, ruta y cuerpo de la petición original
+	Completed   bool        // false mientras la primera petición sigue en curso
+	Status      int         // status de la respuesta guardada
+	Header      http.Header // headers de la respuesta guardada
+	Body        []byte      // cuerpo de la respuesta guardada
+	ExpiresAt   time.Time
+}
+
+// Store guarda las respuestas por clave. Reserve debe ser atómico para que
+// dos peticiones simultáneas con la misma clave no se ejecuten ambas.
+type Store interface {
+	// Reserve crea un registro en curso para key que vence en ttl si no existe
+	// (reserved=true); si ya existe devuelve el registro guardado.
+	Reserve(ctx context.Context, key, fingerprint string, ttl time.Duration) (existing *Record, reserved bool, err error)
+	// Complete guarda la respuesta final de key.
+	Complete(ctx context.Context, key string, rec *Record) error
+	// Release borra la reserva de key, para que se pueda reintentar.
+	Release(ctx context.Context, key string) error
+}
+
+// PurgeInterval es cada cuánto MemoryStore y BoltStore borran las claves
+// vencidas. Entre dos barridas una clave vencida se trata como si no existiera.
+const PurgeInterval = time.Minute
+
+// MemoryStore es un Store en memoria; sirve para una sola instancia.
+type MemoryStore struct {
+	mu        sync.Mutex
+	data      map[string]*Record
+	nextPurge time.Time
+	now       func() time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{data: map[string]*Record{}, now: time.Now}
+}
+
+// Reserve borra las claves vencidas como mucho una vez por PurgeInterval, para
+// no recorrer todos los registros en cada petición.
+func (s *MemoryStore) Reserve(_ context.Context, key, fingerprint string, ttl time.Duration) (*Record, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	if !now.Before(s.nextPurge) {
+		for k, r := range s.data {
+			if !now.Before(r.ExpiresAt) {
+				delete(s.data, k)
+			}
+		}
+		s.nextPurge = now.Add(PurgeInterval)
+	}
+
+	if r, ok := s.data[key]; ok && now.Before(r.ExpiresAt) {
+		cp := *r
+		return &cp, false, nil
+	}
+	s.data[key] = &Record{Fingerprint: fingerprint, ExpiresAt: now.Add(ttl)}
+	return nil, true, nil
+}
+
+func (s *MemoryStore) Complete(_ context.Context, key string, rec *Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cp := *rec
+	cp.Completed = true
+	s.data[key] = &cp
+	return nil
+}
+
+func (s *MemoryStore) Release(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.data, key)
+	return nil
+}
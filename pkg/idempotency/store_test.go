@@ -0,0 +1,149 @@
+package idempotency
+
+import (
+	"context"
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+
+	"proyecto/pkg/storage/boltdb"
+	"proyecto/pkg/storage/postgres"
+	"proyecto/pkg/storage/postgres/postgrestest"
+)
+
+// testStore prueba el contrato de Store sobre una implementación vacía.
+func testStore(t *testing.T, s Store) {
+	ctx := context.Background()
+
+	existing, reserved, err := s.Reserve(ctx, "k", "fp", time.Minute)
+	if err != nil || !reserved || existing != nil {
+		t.Fatalf("primera reserva = %+v, %v, %v", existing, reserved, err)
+	}
+	existing, reserved, err = s.Reserve(ctx, "k", "otro", time.Minute)
+	if err != nil || reserved || existing.Fingerprint != "fp" || existing.Completed {
+		t.Fatalf("reserva en curso = %+v, %v, %v", existing, reserved, err)
+	}
+
+	err = s.Complete(ctx, "k", &Record{
+		Fingerprint: "fp",
+		Status:      http.StatusCreated,
+		Header:      http.Header{"Content-Type": {"application/json"}},
+		Body:        []byte(`{"ok":true}`),
+		ExpiresAt:   time.Now().Add(time.Hour),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	existing, reserved, err = s.Reserve(ctx, "k", "fp", time.Minute)
+	if err != nil || reserved || !existing.Completed || existing.Status != http.StatusCreated ||
+		string(existing.Body) != `{"ok":true}` || existing.Header.Get("Content-Type") != "application/json" {
+		t.Fatalf("reserva completada = %+v, %v, %v", existing, reserved, err)
+	}
+
+	if err := s.Release(ctx, "k"); err != nil {
+		t.Fatal(err)
+	}
+	if _, reserved, err := s.Reserve(ctx, "k", "fp", time.Minute); err != nil || !reserved {
+		t.Errorf("reserva tras Release = %v, %v", reserved, err)
+	}
+
+	// Una reserva que no se completó vence con su ttl.
+	if _, reserved, err := s.Reserve(ctx, "corta", "fp", 50*time.Millisecond); err != nil || !reserved {
+		t.Fatalf("reserva corta = %v, %v", reserved, err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if _, reserved, err := s.Reserve(ctx, "corta", "fp", time.Minute); err != nil || !reserved {
+		t.Errorf("reserva tras vencer = %v, %v", reserved, err)
+	}
+}
+
+func TestMemoryStore(t *testing.T) {
+	testStore(t, NewMemoryStore())
+}
+
+func TestBoltStore(t *testing.T) {
+	db, err := boltdb.Open(filepath.Join(t.TempDir(), "test.db"), []boltdb.Migration{
+		{Version: 1, Name: "create idempotency_keys", Up: boltdb.CreateBuckets("idempotency_keys")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	testStore(t, NewBoltStore(db, "idempotency_keys"))
+}
+
+func TestPostgresStore(t *testing.T) {
+	db, err := postgres.Open(context.Background(), postgrestest.DSN(t), []postgres.Migration{
+		{Version: 1, Name: "create idempotency_keys", SQL: PostgresSchema},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	testStore(t, NewPostgresStore(db))
+}
+
+// testClock es un reloj que solo avanza cuando el test lo pide.
+type testClock struct{ t time.Time }
+
+func (c *testClock) now() time.Time          { return c.t }
+func (c *testClock) advance(d time.Duration) { c.t = c.t.Add(d) }
+
+// testPurge prueba que las claves vencidas se ignoran enseguida pero se borran
+// una vez por PurgeInterval; count devuelve cuántos registros guarda el store.
+func testPurge(t *testing.T, s Store, clock *testClock, count func() int) {
+	ctx := context.Background()
+	for _, k := range []string{"a", "b", "c"} {
+		if _, reserved, err := s.Reserve(ctx, k, "fp", time.Second); err != nil || !reserved {
+			t.Fatalf("reserva de %s = %v, %v", k, reserved, err)
+		}
+	}
+
+	// La primera reserva ya barrió: las vencidas quedan hasta la próxima.
+	clock.advance(2 * time.Second)
+	if _, reserved, err := s.Reserve(ctx, "a", "fp", time.Hour); err != nil || !reserved {
+		t.Errorf("reserva de una clave vencida = %v, %v; quería reservarla de nuevo", reserved, err)
+	}
+	if n := count(); n != 3 {
+		t.Errorf("registros antes de PurgeInterval = %d, quería 3", n)
+	}
+
+	clock.advance(PurgeInterval)
+	if _, _, err := s.Reserve(ctx, "d", "fp", time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if n := count(); n != 2 {
+		t.Errorf("registros tras PurgeInterval = %d, quería 2 (a y d)", n)
+	}
+}
+
+func TestMemoryStorePurge(t *testing.T) {
+	clock := &testClock{t: time.Unix(1_700_000_000, 0)}
+	s := NewMemoryStore()
+	s.now = clock.now
+	testPurge(t, s, clock, func() int { return len(s.data) })
+}
+
+func TestBoltStorePurge(t *testing.T) {
+	db, err := boltdb.Open(filepath.Join(t.TempDir(), "test.db"), []boltdb.Migration{
+		{Version: 1, Name: "create idempotency_keys", Up: boltdb.CreateBuckets("idempotency_keys")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	clock := &testClock{t: time.Unix(1_700_000_000, 0)}
+	s := NewBoltStore(db, "idempotency_keys")
+	s.now = clock.now
+	testPurge(t, s, clock, func() int {
+		var n int
+		db.View(func(tx *bolt.Tx) error {
+			n = tx.Bucket([]byte("idempotency_keys")).Stats().KeyN
+			return nil
+		})
+		return n
+	})
+}
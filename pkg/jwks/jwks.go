@@ -0,0 +1,90 @@
+package jwks
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+var ErrUnsupportedKey = errors.New("unsupported JWK")
+
+// JWK es la representación pública de una llave (RFC 7517).
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	// RSA
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// OKP (Ed25519)
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+// Set es el documento publicado en /.well-known/jwks.json.
+type Set struct {
+	Keys []JWK `json:"keys"`
+}
+
+// Lookup busca una llave por su kid.
+func (s Set) Lookup(kid string) (JWK, bool) {
+	for _, k := range s.Keys {
+		if k.Kid == kid {
+			return k, true
+		}
+	}
+	return JWK{}, false
+}
+
+// Thumbprint calcula el thumbprint RFC 7638, que usamos como kid.
+func (j JWK) Thumbprint() string {
+	var members map[string]string
+	switch j.Kty {
+	case "RSA":
+		members = map[string]string{"e": j.E, "kty": j.Kty, "n": j.N}
+	case "OKP":
+		members = map[string]string{"crv": j.Crv, "kty": j.Kty, "x": j.X}
+	}
+	// encoding/json ordena las llaves del map, que es lo que pide el RFC.
+	data, _ := json.Marshal(members)
+	sum := sha256.Sum256(data)
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// PublicKey reconstruye la llave pública de un JWK, para quien verifica tokens.
+func (j JWK) PublicKey() (crypto.PublicKey, error) {
+	switch j.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(j.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := base64.RawURLEncoding.DecodeString(j.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case "OKP":
+		if !strings.EqualFold(j.Crv, "Ed25519") {
+			return nil, fmt.Errorf("%w: curve %q", ErrUnsupportedKey, j.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(j.X)
+		if err != nil {
+			return nil, err
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid Ed25519 public key size")
+		}
+		return ed25519.PublicKey(x), nil
+	default:
+		return nil, fmt.Errorf("%w: kty %q", ErrUnsupportedKey, j.Kty)
+	}
+}
@@ -0,0 +1,78 @@
+// Package ratelimit limita las peticiones por IP, por usuario (sub del JWT) o
+// por ruta, con token bucket o ventana deslizante. Los contadores viven en un
+// Store: MemoryStore sirve para una instancia; con varias hace falta uno compartido.
+package ratelimit
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Algoritmos (Limit.Algorithm).
+const (
+	// TokenBucket deja pasar ráfagas de hasta Burst y repone Requests por Window.
+	TokenBucket = "token_bucket"
+	// SlidingWindow deja pasar Requests en cualquier ventana de Window
+	// (aproximada con la ventana fija actual y la anterior).
+	SlidingWindow = "sliding_window"
+)
+
+// Claves (Limit.By).
+const (
+	ByIP      = "ip"      // IP del cliente
+	BySubject = "subject" // sub del access token (IP si no hay token)
+	ByRoute   = "route"   // un solo contador para toda la ruta
+)
+
+type Limit struct {
+	Algorithm string        `yaml:"algorithm"` // token_bucket (por defecto) o sliding_window
+	By        string        `yaml:"by"`        // ip (por defecto), subject o route
+	Requests  int           `yaml:"requests"`  // 0 = sin límite
+	Window    time.Duration `yaml:"window"`
+	Burst     int           `yaml:"burst"` // solo token_bucket; 0 = Requests
+}
+
+// withDefaults completa los campos opcionales.
+func (l Limit) withDefaults() Limit {
+	if l.Algorithm == "" {
+		l.Algorithm = TokenBucket
+	}
+	if l.By == "" {
+		l.By = ByIP
+	}
+	if l.Burst <= 0 {
+		l.Burst = l.Requests
+	}
+	return l
+}
+
+func (l Limit) Validate() error {
+	if l.Requests == 0 {
+		return nil
+	}
+	l = l.withDefaults()
+	var errs []error
+	if l.Algorithm != TokenBucket && l.Algorithm != SlidingWindow {
+		errs = append(errs, fmt.Errorf("algorithm desconocido: %q (token_bucket o sliding_window)", l.Algorithm))
+	}
+	if l.By != ByIP && l.By != BySubject && l.By != ByRoute {
+		errs = append(errs, fmt.Errorf("by desconocido: %q (ip, subject o route)", l.By))
+	}
+	if l.Requests < 0 {
+		errs = append(errs, errors.New("requests no puede ser negativo"))
+	}
+	if l.Window <= 0 {
+		errs = append(errs, errors.New("window debe ser mayor que 0"))
+	}
+	return errors.Join(errs...)
+}
+
+// Result es el resultado de contar una petición.
+type Result struct {
+	Allowed    bool
+	Limit      int           // peticiones permitidas (o tamaño de la ráfaga)
+	Remaining  int           // cuántas quedan ahora
+	Reset      time.Duration // cuándo se recupera el límite completo
+	RetryAfter time.Duration // si no se permitió, cuánto esperar
+}
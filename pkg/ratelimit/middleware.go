@@ -0,0 +1,96 @@
+package ratelimit
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"proyecto/pkg/auth"
+)
+
+// Limiter aplica a cada ruta su Limit, guardando los contadores en un Store.
+type Limiter struct {
+	store  Store
+	limits map[string]Limit
+}
+
+// New crea un Limiter con los límites por nombre de ruta; el límite
+// "default" se aplica a las rutas que no tienen uno propio.
+func New(store Store, limits map[string]Limit) *Limiter {
+	return &Limiter{store: store, limits: limits}
+}
+
+// Wrap limita next con el límite de route (o el "default"). Si no hay ninguno
+// (o tiene requests: 0) devuelve next sin cambios. Para limitar por subject
+// debe ir detrás de auth.Middleware.
+func (l *Limiter) Wrap(route string, next http.Handler) http.Handler {
+	limit, ok := l.limits[route]
+	if !ok {
+		limit = l.limits["default"]
+	}
+	if limit.Requests <= 0 {
+		return next
+	}
+	return Middleware(l.store, route, limit)(next)
+}
+
+// Middleware limita las peticiones según limit; name separa los contadores
+// de distintas rutas. Responde 429 con Retry-After al pasarse, y siempre
+// informa el estado con los headers RateLimit-*. Si el Store falla, la
+// petición pasa (es preferible a dejar el servicio sin atender).
+func Middleware(store Store, name string, limit Limit) func(http.Handler) http.Handler {
+	limit = limit.withDefaults()
+	policy := fmt.Sprintf("%d;w=%d", limit.Requests, int(limit.Window.Seconds()))
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			res, err := store.Take(req.Context(), name+":"+key(req, limit.By), limit)
+			if err != nil {
+				log.Printf("Error en rate limit de %s: %v", name, err)
+				next.ServeHTTP(w, req)
+				return
+			}
+
+			h := w.Header()
+			h.Set("RateLimit-Policy", policy)
+			h.Set("RateLimit-Limit", strconv.Itoa(res.Limit))
+			h.Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+			h.Set("RateLimit-Reset", ceilSeconds(res.Reset))
+			if !res.Allowed {
+				h.Set("Retry-After", ceilSeconds(res.RetryAfter))
+				http.Error(w, "Demasiadas peticiones; probá de nuevo más tarde.", http.StatusTooManyRequests)
+				return
+			}
+			next.ServeHTTP(w, req)
+		})
+	}
+}
+
+func key(req *http.Request, by string) string {
+	switch by {
+	case ByRoute:
+		return ""
+	case BySubject:
+		if claims, ok := auth.ClaimsFromContext(req.Context()); ok && claims.Sub != "" {
+			return "sub:" + claims.Sub
+		}
+	}
+	return "ip:" + ClientIP(req)
+}
+
+// ClientIP es la IP de la conexión (sin el puerto).
+func ClientIP(req *http.Request) string {
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		return req.RemoteAddr
+	}
+	return host
+}
+
+// ceilSeconds redondea d hacia arriba a segundos enteros (al menos 1 si d > 0).
+func ceilSeconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
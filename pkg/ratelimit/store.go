@@ -0,0 +1,124 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// Store cuenta las peticiones por clave. Take debe ser atómico, para que
+// peticiones simultáneas (de esta u otras instancias) no se salten el límite.
+type Store interface {
+	Take(ctx context.Context, key string, limit Limit) (Result, error)
+}
+
+// MemoryStore es un Store en memoria; sirve para una sola instancia.
+type MemoryStore struct {
+	mu        sync.Mutex
+	entries   map[string]*entry
+	lastPurge time.Time
+	now       func() time.Time
+}
+
+type entry struct {
+	expiresAt time.Time // sin peticiones hasta entonces, la entrada ya no importa
+
+	// token bucket
+	tokens float64
+	last   time.Time
+
+	// ventana deslizante
+	start      time.Time
+	prev, curr int
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{entries: map[string]*entry{}, now: time.Now}
+}
+
+func (s *MemoryStore) Take(_ context.Context, key string, limit Limit) (Result, error) {
+	limit = limit.withDefaults()
+	now := s.now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.purge(now)
+
+	e, ok := s.entries[key]
+	if !ok {
+		e = &entry{tokens: float64(limit.Burst), last: now, start: now.Truncate(limit.Window)}
+		s.entries[key] = e
+	}
+	e.expiresAt = now.Add(2 * limit.Window)
+	if limit.Algorithm == SlidingWindow {
+		return e.slidingWindow(now, limit), nil
+	}
+	return e.tokenBucket(now, limit), nil
+}
+
+func (e *entry) tokenBucket(now time.Time, l Limit) Result {
+	perSecond := float64(l.Requests) / l.Window.Seconds()
+	e.tokens = math.Min(float64(l.Burst), e.tokens+now.Sub(e.last).Seconds()*perSecond)
+	e.last = now
+
+	res := Result{Limit: l.Burst}
+	if e.tokens >= 1 {
+		e.tokens--
+		res.Allowed = true
+	} else {
+		res.RetryAfter = seconds((1 - e.tokens) / perSecond)
+	}
+	res.Remaining = int(e.tokens)
+	res.Reset = seconds((float64(l.Burst) - e.tokens) / perSecond)
+	return res
+}
+
+func (e *entry) slidingWindow(now time.Time, l Limit) Result {
+	start := now.Truncate(l.Window)
+	if !start.Equal(e.start) {
+		if start.Sub(e.start) == l.Window {
+			e.prev = e.curr
+		} else {
+			e.prev = 0
+		}
+		e.curr, e.start = 0, start
+	}
+	// La parte de la ventana anterior que todavía cae dentro de la deslizante.
+	weight := 1 - float64(now.Sub(start))/float64(l.Window)
+	used := float64(e.prev)*weight + float64(e.curr)
+
+	res := Result{Limit: l.Requests, Reset: start.Add(l.Window).Sub(now)}
+	if used+1 <= float64(l.Requests) {
+		e.curr++
+		used++
+		res.Allowed = true
+	} else {
+		// Se espera a que la ventana anterior pese lo suficiente menos o, si
+		// con la actual ya no alcanza, a la próxima ventana.
+		res.RetryAfter = res.Reset
+		if free := float64(l.Requests-1-e.curr) / float64(e.prev); e.prev > 0 && free >= 0 {
+			elapsed := time.Duration((1 - free) * float64(l.Window))
+			res.RetryAfter = start.Add(elapsed).Sub(now)
+		}
+	}
+	res.Remaining = max(l.Requests-int(math.Ceil(used)), 0)
+	return res
+}
+
+// purge borra las entradas vencidas, como mucho una vez por minuto.
+func (s *MemoryStore) purge(now time.Time) {
+	if now.Sub(s.lastPurge) < time.Minute {
+		return
+	}
+	s.lastPurge = now
+	for k, e := range s.entries {
+		if now.After(e.expiresAt) {
+			delete(s.entries, k)
+		}
+	}
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
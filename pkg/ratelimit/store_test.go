@@ -0,0 +1,126 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// testClock es un reloj que solo avanza cuando el test lo pide.
+type testClock struct{ t time.Time }
+
+func (c *testClock) now() time.Time          { return c.t }
+func (c *testClock) advance(d time.Duration) { c.t = c.t.Add(d) }
+
+func newTestStore() (*MemoryStore, *testClock) {
+	clock := &testClock{t: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	s := NewMemoryStore()
+	s.now = clock.now
+	return s, clock
+}
+
+// takeN pide n veces y devuelve cuántas pasaron y el último resultado.
+func takeN(t *testing.T, s *MemoryStore, n int, limit Limit) (int, Result) {
+	t.Helper()
+	allowed := 0
+	var res Result
+	for range n {
+		var err error
+		res, err = s.Take(context.Background(), "k", limit)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.Allowed {
+			allowed++
+		}
+	}
+	return allowed, res
+}
+
+func TestTokenBucketRefill(t *testing.T) {
+	s, clock := newTestStore()
+	limit := Limit{Algorithm: TokenBucket, Requests: 10, Window: time.Minute, Burst: 5}
+
+	// La ráfaga deja pasar Burst y después hay que esperar un token (6s).
+	if n, res := takeN(t, s, 6, limit); n != 5 || res.Allowed {
+		t.Fatalf("ráfaga: pasaron %d, quería 5", n)
+	} else if res.RetryAfter != 6*time.Second {
+		t.Errorf("RetryAfter = %v, quería 6s", res.RetryAfter)
+	}
+
+	clock.advance(3 * time.Second)
+	if n, _ := takeN(t, s, 1, limit); n != 0 {
+		t.Errorf("con medio token pasaron %d, quería 0", n)
+	}
+	clock.advance(3 * time.Second)
+	if n, res := takeN(t, s, 2, limit); n != 1 {
+		t.Errorf("con un token pasaron %d, quería 1", n)
+	} else if res.Reset != 30*time.Second {
+		t.Errorf("Reset = %v, quería 30s", res.Reset)
+	}
+
+	// Con mucho tiempo sin pedir, los tokens no pasan de Burst.
+	clock.advance(time.Hour)
+	if n, _ := takeN(t, s, 10, limit); n != 5 {
+		t.Errorf("después de una hora pasaron %d, quería 5", n)
+	}
+}
+
+func TestSlidingWindowWeighting(t *testing.T) {
+	s, clock := newTestStore()
+	limit := Limit{Algorithm: SlidingWindow, Requests: 10, Window: time.Minute}
+
+	if n, res := takeN(t, s, 11, limit); n != 10 {
+		t.Fatalf("primera ventana: pasaron %d, quería 10", n)
+	} else if res.RetryAfter != time.Minute {
+		t.Errorf("RetryAfter = %v, quería 1m", res.RetryAfter)
+	}
+
+	clock.advance(30 * time.Second)
+	if n, res := takeN(t, s, 1, limit); n != 0 {
+		t.Errorf("misma ventana: pasaron %d, quería 0", n)
+	} else if res.RetryAfter != 30*time.Second {
+		t.Errorf("RetryAfter = %v, quería 30s (la próxima ventana)", res.RetryAfter)
+	}
+
+	// A los 15s de la ventana siguiente la anterior pesa 0.75: 7.5 usadas,
+	// así que pasan 2 y la tercera espera a que pese 0.7 (a los 18s).
+	clock.advance(45 * time.Second)
+	n, res := takeN(t, s, 3, limit)
+	if n != 2 {
+		t.Errorf("ventana siguiente: pasaron %d, quería 2", n)
+	}
+	if res.RetryAfter != 3*time.Second {
+		t.Errorf("RetryAfter = %v, quería 3s", res.RetryAfter)
+	}
+	if res.Remaining != 0 {
+		t.Errorf("Remaining = %d, quería 0", res.Remaining)
+	}
+	clock.advance(res.RetryAfter + time.Second)
+	if n, _ := takeN(t, s, 1, limit); n != 1 {
+		t.Errorf("después de RetryAfter pasaron %d, quería 1", n)
+	}
+
+	// Si se salta una ventana entera, la anterior ya no cuenta.
+	clock.advance(2 * time.Minute)
+	if n, _ := takeN(t, s, 11, limit); n != 10 {
+		t.Errorf("después de una ventana vacía pasaron %d, quería 10", n)
+	}
+}
+
+func TestMemoryStorePurge(t *testing.T) {
+	s, clock := newTestStore()
+	limit := Limit{Requests: 1, Window: time.Second}
+	for _, key := range []string{"a", "b"} {
+		if _, err := s.Take(context.Background(), key, limit); err != nil {
+			t.Fatal(err)
+		}
+	}
+	clock.advance(2 * time.Minute)
+	if _, err := s.Take(context.Background(), "c", limit); err != nil {
+		t.Fatal(err)
+	}
+	if len(s.entries) != 1 {
+		t.Errorf("quedaron %d entradas, quería 1", len(s.entries))
+	}
+}
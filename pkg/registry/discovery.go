@@ -11,12 +11,24 @@ import (
 
 
 type Registry interface {
-	Register(ctx context.Context, instanceID string, serviceName string, hostPort string) error
+	Register(ctx context.Context, instanceID string, serviceName string, hostPort string, opts ...RegisterOption) error
 	Deregister(ctx context.Context, instanceID string, serviceName string) error
-	ServiceAddress(ctx context.Context, serviceID string) ([]string, error)
+	ServiceAddress(ctx context.Context, serviceID string, opts ...QueryOption) ([]string, error)
 	ReportHealthyState(instanceID string, serviceName string) error
+	// ReportState reporta el resultado de los checks de la instancia, con un texto explicativo.
+	ReportState(instanceID string, serviceName string, state HealthState, output string) error
 }
 
+// HealthState es el estado de salud de una instancia (los mismos que Consul).
+// Las instancias en warning siguen recibiendo tráfico; las critical no.
+type HealthState string
+
+const (
+	HealthPassing  HealthState = "passing"
+	HealthWarning  HealthState = "warning"
+	HealthCritical HealthState = "critical"
+)
+
 var ErrNotFound = errors.New("No service addresses found")
 
 func GenerateInstanceID(serviceName string) string {
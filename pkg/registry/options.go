@@ -0,0 +1,96 @@
+package discovery
+
+import (
+	"slices"
+	"time"
+)
+
+// Registration son los datos opcionales de un registro.
+type Registration struct {
+	Tags []string          // p.ej. "version=v2", "zone=a"; se pueden usar para filtrar
+	Meta map[string]string // clave/valor informativos
+	// HTTPCheck es la ruta (p.ej. /healthz) que el registry consulta cada
+	// CheckInterval, además del latido TTL. Vacío = solo TTL.
+	HTTPCheck     string
+	CheckInterval time.Duration
+}
+
+type RegisterOption func(*Registration)
+
+// NewRegistration aplica las opciones.
+func NewRegistration(opts ...RegisterOption) Registration {
+	r := Registration{Meta: map[string]string{}}
+	for _, opt := range opts {
+		opt(&r)
+	}
+	return r
+}
+
+func WithTags(tags ...string) RegisterOption {
+	return func(r *Registration) {
+		for _, t := range tags {
+			if t != "" && !slices.Contains(r.Tags, t) {
+				r.Tags = append(r.Tags, t)
+			}
+		}
+	}
+}
+
+func WithMeta(key, value string) RegisterOption {
+	return func(r *Registration) { r.Meta[key] = value }
+}
+
+// WithVersion agrega la versión como metadato y como tag "version=<v>",
+// para poder elegir una versión concreta con WithTag(VersionTag(v)).
+func WithVersion(version string) RegisterOption {
+	return func(r *Registration) {
+		WithMeta("version", version)(r)
+		WithTags(VersionTag(version))(r)
+	}
+}
+
+// WithHTTPCheck pide al registry que compruebe la salud con GET http://<hostPort><path>.
+func WithHTTPCheck(path string, interval time.Duration) RegisterOption {
+	return func(r *Registration) {
+		r.HTTPCheck = path
+		r.CheckInterval = interval
+	}
+}
+
+func VersionTag(version string) string {
+	return "version=" + version
+}
+
+// Query son los filtros opcionales de ServiceAddress.
+type Query struct {
+	Tags []string // la instancia debe tener todos estos tags
+}
+
+type QueryOption func(*Query)
+
+func NewQuery(opts ...QueryOption) Query {
+	var q Query
+	for _, opt := range opts {
+		opt(&q)
+	}
+	return q
+}
+
+// WithTag filtra las instancias que tengan el tag.
+func WithTag(tag string) QueryOption {
+	return func(q *Query) {
+		if tag != "" && !slices.Contains(q.Tags, tag) {
+			q.Tags = append(q.Tags, tag)
+		}
+	}
+}
+
+// Matches indica si una instancia con esos tags cumple el filtro.
+func (q Query) Matches(tags []string) bool {
+	for _, t := range q.Tags {
+		if !slices.Contains(tags, t) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,264 @@
+// Package service es el arranque común de los microservicios: registry,
+// checks de salud, servidor HTTP y el orden de arranque y apagado. Cada main
+// solo arma sus dependencias y rutas sobre Service y llama a Run.
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"proyecto/pkg/discovery"
+	"proyecto/pkg/health"
+	registry "proyecto/pkg/registry"
+)
+
+type Config struct {
+	Name string `yaml:"name" env:"SERVICE_NAME"` // nombre con el que se registra
+	Port int    `yaml:"port" env:"SERVICE_PORT"`
+	// Host es la dirección que se publica en el registry para que otros la usen
+	// (SERVICE_HOST): localhost en local, el nombre del contenedor en Docker.
+	// 0.0.0.0 NO es válido para Consul.
+	Host     string           `yaml:"host" env:"SERVICE_HOST"`
+	Registry discovery.Config `yaml:"registry"`
+
+	// Datos del registro: la versión y la zona se publican como tags version=<v> y zone=<z>.
+	Version string   `yaml:"version" env:"SERVICE_VERSION"`
+	Zone    string   `yaml:"zone" env:"SERVICE_ZONE"`
+	Tags    []string `yaml:"tags" env:"SERVICE_TAGS"`
+	// HealthCheck es ttl (solo el latido) o http (además el registry consulta /healthz).
+	HealthCheck string `yaml:"health_check" env:"HEALTH_CHECK"`
+
+	HeartbeatInterval time.Duration `yaml:"heartbeat_interval" env:"HEARTBEAT_INTERVAL"` // cada cuánto se reportan los checks al registry
+	// DrainDelay es cuánto se sigue atendiendo tras deregistrarse, para que
+	// los clientes (y sus cachés del registry) dejen de mandar tráfico.
+	DrainDelay      time.Duration `yaml:"drain_delay" env:"DRAIN_DELAY"`
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout" env:"SHUTDOWN_TIMEOUT"` // máximo para terminar las peticiones en curso
+}
+
+func DefaultConfig(name string, port int) Config {
+	return Config{
+		Name:              name,
+		Port:              port,
+		Host:              "localhost",
+		Registry:          discovery.DefaultConfig(),
+		HealthCheck:       "ttl",
+		HeartbeatInterval: 3 * time.Second,
+		DrainDelay:        2 * time.Second,
+		ShutdownTimeout:   10 * time.Second,
+	}
+}
+
+func (c Config) Validate() error {
+	var errs []error
+	if c.Name == "" {
+		errs = append(errs, errors.New("service.name es obligatorio"))
+	}
+	if c.Port <= 0 || c.Port > 65535 {
+		errs = append(errs, fmt.Errorf("service.port inválido: %d", c.Port))
+	}
+	if c.Host == "" || c.Host == "0.0.0.0" {
+		errs = append(errs, fmt.Errorf("service.host (SERVICE_HOST) debe ser una dirección alcanzable, no %q", c.Host))
+	}
+	if c.HealthCheck != "ttl" && c.HealthCheck != "http" {
+		errs = append(errs, fmt.Errorf("service.health_check debe ser ttl o http, no %q", c.HealthCheck))
+	}
+	if c.HeartbeatInterval <= 0 {
+		errs = append(errs, errors.New("service.heartbeat_interval debe ser mayor que 0"))
+	} else if c.HeartbeatInterval >= 5*time.Second {
+		// El check TTL de Consul vence a los 5s.
+		errs = append(errs, errors.New("service.heartbeat_interval debe ser menor que el TTL del registry (5s)"))
+	}
+	if c.ShutdownTimeout <= 0 {
+		errs = append(errs, errors.New("service.shutdown_timeout debe ser mayor que 0"))
+	}
+	errs = append(errs, c.Registry.Validate())
+	return errors.Join(errs...)
+}
+
+// registerOptions arma los tags/metadatos/check HTTP del registro.
+func (c Config) registerOptions() []registry.RegisterOption {
+	opts := []registry.RegisterOption{registry.WithTags(c.Tags...)}
+	if c.Version != "" {
+		opts = append(opts, registry.WithVersion(c.Version))
+	}
+	if c.Zone != "" {
+		opts = append(opts, registry.WithMeta("zone", c.Zone), registry.WithTags("zone="+c.Zone))
+	}
+	if c.HealthCheck == "http" {
+		opts = append(opts, registry.WithHTTPCheck("/healthz", 10*time.Second))
+	}
+	return opts
+}
+
+type Service struct {
+	cfg        Config
+	Name       string
+	InstanceID string
+	Registry   registry.Registry
+	Health     *health.Checker
+	Mux        *http.ServeMux
+
+	closers []func() error
+	reloads []func() error
+}
+
+// New crea el registry y el mux (con /healthz y /readyz). El registro en el
+// registry se hace en Run, cuando ya se está escuchando.
+func New(cfg Config) (*Service, error) {
+	log.Printf("Starting %s on port %d", cfg.Name, cfg.Port)
+	log.Printf("REGISTRY_BACKEND = %s (CONSUL_HOST = %s)", cfg.Registry.Backend, cfg.Registry.ConsulAddr)
+
+	reg, closeRegistry, err := discovery.New(cfg.Registry)
+	if err != nil {
+		return nil, fmt.Errorf("creating registry: %w", err)
+	}
+
+	s := &Service{
+		cfg:        cfg,
+		Name:       cfg.Name,
+		InstanceID: registry.GenerateInstanceID(cfg.Name),
+		Registry:   reg,
+		Health:     health.New(time.Second),
+		Mux:        http.NewServeMux(),
+	}
+	s.OnShutdown(func() error { closeRegistry(); return nil })
+
+	s.Health.Add("registry", health.Warning, health.RegistryCheck(reg, cfg.Name))
+	s.Mux.Handle("/healthz", health.LivenessHandler())   // el proceso atiende (check HTTP del registry)
+	s.Mux.Handle("/readyz", s.Health.ReadinessHandler()) // dependencias: repositorio, registry, otros servicios
+	return s, nil
+}
+
+// OnShutdown agrega algo que cerrar al final del apagado (p.ej. un
+// repositorio). Se cierran en orden inverso al que se agregaron.
+func (s *Service) OnShutdown(fn func() error) {
+	s.closers = append(s.closers, fn)
+}
+
+// OnReload agrega algo que recargar al recibir SIGHUP (p.ej. llaves o
+// secretos leídos de archivos). Si falla se registra el error y se sigue con
+// lo que había.
+func (s *Service) OnReload(fn func() error) {
+	s.reloads = append(s.reloads, fn)
+}
+
+func (s *Service) reload() {
+	log.Println("Recibido SIGHUP, recargando...")
+	for _, fn := range s.reloads {
+		if err := fn(); err != nil {
+			log.Printf("Error recargando: %v", err)
+		}
+	}
+}
+
+// Run atiende hasta recibir SIGINT/SIGTERM (o hasta que se cancele ctx), en este orden:
+//
+//  1. escucha en el puerto (si está ocupado falla antes de registrarse)
+//  2. se registra y empieza el latido con los checks de salud
+//  3. al apagar: se reporta critical y se deregistra, sigue atendiendo
+//     DrainDelay, cierra el servidor esperando hasta ShutdownTimeout las
+//     peticiones en curso y cierra lo agregado con OnShutdown.
+//
+// Mientras tanto, cada SIGHUP corre lo agregado con OnReload.
+func (s *Service) Run(ctx context.Context) error {
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	defer s.close()
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+	go func() {
+		for {
+			select {
+			case <-hup:
+				s.reload()
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	ln, err := net.Listen("tcp", fmt.Sprintf(":%d", s.cfg.Port))
+	if err != nil {
+		return err
+	}
+	srv := &http.Server{
+		Handler:      s.Mux,
+		ReadTimeout:  10 * time.Second, // tiempo máx. para leer la petición
+		WriteTimeout: 15 * time.Second, // tiempo máx. para escribir la respuesta
+		IdleTimeout:  60 * time.Second, // tiempo máx. de conexión inactiva (keep-alive)
+	}
+	serveErr := make(chan error, 1)
+	go func() { serveErr <- srv.Serve(ln) }()
+	log.Printf("%s escuchando en %s", s.Name, ln.Addr())
+
+	hostPort := fmt.Sprintf("%s:%d", s.cfg.Host, s.cfg.Port)
+	if err := s.Registry.Register(ctx, s.InstanceID, s.Name, hostPort, s.cfg.registerOptions()...); err != nil {
+		_ = srv.Close()
+		return fmt.Errorf("registering service: %w", err)
+	}
+	log.Printf("%s registrado con ID=%s y address=%s", s.Name, s.InstanceID, hostPort)
+
+	hbCtx, stopHeartbeat := context.WithCancel(context.Background())
+	hbDone := make(chan struct{})
+	go func() {
+		defer close(hbDone)
+		s.Health.Heartbeat(hbCtx, s.Registry, s.InstanceID, s.Name, s.cfg.HeartbeatInterval, func(err error) {
+			log.Printf("Failed to report health state: %v", err)
+		})
+	}()
+
+	var runErr error
+	select {
+	case <-ctx.Done():
+		log.Println("Recibida señal, apagando...")
+	case err := <-serveErr:
+		runErr = fmt.Errorf("server error: %w", err)
+	}
+
+	// El latido se para antes de deregistrar, para que no vuelva a marcar la instancia como sana.
+	stopHeartbeat()
+	<-hbDone
+	if err := s.Registry.ReportState(s.InstanceID, s.Name, registry.HealthCritical, "shutting down"); err != nil {
+		log.Printf("Failed to report shutdown: %v", err)
+	}
+	if err := s.Registry.Deregister(context.Background(), s.InstanceID, s.Name); err != nil {
+		log.Printf("Failed to deregister: %v", err)
+	}
+
+	if runErr != nil {
+		return runErr
+	}
+
+	log.Printf("Deregistrado; atendiendo %s más antes de cerrar", s.cfg.DrainDelay)
+	time.Sleep(s.cfg.DrainDelay)
+
+	log.Println("Shutting down HTTP server...")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), s.cfg.ShutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("shutting down: %w", err)
+	}
+	if err := <-serveErr; err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+func (s *Service) close() {
+	for i := len(s.closers) - 1; i >= 0; i-- {
+		if err := s.closers[i](); err != nil {
+			log.Printf("Error cerrando: %v", err)
+		}
+	}
+	s.closers = nil
+}
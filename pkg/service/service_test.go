@@ -0,0 +1,206 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"proyecto/pkg/discovery/memory"
+	registry "proyecto/pkg/registry"
+)
+
+func TestConfigValidate(t *testing.T) {
+	if err := testConfig(8080).Validate(); err != nil {
+		t.Fatalf("config válida: %v", err)
+	}
+
+	bad := testConfig(0)
+	bad.Name = ""
+	bad.Host = "0.0.0.0"
+	bad.HealthCheck = "tcp"
+	bad.HeartbeatInterval = 5 * time.Second
+	bad.ShutdownTimeout = 0
+	bad.Registry.Backend = "etcd"
+	err := bad.Validate()
+	// Se informan todos los errores juntos.
+	for _, want := range []string{"service.name", "service.port", "service.host", "service.health_check", "service.heartbeat_interval", "service.shutdown_timeout", "registry.backend"} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Validate = %v, falta el error de %s", err, want)
+		}
+	}
+}
+
+// event es una llamada al registry: qué se hizo y, para los reportes, con qué estado.
+type event struct {
+	op    string
+	state registry.HealthState
+}
+
+// recordingRegistry es el registry en memoria que además anota cada llamada.
+type recordingRegistry struct {
+	*memory.Registry
+	mu     sync.Mutex
+	events []event
+}
+
+func (r *recordingRegistry) record(e event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, e)
+}
+
+func (r *recordingRegistry) snapshot() []event {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]event(nil), r.events...)
+}
+
+func (r *recordingRegistry) ReportState(instanceID, serviceName string, state registry.HealthState, output string) error {
+	r.record(event{"report", state})
+	return r.Registry.ReportState(instanceID, serviceName, state, output)
+}
+
+func (r *recordingRegistry) Deregister(ctx context.Context, instanceID, serviceName string) error {
+	r.record(event{op: "deregister"})
+	return r.Registry.Deregister(ctx, instanceID, serviceName)
+}
+
+func testConfig(port int) Config {
+	cfg := DefaultConfig("svc", port)
+	cfg.Registry.Backend = "memory"
+	cfg.HeartbeatInterval = 10 * time.Millisecond
+	cfg.DrainDelay = 300 * time.Millisecond
+	cfg.ShutdownTimeout = time.Second
+	return cfg
+}
+
+// freePort devuelve un puerto libre de la máquina.
+func freePort(t *testing.T) int {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	return ln.Addr().(*net.TCPAddr).Port
+}
+
+func TestShutdownOrder(t *testing.T) {
+	port := freePort(t)
+	s, err := New(testConfig(port))
+	if err != nil {
+		t.Fatal(err)
+	}
+	reg := &recordingRegistry{Registry: memory.NewRegistry(time.Second)}
+	s.Registry = reg
+	var closed bool
+	s.OnShutdown(func() error { closed = true; return nil })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- s.Run(ctx) }()
+
+	// Espera al primer latido: ya está registrada y sana.
+	waitFor(t, func() bool {
+		_, err := reg.ServiceAddress(context.Background(), "svc")
+		return err == nil
+	})
+	cancel()
+
+	// Primero se reporta critical y se deregistra...
+	waitFor(t, func() bool {
+		events := reg.snapshot()
+		return len(events) > 0 && events[len(events)-1].op == "deregister"
+	})
+	events := reg.snapshot()
+	if n := len(events); n < 2 || events[n-2] != (event{"report", registry.HealthCritical}) {
+		t.Errorf("eventos = %v, quería report critical justo antes del deregister", events)
+	}
+	if _, err := reg.ServiceAddress(context.Background(), "svc"); !errors.Is(err, registry.ErrNotFound) {
+		t.Errorf("ServiceAddress tras deregistrar = %v, quería ErrNotFound", err)
+	}
+	// ...y después se sigue atendiendo durante DrainDelay.
+	resp, err := http.Get(fmt.Sprintf("http://127.0.0.1:%d/healthz", port))
+	if err != nil {
+		t.Fatalf("petición durante el drenaje: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("/healthz durante el drenaje = %d", resp.StatusCode)
+	}
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Run = %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run no terminó")
+	}
+	if !closed {
+		t.Error("no se cerró lo agregado con OnShutdown")
+	}
+	// El latido ya estaba parado: nada volvió a marcarla sana.
+	if got := reg.snapshot(); len(got) != len(events) {
+		t.Errorf("eventos tras el deregister: %v", got[len(events):])
+	}
+}
+
+// waitFor espera hasta 2s a que cond se cumpla.
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("la condición no se cumplió a tiempo")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestReloadOnSIGHUP(t *testing.T) {
+	s, err := New(testConfig(freePort(t)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	reloaded := make(chan string, 2)
+	s.OnReload(func() error { reloaded <- "primero"; return errors.New("archivo roto") })
+	s.OnReload(func() error { reloaded <- "segundo"; return nil })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan error, 1)
+	go func() { done <- s.Run(ctx) }()
+	waitFor(t, func() bool {
+		_, err := s.Registry.ServiceAddress(context.Background(), "svc")
+		return err == nil
+	})
+
+	if err := syscall.Kill(os.Getpid(), syscall.SIGHUP); err != nil {
+		t.Fatal(err)
+	}
+	// Un error en una recarga no impide las siguientes.
+	for _, want := range []string{"primero", "segundo"} {
+		select {
+		case got := <-reloaded:
+			if got != want {
+				t.Errorf("recarga = %s, quería %s", got, want)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("no se corrió la recarga %s", want)
+		}
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("Run = %v", err)
+	}
+}
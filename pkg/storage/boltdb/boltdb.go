@@ -0,0 +1,96 @@
+package boltdb
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	metaBucket       = []byte("_meta")
+	schemaVersionKey = []byte("schema_version")
+)
+
+// Migration es un paso del esquema. Se aplican en orden de Version, cada uno en
+// su propia transacción junto con el registro de la versión alcanzada.
+type Migration struct {
+	Version int
+	Name    string
+	Up      func(tx *bolt.Tx) error
+}
+
+// Open abre (o crea) la base en path y aplica las migraciones pendientes.
+func Open(path string, migrations []Migration) (*bolt.DB, error) {
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("opening %s: %w", path, err)
+	}
+	if err := Migrate(db, migrations); err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// Migrate aplica las migraciones con versión mayor a la guardada en la base.
+func Migrate(db *bolt.DB, migrations []Migration) error {
+	for _, m := range migrations {
+		err := db.Update(func(tx *bolt.Tx) error {
+			meta, err := tx.CreateBucketIfNotExists(metaBucket)
+			if err != nil {
+				return err
+			}
+			current := 0
+			if v := meta.Get(schemaVersionKey); v != nil {
+				current = int(binary.BigEndian.Uint64(v))
+			}
+			if m.Version <= current {
+				return nil
+			}
+			if m.Version != current+1 {
+				return fmt.Errorf("migration %d (%s) found but schema is at %d", m.Version, m.Name, current)
+			}
+			if err := m.Up(tx); err != nil {
+				return err
+			}
+			return meta.Put(schemaVersionKey, binary.BigEndian.AppendUint64(nil, uint64(m.Version)))
+		})
+		if err != nil {
+			return fmt.Errorf("migration %d (%s): %w", m.Version, m.Name, err)
+		}
+	}
+	return nil
+}
+
+// CreateBuckets es una migración común: crea los buckets indicados.
+func CreateBuckets(names ...string) func(tx *bolt.Tx) error {
+	return func(tx *bolt.Tx) error {
+		for _, name := range names {
+			if _, err := tx.CreateBucketIfNotExists([]byte(name)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// GetJSON decodifica el valor de key en out; devuelve false si no existe.
+func GetJSON(b *bolt.Bucket, key string, out any) (bool, error) {
+	v := b.Get([]byte(key))
+	if v == nil {
+		return false, nil
+	}
+	return true, json.Unmarshal(v, out)
+}
+
+// PutJSON guarda v codificado como JSON en key.
+func PutJSON(b *bolt.Bucket, key string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return b.Put([]byte(key), data)
+}
@@ -0,0 +1,148 @@
+package boltdb
+
+import (
+	"encoding/binary"
+	"errors"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+func schemaVersion(t *testing.T, db *bolt.DB) int {
+	t.Helper()
+	var v int
+	err := db.View(func(tx *bolt.Tx) error {
+		meta := tx.Bucket(metaBucket)
+		if meta == nil {
+			return nil
+		}
+		if raw := meta.Get(schemaVersionKey); raw != nil {
+			v = int(binary.BigEndian.Uint64(raw))
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+func TestOpenAppliesPendingMigrations(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+	var applied []int
+	step := func(v int, bucket string) Migration {
+		return Migration{Version: v, Name: bucket, Up: func(tx *bolt.Tx) error {
+			applied = append(applied, v)
+			return CreateBuckets(bucket)(tx)
+		}}
+	}
+
+	db, err := Open(path, []Migration{step(1, "a"), step(2, "b")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := schemaVersion(t, db); got != 2 {
+		t.Errorf("versión = %d, quería 2", got)
+	}
+	db.Close()
+
+	// Al reabrir solo corre la migración nueva.
+	db, err = Open(path, []Migration{step(1, "a"), step(2, "b"), step(3, "c")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if want := []int{1, 2, 3}; !slices.Equal(applied, want) {
+		t.Errorf("migraciones aplicadas = %v, quería %v", applied, want)
+	}
+	err = db.View(func(tx *bolt.Tx) error {
+		for _, name := range []string{"a", "b", "c"} {
+			if tx.Bucket([]byte(name)) == nil {
+				t.Errorf("falta el bucket %q", name)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMigrateRejectsGap(t *testing.T) {
+	db, err := Open(filepath.Join(t.TempDir(), "test.db"), []Migration{
+		{Version: 1, Name: "a", Up: CreateBuckets("a")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	err = Migrate(db, []Migration{
+		{Version: 1, Name: "a", Up: CreateBuckets("a")},
+		{Version: 3, Name: "c", Up: CreateBuckets("c")},
+	})
+	if err == nil || !strings.Contains(err.Error(), "schema is at 1") {
+		t.Fatalf("Migrate con un hueco = %v, quería error", err)
+	}
+}
+
+func TestFailedMigrationRollsBack(t *testing.T) {
+	db, err := Open(filepath.Join(t.TempDir(), "test.db"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	boom := errors.New("boom")
+	err = Migrate(db, []Migration{{Version: 1, Name: "falla", Up: func(tx *bolt.Tx) error {
+		if err := CreateBuckets("a")(tx); err != nil {
+			return err
+		}
+		return boom
+	}}})
+	if !errors.Is(err, boom) {
+		t.Fatalf("Migrate = %v, quería %v", err, boom)
+	}
+	if got := schemaVersion(t, db); got != 0 {
+		t.Errorf("versión = %d, quería 0", got)
+	}
+	_ = db.View(func(tx *bolt.Tx) error {
+		if tx.Bucket([]byte("a")) != nil {
+			t.Error("el bucket de la migración fallida quedó creado")
+		}
+		return nil
+	})
+}
+
+func TestJSONRoundTrip(t *testing.T) {
+	db, err := Open(filepath.Join(t.TempDir(), "test.db"), []Migration{
+		{Version: 1, Name: "a", Up: CreateBuckets("a")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	type rec struct{ Name string }
+	err = db.Update(func(tx *bolt.Tx) error {
+		return PutJSON(tx.Bucket([]byte("a")), "k", rec{Name: "ana"})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = db.View(func(tx *bolt.Tx) error {
+		var got rec
+		found, err := GetJSON(tx.Bucket([]byte("a")), "k", &got)
+		if err != nil || !found || got.Name != "ana" {
+			t.Errorf("GetJSON = %v, %v, %v", got, found, err)
+		}
+		found, err = GetJSON(tx.Bucket([]byte("a")), "otra", &got)
+		if err != nil || found {
+			t.Errorf("GetJSON de una clave que no existe = %v, %v", found, err)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,96 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"proyecto/pkg/storage/postgres/postgrestest"
+)
+
+var testMigrations = []Migration{
+	{Version: 1, Name: "uno", SQL: `CREATE TABLE uno (id TEXT PRIMARY KEY)`},
+	{Version: 2, Name: "dos", SQL: `CREATE TABLE dos (id TEXT PRIMARY KEY)`},
+}
+
+func openTest(t *testing.T, dsn string, migrations []Migration) *sql.DB {
+	t.Helper()
+	db, err := Open(context.Background(), dsn, migrations)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func schemaVersion(t *testing.T, db *sql.DB) int {
+	t.Helper()
+	var v int
+	if err := db.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&v); err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+func TestOpenAppliesPendingMigrations(t *testing.T) {
+	dsn := postgrestest.DSN(t)
+	db := openTest(t, dsn, testMigrations[:1])
+	if v := schemaVersion(t, db); v != 1 {
+		t.Fatalf("versión = %d, quería 1", v)
+	}
+
+	// Al reabrir solo se aplica la nueva; si se volviera a aplicar la 1, CREATE TABLE fallaría.
+	db = openTest(t, dsn, testMigrations)
+	if v := schemaVersion(t, db); v != 2 {
+		t.Errorf("versión = %d, quería 2", v)
+	}
+	if _, err := db.Exec(`INSERT INTO dos (id) VALUES ('a')`); err != nil {
+		t.Errorf("la tabla de la migración 2 no existe: %v", err)
+	}
+}
+
+func TestMigrateRejectsGap(t *testing.T) {
+	dsn := postgrestest.DSN(t)
+	db := openTest(t, dsn, nil)
+	err := Migrate(context.Background(), db, []Migration{{Version: 2, Name: "dos", SQL: `SELECT 1`}})
+	if err == nil {
+		t.Fatal("Migrate aceptó saltearse la migración 1")
+	}
+}
+
+func TestFailedMigrationRollsBack(t *testing.T) {
+	dsn := postgrestest.DSN(t)
+	db := openTest(t, dsn, testMigrations[:1])
+	err := Migrate(context.Background(), db, []Migration{
+		testMigrations[0],
+		{Version: 2, Name: "rota", SQL: `CREATE TABLE tres (id TEXT); SELECT * FROM no_existe`},
+	})
+	if err == nil {
+		t.Fatal("Migrate no devolvió el error de la migración")
+	}
+	if v := schemaVersion(t, db); v != 1 {
+		t.Errorf("versión = %d, quería 1", v)
+	}
+	var exists bool
+	if err := db.QueryRow(`SELECT to_regclass('tres') IS NOT NULL`).Scan(&exists); err != nil {
+		t.Fatal(err)
+	}
+	if exists {
+		t.Error("la tabla de la migración fallida quedó creada")
+	}
+}
+
+func TestIsUniqueViolation(t *testing.T) {
+	dsn := postgrestest.DSN(t)
+	db := openTest(t, dsn, testMigrations[:1])
+	if _, err := db.Exec(`INSERT INTO uno (id) VALUES ('a')`); err != nil {
+		t.Fatal(err)
+	}
+	_, err := db.Exec(`INSERT INTO uno (id) VALUES ('a')`)
+	if !IsUniqueViolation(err) {
+		t.Errorf("IsUniqueViolation(%v) = false", err)
+	}
+	if IsUniqueViolation(sql.ErrNoRows) {
+		t.Error("IsUniqueViolation(ErrNoRows) = true")
+	}
+}
@@ -0,0 +1,62 @@
+// Package postgrestest prepara bases PostgreSQL para los tests. Los tests
+// corren solo si POSTGRES_TEST_DSN apunta a una base descartable; cada uno
+// trabaja en un esquema propio que se borra al terminar.
+package postgrestest
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+
+	_ "github.com/jackc/pgx/v5/stdlib" // driver "pgx" para database/sql
+)
+
+// EnvDSN es la variable con la base de los tests.
+const EnvDSN = "POSTGRES_TEST_DSN"
+
+// DSN devuelve un DSN que apunta a un esquema vacío creado para t, o saltea
+// el test si EnvDSN no está definida.
+func DSN(t testing.TB) string {
+	t.Helper()
+	dsn := strings.TrimSpace(os.Getenv(EnvDSN))
+	if dsn == "" {
+		t.Skipf("%s no está definida", EnvDSN)
+	}
+
+	db, err := sql.Open("pgx", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	b := make([]byte, 6)
+	_, _ = rand.Read(b)
+	schema := "test_" + hex.EncodeToString(b)
+	if _, err := db.ExecContext(context.Background(), `CREATE SCHEMA `+schema); err != nil {
+		t.Fatalf("creando el esquema %s: %v", schema, err)
+	}
+	t.Cleanup(func() {
+		if _, err := db.ExecContext(context.Background(), `DROP SCHEMA `+schema+` CASCADE`); err != nil {
+			t.Errorf("borrando el esquema %s: %v", schema, err)
+		}
+	})
+	return withSearchPath(dsn, schema)
+}
+
+// withSearchPath agrega search_path al DSN, sea URL o clave=valor.
+func withSearchPath(dsn, schema string) string {
+	if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+		if u, err := url.Parse(dsn); err == nil {
+			q := u.Query()
+			q.Set("search_path", schema)
+			u.RawQuery = q.Encode()
+			return u.String()
+		}
+	}
+	return dsn + " search_path=" + schema
+}
@@ -0,0 +1,32 @@
+// Package storage tiene la configuración común del almacenamiento de los
+// servicios; las implementaciones están en boltdb y postgres.
+package storage
+
+import (
+	"errors"
+	"fmt"
+)
+
+type Config struct {
+	// Backend es memory (se pierde al reiniciar), bolt (un archivo) o postgres.
+	Backend     string `yaml:"backend" env:"STORAGE_BACKEND"`
+	BoltPath    string `yaml:"bolt_path" env:"BOLT_PATH"` // vacío = <servicio>.db
+	DatabaseURL string `yaml:"database_url" env:"DATABASE_URL" secret:"true"`
+}
+
+func DefaultConfig() Config {
+	return Config{Backend: "memory"}
+}
+
+func (c Config) Validate() error {
+	switch c.Backend {
+	case "memory", "bolt":
+	case "postgres":
+		if c.DatabaseURL == "" {
+			return errors.New("storage.database_url (DATABASE_URL) es obligatorio con postgres")
+		}
+	default:
+		return fmt.Errorf("storage.backend desconocido: %q (memory, bolt o postgres)", c.Backend)
+	}
+	return nil
+}